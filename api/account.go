@@ -4,11 +4,14 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	db "github.com/faisal-a-n/simplebank/db/sqlc"
 	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
@@ -18,6 +21,49 @@ type createAccountRequest struct {
 	Currency string `json:"currency" binding:"required,currency"`
 }
 
+type accountResponse struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Name      string     `json:"name"`
+	Balance   util.Money `json:"balance"`
+	Currency  string     `json:"currency"`
+	Status    string     `json:"status"`
+	Number    string     `json:"number"`
+	CreatedAt int64      `json:"created_at"`
+}
+
+func accountResponseBuilder(account db.Account) accountResponse {
+	return accountResponse{
+		ID:        account.ID,
+		UserID:    account.UserID,
+		Name:      account.Name,
+		Balance:   util.NewMoney(account.Balance, account.Currency),
+		Currency:  account.Currency,
+		Status:    account.Status,
+		Number:    account.Number,
+		CreatedAt: account.CreatedAt,
+	}
+}
+
+//Account numbers are random, so a clash with an existing one is possible but rare enough
+//that a few attempts with a new number are plenty
+const accountNumberAttempts = 3
+
+func (server *Server) createNumberedAccount(ctx *gin.Context, arg db.CreateAccountParams) (db.Account, error) {
+	for attempt := 1; ; attempt++ {
+		number, err := util.NewAccountNumber()
+		if err != nil {
+			return db.Account{}, err
+		}
+		arg.Number = number
+		account, err := server.store.CreateAccount(ctx, arg)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "accounts_number_key" && attempt < accountNumberAttempts {
+			continue
+		}
+		return account, err
+	}
+}
+
 type getAccountReq struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
@@ -43,7 +89,7 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		CreatedAt: time.Now().Unix(),
 	}
 
-	account, err := server.store.CreateAccount(ctx, arg)
+	account, err := server.createNumberedAccount(ctx, arg)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
@@ -58,7 +104,8 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	ctx.JSON(http.StatusCreated, responseHandler(200, "Account has been created", account))
+	server.recordAudit(ctx, authPayload.UserID, db.AuditAccountCreate, db.AuditTargetAccount, strconv.FormatInt(account.ID, 10), nil, account)
+	ctx.JSON(http.StatusCreated, responseHandler(200, "Account has been created", accountResponseBuilder(account)))
 }
 
 //Get account by id
@@ -84,7 +131,7 @@ func (server *Server) getAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("Account does not belong to the user")))
 		return
 	}
-	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", account))
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", accountResponseBuilder(account)))
 }
 
 //Get accounts list
@@ -111,5 +158,145 @@ func (server *Server) getAccounts(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", accounts))
+	response := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		response[i] = accountResponseBuilder(account)
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", response))
+}
+
+//Money left in the account is moved to SweepAccountID, without it the balance must be zero
+type closeAccountRequest struct {
+	SweepAccountID int64 `json:"sweep_account_id" binding:"omitempty,min=1"`
+}
+
+type closeAccountResponse struct {
+	Account accountResponse         `json:"account"`
+	Sweep   *createTransferResponse `json:"sweep,omitempty"`
+}
+
+//Closes one of the user's accounts. Closed accounts keep their history but can't send or receive money
+func (server *Server) closeAccount(ctx *gin.Context) {
+	var uri getAccountReq
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+	//The body is optional when the account is already empty
+	var req closeAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	account, ok := server.getOwnedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+	missingSweepAccount := fmt.Errorf("Provided account [%d] doesn't exist", req.SweepAccountID)
+	if req.SweepAccountID != 0 {
+		sweepAccount, err := server.store.GetAccount(ctx, req.SweepAccountID)
+		if err != nil && err != sql.ErrNoRows {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		//Balances can only be swept between the user's own accounts, other users' accounts are
+		//reported as missing so account ids can't be probed
+		if err == sql.ErrNoRows || sweepAccount.UserID != account.UserID {
+			ctx.JSON(http.StatusNotFound, errorResponse(missingSweepAccount))
+			return
+		}
+	}
+
+	result, err := server.store.CloseAccountTx(ctx, db.CloseAccountTxParams{
+		AccountID:      account.ID,
+		SweepAccountID: req.SweepAccountID,
+		Actor:          auditActor(ctx, account.UserID),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(missingSweepAccount))
+			return
+		}
+		if status, code := accountErrorCode(err); status != 0 {
+			ctx.JSON(status, errorCodeResponse(code, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := closeAccountResponse{Account: accountResponseBuilder(result.Account)}
+	if result.Sweep != nil {
+		response.Sweep = &createTransferResponse{
+			Transfer:    transferResponseBuilder(result.Sweep.Transaction),
+			FromAccount: accountResponseBuilder(result.Sweep.FromAccount),
+			ToAccount:   accountResponseBuilder(result.Sweep.ToAccount),
+			FromEntry:   entryResponseBuilder(result.Sweep.FromEntry, result.Sweep.FromAccount.Currency),
+			ToEntry:     entryResponseBuilder(result.Sweep.ToEntry, result.Sweep.ToAccount.Currency),
+		}
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Account has been closed", response))
+}
+
+//Reopens an account the user closed. Frozen accounts can only be unfrozen by an admin
+func (server *Server) reopenAccount(ctx *gin.Context) {
+	var uri getAccountReq
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+	account, ok := server.getOwnedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	account, err := server.store.SetAccountStatusTx(ctx, db.SetAccountStatusTxParams{
+		AccountID: account.ID,
+		From:      db.AccountStatusClosed,
+		Status:    db.AccountStatusActive,
+		Actor:     auditActor(ctx, account.UserID),
+	})
+	if err != nil {
+		if status, code := accountErrorCode(err); status != 0 {
+			ctx.JSON(status, errorCodeResponse(code, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Account has been reopened", accountResponseBuilder(account)))
+}
+
+//Gets an account of the calling user, writing the response when it doesn't exist or isn't theirs
+func (server *Server) getOwnedAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No account with this id")))
+			return db.Account{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.Account{}, false
+	}
+	if !checkOwnership(ctx, account) {
+		return db.Account{}, false
+	}
+	return account, true
+}
+
+//Status and stable code for errors caused by an account's status, zero for any other error
+func accountErrorCode(err error) (int, string) {
+	switch {
+	case errors.Is(err, db.ErrAccountFrozen):
+		return http.StatusForbidden, "account_frozen"
+	case errors.Is(err, db.ErrAccountClosed):
+		return http.StatusForbidden, "account_closed"
+	case errors.Is(err, db.ErrAccountNotEmpty):
+		return http.StatusConflict, "account_not_empty"
+	case errors.Is(err, db.ErrAccountStatusChange):
+		return http.StatusConflict, "invalid_status_change"
+	case errors.Is(err, db.ErrInvalidSweepAccount):
+		return http.StatusBadRequest, "invalid_sweep_account"
+	}
+	return 0, ""
 }
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 
@@ -122,6 +123,30 @@ func TestGetAccountAPI(t *testing.T) {
 	}
 }
 
+type eqCreateAccountParams struct {
+	arg db.CreateAccountParams
+}
+
+//Account numbers are generated by the handler, so any valid number matches
+func (e eqCreateAccountParams) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateAccountParams)
+	if !ok || !util.IsValidAccountNumber(arg.Number) {
+		return false
+	}
+	e.arg.Number = arg.Number
+	e.arg.CreatedAt = arg.CreatedAt
+
+	return reflect.DeepEqual(e.arg, arg)
+}
+
+func (e eqCreateAccountParams) String() string {
+	return fmt.Sprintf("matches arg %v with a valid account number", e.arg)
+}
+
+func EqCreateAccountParams(arg db.CreateAccountParams) gomock.Matcher {
+	return eqCreateAccountParams{arg}
+}
+
 func TestCreateAccountAPI(t *testing.T) {
 	account := randomAccount()
 	testCases := []struct {
@@ -148,13 +173,40 @@ func TestCreateAccountAPI(t *testing.T) {
 					Balance:   0,
 					CreatedAt: account.CreatedAt,
 				}
-				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(args)).
+				store.EXPECT().CreateAccount(gomock.Any(), EqCreateAccountParams(args)).
 					Times(1).
 					Return(account, nil)
+				expectAudit(t, store, account.UserID, db.AuditAccountCreate, db.AuditTargetAccount, fmt.Sprint(account.ID))
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				fmt.Println(recorder.Body)
 				require.Equal(t, http.StatusCreated, recorder.Code)
+				checkAccounts(t, recorder.Body, account)
+			},
+		},
+		{
+			name: "AccountNumberTaken",
+			body: gin.H{
+				"name":     account.Name,
+				"currency": account.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				//A clash on the number is retried with a new one
+				gomock.InOrder(
+					store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.Account{}, &pq.Error{Code: "23505", Constraint: "accounts_number_key"}),
+					store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(account, nil),
+				)
+				expectAudit(t, store, account.UserID, db.AuditAccountCreate, db.AuditTargetAccount, fmt.Sprint(account.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
 			},
 		},
 		{
@@ -190,7 +242,7 @@ func TestCreateAccountAPI(t *testing.T) {
 					Currency:  account.Currency,
 					CreatedAt: account.CreatedAt,
 				}
-				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(args)).
+				store.EXPECT().CreateAccount(gomock.Any(), EqCreateAccountParams(args)).
 					Times(1).
 					Return(db.Account{}, sql.ErrConnDone)
 			},
@@ -301,7 +353,7 @@ func TestGetAccountsAPI(t *testing.T) {
 				err = json.Unmarshal(data, &response)
 				require.NoError(t, err)
 
-				var fetchedAccounts []db.Account
+				var fetchedAccounts []accountResponse
 				err = json.Unmarshal(response["data"], &fetchedAccounts)
 				require.NoError(t, err)
 				require.NotEmpty(t, fetchedAccounts)
@@ -388,6 +440,246 @@ func TestGetAccountsAPI(t *testing.T) {
 
 }
 
+func TestCloseAccountAPI(t *testing.T) {
+	account := randomAccount()
+	account.Balance = 0
+	sweepAccount := randomAccountWithCurrency(account.Currency)
+	sweepAccount.UserID = account.UserID
+	otherAccount := randomAccountWithCurrency(account.Currency)
+
+	testCases := []struct {
+		name          string
+		body          interface{}
+		userID        int64
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "EmptyAccount",
+			body:   nil,
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				closed := account
+				closed.Status = db.AccountStatusClosed
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CloseAccountTxParams) (db.CloseAccountTxResult, error) {
+						require.Equal(t, account.ID, arg.AccountID)
+						require.Zero(t, arg.SweepAccountID)
+						require.Equal(t, account.UserID, arg.Actor.ActorID)
+						return db.CloseAccountTxResult{Account: closed}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"status":"closed"`)
+				require.NotContains(t, recorder.Body.String(), `"sweep"`)
+			},
+		},
+		{
+			name:   "Sweep",
+			body:   gin.H{"sweep_account_id": sweepAccount.ID},
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				closed := account
+				closed.Status = db.AccountStatusClosed
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(sweepAccount.ID)).Times(1).Return(sweepAccount, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CloseAccountTxParams) (db.CloseAccountTxResult, error) {
+						require.Equal(t, sweepAccount.ID, arg.SweepAccountID)
+						return db.CloseAccountTxResult{
+							Account: closed,
+							Sweep:   &db.TransferTxResult{FromAccount: closed, ToAccount: sweepAccount},
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"sweep"`)
+			},
+		},
+		{
+			name:   "NotEmpty",
+			body:   nil,
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CloseAccountTxResult{}, db.ErrAccountNotEmpty)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error_code":"account_not_empty"`)
+			},
+		},
+		{
+			name:   "Frozen",
+			body:   nil,
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CloseAccountTxResult{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error_code":"account_frozen"`)
+			},
+		},
+		{
+			name:   "SweepAccountNotFound",
+			body:   gin.H{"sweep_account_id": sweepAccount.ID},
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(sweepAccount.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "SweepAccountNotOwned",
+			body:   gin.H{"sweep_account_id": otherAccount.ID},
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(otherAccount.ID)).Times(1).Return(otherAccount, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidSweepAccount",
+			body:   gin.H{"sweep_account_id": account.ID},
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CloseAccountTxResult{}, db.ErrInvalidSweepAccount)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error_code":"invalid_sweep_account"`)
+			},
+		},
+		{
+			name:   "NotOwner",
+			body:   nil,
+			userID: account.UserID + 1,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockController := gomock.NewController(t)
+			defer mockController.Finish()
+
+			store := mock_db.NewMockStore(mockController)
+			testCase.builStubs(store)
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body io.Reader = http.NoBody
+			if testCase.body != nil {
+				data, err := json.Marshal(testCase.body)
+				require.NoError(t, err)
+				body = bytes.NewReader(data)
+			}
+			url := fmt.Sprintf("/accounts/%d/close", account.ID)
+			req, err := http.NewRequest(http.MethodPost, url, body)
+			require.NoError(t, err)
+			addAuthorizationHeader(t, req, server.tokenMaker, testCase.userID, authorizationHeaderKey, authorizationType, time.Minute)
+			server.router.ServeHTTP(recorder, req)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReopenAccountAPI(t *testing.T) {
+	account := randomAccount()
+	account.Status = db.AccountStatusClosed
+
+	testCases := []struct {
+		name          string
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			builStubs: func(store *mock_db.MockStore) {
+				reopened := account
+				reopened.Status = db.AccountStatusActive
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.SetAccountStatusTxParams) (db.Account, error) {
+						require.Equal(t, db.AccountStatusClosed, arg.From)
+						require.Equal(t, db.AccountStatusActive, arg.Status)
+						require.Equal(t, account.UserID, arg.Actor.ActorID)
+						return reopened, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"status":"active"`)
+			},
+		},
+		{
+			//Only an admin can unfreeze
+			name: "Frozen",
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error_code":"account_frozen"`)
+			},
+		},
+		{
+			name: "AlreadyActive",
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.Account{}, fmt.Errorf("%w, it is already active", db.ErrAccountStatusChange))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error_code":"invalid_status_change"`)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockController := gomock.NewController(t)
+			defer mockController.Finish()
+
+			store := mock_db.NewMockStore(mockController)
+			testCase.builStubs(store)
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/reopen", account.ID)
+			req, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+			addAuthorizationHeader(t, req, server.tokenMaker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			server.router.ServeHTTP(recorder, req)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
 func randomAccount() db.Account {
 	user := generateRandomUser()
 	return db.Account{
@@ -396,6 +688,8 @@ func randomAccount() db.Account {
 		Balance:   util.GenerateAmount(),
 		Currency:  util.GenerateCurrency(),
 		UserID:    user.ID,
+		Status:    db.AccountStatusActive,
+		Number:    util.GenerateAccountNumber(),
 		CreatedAt: time.Now().Unix(),
 	}
 }
@@ -414,9 +708,10 @@ func checkAccounts(t *testing.T, body *bytes.Buffer, account db.Account) {
 	err = json.Unmarshal(data, &response)
 	require.NoError(t, err)
 
-	var fetchedAccount db.Account
+	var fetchedAccount accountResponse
 	err = json.Unmarshal(response["data"], &fetchedAccount)
 	require.NoError(t, err)
 
-	require.Equal(t, account, fetchedAccount)
+	require.Equal(t, accountResponseBuilder(account), fetchedAccount)
+	require.Equal(t, util.NewMoney(account.Balance, account.Currency), fetchedAccount.Balance)
 }
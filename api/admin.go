@@ -0,0 +1,327 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+type adminUserReq struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type updateUserRoleRequest struct {
+	Role   string `json:"role" binding:"required,oneof=customer support admin"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+//Changes a user's role. The user is logged out everywhere so the new role applies straight away
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri adminUserReq
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	//Stops the last admin from locking everyone out of the admin routes by accident
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	if authPayload.UserID == uri.ID {
+		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("You can't change your own role")))
+		return
+	}
+
+	user, err := server.store.UpdateUserRoleTx(ctx, db.UpdateUserRoleTxParams{
+		UserID: uri.ID,
+		Role:   req.Role,
+		Actor:  auditActor(ctx, authPayload.UserID),
+		Reason: req.Reason,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No user with this id")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocation.RevokeUser(user.ID)
+	ctx.JSON(http.StatusOK, responseHandler(200, "Role updated", userResponseBuilder(user)))
+}
+
+type searchUsersReq struct {
+	Email  string `form:"email" binding:"required,min=3"`
+	PageID int32  `form:"page_id" binding:"required,min=1"`
+	Count  int32  `form:"count" binding:"required,min=5,max=50"`
+}
+
+//Finds users whose email contains the search term
+func (server *Server) searchUsers(ctx *gin.Context) {
+	var req searchUsersReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	users, err := server.store.SearchUsersByEmail(ctx, db.SearchUsersByEmailParams{
+		Email: req.Email,
+		Count: req.Count,
+		Skip:  (req.PageID - 1) * req.Count,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := make([]userDetailsResponse, len(users))
+	for i, user := range users {
+		response[i] = userResponseBuilder(user)
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", response))
+}
+
+type adminAccountReq struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type adminGetAccountReq struct {
+	PageID int32 `form:"page_id" binding:"omitempty,min=1"`
+	Count  int32 `form:"count" binding:"omitempty,min=5,max=100"`
+}
+
+type adminAccountResponse struct {
+	Account accountResponse `json:"account"`
+	Entries []entryResponse `json:"entries"`
+}
+
+//Shows any account with its latest entries, newest first
+func (server *Server) adminGetAccount(ctx *gin.Context) {
+	var uri adminAccountReq
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+	var req adminGetAccountReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.PageID == 0 {
+		req.PageID = 1
+	}
+	if req.Count == 0 {
+		req.Count = 20
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No account with this id")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	entries, err := server.store.ListRecentEntries(ctx, db.ListRecentEntriesParams{
+		AccountID: account.ID,
+		Limit:     req.Count,
+		Offset:    (req.PageID - 1) * req.Count,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := adminAccountResponse{
+		Account: accountResponseBuilder(account),
+		Entries: make([]entryResponse, len(entries)),
+	}
+	for i, entry := range entries {
+		response.Entries[i] = entryResponseBuilder(entry, account.Currency)
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", response))
+}
+
+//Every admin change needs a reason, it is kept in the audit log
+type adminReasonRequest struct {
+	Reason string `json:"reason" binding:"required,min=5,max=500"`
+}
+
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	server.setAccountStatus(ctx, db.AccountStatusActive, db.AccountStatusFrozen)
+}
+
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	server.setAccountStatus(ctx, db.AccountStatusFrozen, db.AccountStatusActive)
+}
+
+//Frozen accounts can't send or receive transfers until they are unfrozen. Closed accounts
+//are left alone, only their owner can reopen them
+func (server *Server) setAccountStatus(ctx *gin.Context, from, status string) {
+	var uri adminAccountReq
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+	var req adminReasonRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	account, err := server.store.SetAccountStatusTx(ctx, db.SetAccountStatusTxParams{
+		AccountID: uri.ID,
+		From:      from,
+		Status:    status,
+		Actor:     auditActor(ctx, authPayload.UserID),
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No account with this id")))
+			return
+		}
+		if status, code := accountErrorCode(err); status != 0 {
+			ctx.JSON(status, errorCodeResponse(code, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Account status updated", accountResponseBuilder(account)))
+}
+
+//Amount is in the account's currency, negative amounts debit the account
+type adjustBalanceRequest struct {
+	Amount util.Money `json:"amount"`
+	Reason string     `json:"reason" binding:"required,min=5,max=500"`
+}
+
+type adjustBalanceResponse struct {
+	Account accountResponse `json:"account"`
+	Entry   entryResponse   `json:"entry"`
+}
+
+func (server *Server) adjustBalance(ctx *gin.Context) {
+	var uri adminAccountReq
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+	var req adjustBalanceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Amount.IsZero() {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("amount must not be zero")))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	result, err := server.store.AdjustBalanceTx(ctx, db.AdjustBalanceTxParams{
+		AccountID: uri.ID,
+		Amount:    req.Amount.Amount,
+		Currency:  req.Amount.Currency,
+		Actor:     auditActor(ctx, authPayload.UserID),
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No account with this id")))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrAdjustmentCurrency) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		if status, code := accountErrorCode(err); status != 0 {
+			ctx.JSON(status, errorCodeResponse(code, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := adjustBalanceResponse{
+		Account: accountResponseBuilder(result.Account),
+		Entry:   entryResponseBuilder(result.Entry, result.Account.Currency),
+	}
+	ctx.JSON(http.StatusCreated, responseHandler(200, "Adjustment posted", response))
+}
+
+//Logs the user out everywhere, for example when their credentials are reported stolen
+func (server *Server) blockUserSessions(ctx *gin.Context) {
+	var uri adminUserReq
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+	var req adminReasonRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No user with this id")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	err = server.store.BlockUserSessionsTx(ctx, db.BlockUserSessionsTxParams{
+		UserID: user.ID,
+		Actor:  auditActor(ctx, authPayload.UserID),
+		Reason: req.Reason,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocation.RevokeUser(user.ID)
+	ctx.JSON(http.StatusOK, responseHandler(200, "Sessions blocked", userResponseBuilder(user)))
+}
+
+type listAuditLogReq struct {
+	TargetType string `form:"target_type" binding:"omitempty,oneof=account email ip session transfer user"`
+	TargetID   string `form:"target_id"`
+	PageID     int32  `form:"page_id" binding:"required,min=1"`
+	Count      int32  `form:"count" binding:"required,min=5,max=100"`
+}
+
+//Lists the audit trail newest first, optionally for a single account or user
+func (server *Server) listAuditLog(ctx *gin.Context) {
+	var req listAuditLogReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	entries, err := server.store.ListAuditLog(ctx, db.ListAuditLogParams{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Count:      req.Count,
+		Skip:       (req.PageID - 1) * req.Count,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", entries))
+}
@@ -0,0 +1,514 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateUserRoleAPI(t *testing.T) {
+	admin := generateRandomUser()
+	admin.Role = util.RoleAdmin
+	user := generateRandomUser()
+	user.ID = admin.ID + 1
+
+	testCases := []struct {
+		name          string
+		userID        int64
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, maker token.Maker)
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			userID: user.ID,
+			body:   gin.H{"role": util.RoleSupport},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addRoleAuthorizationHeader(t, request, maker, admin.ID, util.RoleAdmin, util.RoleScopes(util.RoleAdmin))
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				updated := user
+				updated.Role = util.RoleSupport
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateUserRoleTxParams) (db.User, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, util.RoleSupport, arg.Role)
+						require.Equal(t, admin.ID, arg.Actor.ActorID)
+						require.NotEmpty(t, arg.Actor.RequestID)
+						return updated, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data userDetailsResponse `json:"data"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, util.RoleSupport, response.Data.Role)
+			},
+		},
+		{
+			name:   "NotAdmin",
+			userID: user.ID,
+			body:   gin.H{"role": util.RoleAdmin},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "OwnRole",
+			userID: admin.ID,
+			body:   gin.H{"role": util.RoleCustomer},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addRoleAuthorizationHeader(t, request, maker, admin.ID, util.RoleAdmin, util.RoleScopes(util.RoleAdmin))
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "UnknownRole",
+			userID: user.ID,
+			body:   gin.H{"role": "owner"},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addRoleAuthorizationHeader(t, request, maker, admin.ID, util.RoleAdmin, util.RoleScopes(util.RoleAdmin))
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "UserNotFound",
+			userID: user.ID,
+			body:   gin.H{"role": util.RoleSupport},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addRoleAuthorizationHeader(t, request, maker, admin.ID, util.RoleAdmin, util.RoleScopes(util.RoleAdmin))
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+			server := NewTestServer(t, store)
+
+			body, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/admin/users/%d/role", testCase.userID)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+			require.NoError(t, err)
+			testCase.setupAuth(t, request, server.tokenMaker)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+//Sends an admin request, staff is authorised with the role's full set of scopes
+//Sent with every admin request so the audit actor can be matched exactly
+const adminRequestID = "admin-request"
+
+func serveAdminRequest(t *testing.T, server *Server, method, url string, body interface{}, staffID int64, role string) *httptest.ResponseRecorder {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	request, err := http.NewRequest(method, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	addRoleAuthorizationHeader(t, request, server.tokenMaker, staffID, role, util.RoleScopes(role))
+	request.Header.Set(requestIDHeaderKey, adminRequestID)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestSearchUsersAPI(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+	user := generateRandomUser()
+
+	store.EXPECT().SearchUsersByEmail(gomock.Any(), gomock.Eq(db.SearchUsersByEmailParams{
+		Email: user.Email,
+		Count: 5,
+		Skip:  5,
+	})).Times(1).Return([]db.User{user}, nil)
+
+	//Support staff can search
+	url := fmt.Sprintf("/admin/users?email=%s&page_id=2&count=5", user.Email)
+	recorder := serveAdminRequest(t, server, http.MethodGet, url, nil, 1, util.RoleSupport)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Data []userDetailsResponse `json:"data"`
+	}
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Equal(t, []userDetailsResponse{userResponseBuilder(user)}, response.Data)
+
+	//Customers can't
+	recorder = serveAdminRequest(t, server, http.MethodGet, url, nil, 1, util.RoleCustomer)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestAdminGetAccountAPI(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+	account := randomAccount()
+	entry := db.Entry{ID: 1, AccountID: account.ID, Amount: account.Balance, CreatedAt: account.CreatedAt}
+
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().ListRecentEntries(gomock.Any(), gomock.Eq(db.ListRecentEntriesParams{
+		AccountID: account.ID,
+		Limit:     20,
+		Offset:    0,
+	})).Times(1).Return([]db.Entry{entry}, nil)
+
+	recorder := serveAdminRequest(t, server, http.MethodGet, fmt.Sprintf("/admin/accounts/%d", account.ID), nil, 1, util.RoleSupport)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Data adminAccountResponse `json:"data"`
+	}
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Equal(t, accountResponseBuilder(account), response.Data.Account)
+	require.Equal(t, []entryResponse{entryResponseBuilder(entry, account.Currency)}, response.Data.Entries)
+
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrNoRows)
+	recorder = serveAdminRequest(t, server, http.MethodGet, fmt.Sprintf("/admin/accounts/%d", account.ID), nil, 1, util.RoleSupport)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestFreezeAccountAPI(t *testing.T) {
+	account := randomAccount()
+	adminID := account.UserID + 1
+	reason := gin.H{"reason": "Reported as compromised"}
+
+	testCases := []struct {
+		name          string
+		url           string
+		body          interface{}
+		role          string
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Freeze",
+			url:  fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			body: reason,
+			role: util.RoleAdmin,
+			builStubs: func(store *mock_db.MockStore) {
+				frozen := account
+				frozen.Status = db.AccountStatusFrozen
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Eq(db.SetAccountStatusTxParams{
+					AccountID: account.ID,
+					From:      db.AccountStatusActive,
+					Status:    db.AccountStatusFrozen,
+					Actor:     db.AuditActor{ActorID: adminID, RequestID: adminRequestID},
+					Reason:    "Reported as compromised",
+				})).Times(1).Return(frozen, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"status":"frozen"`)
+			},
+		},
+		{
+			name: "Unfreeze",
+			url:  fmt.Sprintf("/admin/accounts/%d/unfreeze", account.ID),
+			body: reason,
+			role: util.RoleAdmin,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.SetAccountStatusTxParams) (db.Account, error) {
+						require.Equal(t, db.AccountStatusFrozen, arg.From)
+						require.Equal(t, db.AccountStatusActive, arg.Status)
+						return account, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MissingReason",
+			url:  fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			body: gin.H{},
+			role: util.RoleAdmin,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "SupportCantFreeze",
+			url:  fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			body: reason,
+			role: util.RoleSupport,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "AccountClosed",
+			url:  fmt.Sprintf("/admin/accounts/%d/unfreeze", account.ID),
+			body: reason,
+			role: util.RoleAdmin,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, db.ErrAccountClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error_code":"account_closed"`)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			url:  fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			body: reason,
+			role: util.RoleAdmin,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+			server := NewTestServer(t, store)
+
+			recorder := serveAdminRequest(t, server, http.MethodPost, testCase.url, testCase.body, adminID, testCase.role)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdjustBalanceAPI(t *testing.T) {
+	account := randomAccount()
+	adminID := account.UserID + 1
+	url := fmt.Sprintf("/admin/accounts/%d/adjustments", account.ID)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"amount": gin.H{"amount": -100, "currency": account.Currency}, "reason": "Reversed card payment"},
+			builStubs: func(store *mock_db.MockStore) {
+				adjusted := account
+				adjusted.Balance -= 100
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Eq(db.AdjustBalanceTxParams{
+					AccountID: account.ID,
+					Amount:    -100,
+					Currency:  account.Currency,
+					Actor:     db.AuditActor{ActorID: adminID, RequestID: adminRequestID},
+					Reason:    "Reversed card payment",
+				})).Times(1).Return(db.AdjustBalanceTxResult{
+					Account: adjusted,
+					Entry:   db.Entry{ID: 1, AccountID: account.ID, Amount: -100},
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "ZeroAmount",
+			body: gin.H{"amount": gin.H{"amount": 0, "currency": account.Currency}, "reason": "Reversed card payment"},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingReason",
+			body: gin.H{"amount": gin.H{"amount": 100, "currency": account.Currency}},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{"amount": gin.H{"amount": -account.Balance - 1, "currency": account.Currency}, "reason": "Reversed card payment"},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(1).Return(db.AdjustBalanceTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedCurrency",
+			body: gin.H{"amount": gin.H{"amount": 100, "currency": "XYZ"}, "reason": "Reversed card payment"},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{"amount": gin.H{"value": "100", "currency": "JPY"}, "reason": "Reversed card payment"},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(1).Return(db.AdjustBalanceTxResult{}, db.ErrAdjustmentCurrency)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+			server := NewTestServer(t, store)
+
+			recorder := serveAdminRequest(t, server, http.MethodPost, url, testCase.body, adminID, util.RoleAdmin)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateUserRoleRevokesTokens(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+	user := generateRandomUser()
+	user.Role = util.RoleAdmin
+	adminID := user.ID + 1
+
+	demoted := user
+	demoted.Role = util.RoleCustomer
+	store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(1).Return(demoted, nil)
+
+	url := fmt.Sprintf("/admin/users/%d/role", user.ID)
+	recorder := serveAdminRequest(t, server, http.MethodPut, url, gin.H{"role": util.RoleCustomer}, adminID, util.RoleAdmin)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	//A demoted admin's tokens stop working on this server straight away
+	revoked, err := server.revocation.IsRevoked(context.Background(), &token.Payload{UserID: user.ID, IssuedAt: time.Now().Add(-time.Second)})
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestBlockUserSessionsAPI(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+	user := generateRandomUser()
+	adminID := user.ID + 1
+
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+	store.EXPECT().BlockUserSessionsTx(gomock.Any(), gomock.Eq(db.BlockUserSessionsTxParams{
+		UserID: user.ID,
+		Actor:  db.AuditActor{ActorID: adminID, RequestID: adminRequestID},
+		Reason: "Credentials leaked",
+	})).Times(1).Return(nil)
+
+	url := fmt.Sprintf("/admin/users/%d/sessions/block", user.ID)
+	recorder := serveAdminRequest(t, server, http.MethodPost, url, gin.H{"reason": "Credentials leaked"}, adminID, util.RoleAdmin)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	//Tokens the user already holds stop working on this server straight away
+	revoked, err := server.revocation.IsRevoked(context.Background(), &token.Payload{UserID: user.ID, IssuedAt: time.Now().Add(-time.Second)})
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestListAuditLogAPI(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+
+	store.EXPECT().ListAuditLog(gomock.Any(), gomock.Eq(db.ListAuditLogParams{
+		TargetType: db.AuditTargetAccount,
+		TargetID:   "7",
+		Count:      10,
+		Skip:       0,
+	})).Times(1).Return([]db.AuditLog{{ID: 1, Action: db.AuditAccountFreeze}}, nil)
+
+	recorder := serveAdminRequest(t, server, http.MethodGet, "/admin/audit-log?target_type=account&target_id=7&page_id=1&count=10", nil, 1, util.RoleSupport)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = serveAdminRequest(t, server, http.MethodGet, "/admin/audit-log?target_type=card&page_id=1&count=10", nil, 1, util.RoleSupport)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeaderKey = "X-Request-ID"
+	requestIDKey       = "request_id"
+)
+
+//Request ids from callers are kept when they are short and printable
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+//Gives every request an id, echoed in the response and stored with audit log rows.
+//An id set by a proxy in front of the server is reused so the request can be followed end to end
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeaderKey)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		ctx.Set(requestIDKey, requestID)
+		ctx.Header(requestIDHeaderKey, requestID)
+		ctx.Next()
+	}
+}
+
+func auditActor(ctx *gin.Context, actorID int64) db.AuditActor {
+	return db.AuditActor{
+		ActorID:   actorID,
+		RequestID: ctx.GetString(requestIDKey),
+		ClientIp:  ctx.ClientIP(),
+	}
+}
+
+//Appends an event that has already happened to the audit log. A failed write is logged
+//rather than failing the request, the client would otherwise retry something that succeeded
+func (server *Server) recordAudit(ctx *gin.Context, actorID int64, action, targetType, targetID string, before, after interface{}) {
+	arg, err := db.NewAuditLogParams(auditActor(ctx, actorID), action, targetType, targetID, "", before, after)
+	if err == nil {
+		_, err = server.store.AppendAuditLog(ctx, arg)
+	}
+	if err != nil {
+		log.Printf("couldn't write %s to the audit log: %v", action, err)
+	}
+}
+
+type verifyAuditLogResponse struct {
+	db.AuditVerification
+	Valid bool `json:"valid"`
+}
+
+//Walks the whole audit log and reports the first row that was changed or removed
+func (server *Server) verifyAuditLog(ctx *gin.Context) {
+	result, err := db.VerifyAuditLog(ctx, server.store)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	message := "Audit log is intact"
+	if !result.Valid() {
+		message = "Audit log has been tampered with"
+	}
+	response := verifyAuditLogResponse{
+		AuditVerification: result,
+		Valid:             result.Valid(),
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, message, response))
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+//Expects one audit log row for the action, written with the request's id
+func expectAudit(t *testing.T, store *mock_db.MockStore, actorID int64, action, targetType, targetID string) {
+	store.EXPECT().AppendAuditLog(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateAuditLogParams) (db.AuditLog, error) {
+			require.Equal(t, actorID, arg.ActorID)
+			require.Equal(t, action, arg.Action)
+			require.Equal(t, targetType, arg.TargetType)
+			require.Equal(t, targetID, arg.TargetID)
+			require.NotEmpty(t, arg.RequestID)
+			return db.AuditLog{}, nil
+		})
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requestID string
+		check     func(t *testing.T, requestID string)
+	}{
+		{
+			name:      "Generated",
+			requestID: "",
+			check: func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 36)
+			},
+		},
+		{
+			name:      "FromProxy",
+			requestID: "lb-1:3f2a9c",
+			check: func(t *testing.T, requestID string) {
+				require.Equal(t, "lb-1:3f2a9c", requestID)
+			},
+		},
+		{
+			name:      "Invalid",
+			requestID: "has spaces\nand a newline",
+			check: func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 36)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			var seen string
+			router.GET("/", requestIDMiddleware(), func(ctx *gin.Context) {
+				seen = ctx.GetString(requestIDKey)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set(requestIDHeaderKey, tc.requestID)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			tc.check(t, seen)
+			require.Equal(t, seen, recorder.Header().Get(requestIDHeaderKey))
+		})
+	}
+}
+
+func TestVerifyAuditLogAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		logs          []db.AuditLog
+		role          string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Empty",
+			logs: []db.AuditLog{},
+			role: util.RoleSupport,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				response := decodeVerifyAuditLog(t, recorder)
+				require.True(t, response.Valid)
+			},
+		},
+		{
+			name: "Tampered",
+			logs: []db.AuditLog{{ID: 4, Action: db.AuditAccountAdjustment, Hash: "edited"}},
+			role: util.RoleAdmin,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				response := decodeVerifyAuditLog(t, recorder)
+				require.False(t, response.Valid)
+				require.Equal(t, int64(4), response.BrokenAt)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			store.EXPECT().ListAuditLogAfter(gomock.Any(), gomock.Any()).Times(1).Return(tc.logs, nil)
+
+			server := NewTestServer(t, store)
+			recorder := serveAdminRequest(t, server, http.MethodGet, "/admin/audit-log/verify", nil, 1, tc.role)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func decodeVerifyAuditLog(t *testing.T, recorder *httptest.ResponseRecorder) verifyAuditLogResponse {
+	var response struct {
+		Data verifyAuditLogResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	return response.Data
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+//Lists the currencies known to the bank, only enabled ones can be used for new accounts and transfers
+func (server *Server) listCurrencies(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, responseHandler(200, "Currencies", util.Currencies()))
+}
+
+//Loads the currency registry from the currencies table
+func LoadCurrencies(ctx context.Context, store db.Querier) error {
+	rows, err := store.ListCurrencies(ctx)
+	if err != nil {
+		return err
+	}
+	currencies := make([]util.Currency, len(rows))
+	for i, row := range rows {
+		currencies[i] = util.Currency{
+			Code:        row.Code,
+			NumericCode: row.NumericCode,
+			Exponent:    int(row.Exponent),
+			Symbol:      row.Symbol,
+			Name:        row.Name,
+			Enabled:     row.Enabled,
+		}
+	}
+	util.SetCurrencies(currencies)
+	return nil
+}
+
+//Reloads the currency registry on an interval so currencies enabled or disabled in the database
+//are picked up by every running server without a redeploy
+func WatchCurrencies(ctx context.Context, store db.Querier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := LoadCurrencies(ctx, store); err != nil {
+				log.Printf("couldn't reload currencies: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCurrenciesAPI(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+
+	request, err := http.NewRequest(http.MethodGet, "/currencies", nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Data []util.Currency `json:"data"`
+	}
+	err = json.Unmarshal(recorder.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Equal(t, util.Currencies(), response.Data)
+}
+
+func TestLoadCurrencies(t *testing.T) {
+	defaults := util.Currencies()
+	defer util.SetCurrencies(defaults)
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return([]db.Currency{
+		{Code: "USD", NumericCode: "840", Exponent: 2, Symbol: "$", Enabled: false},
+		{Code: "GBP", NumericCode: "826", Exponent: 2, Symbol: "£", Enabled: true},
+	}, nil)
+
+	err := LoadCurrencies(context.Background(), store)
+	require.NoError(t, err)
+
+	//The currency validator follows the registry
+	require.False(t, util.IsSupportedCurrency("USD"))
+	require.True(t, util.IsSupportedCurrency("GBP"))
+
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+	err = LoadCurrencies(context.Background(), store)
+	require.Error(t, err)
+	require.True(t, util.IsSupportedCurrency("GBP"))
+}
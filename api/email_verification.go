@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+//Random bytes in an email verification token
+const emailVerificationTokenSize = 32
+
+var errEmailNotVerified = errors.New("Email has to be verified first")
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+//Creates a verification token for the user's current address and mails it to them
+func (server *Server) sendEmailVerification(ctx *gin.Context, user db.User) error {
+	verificationToken, err := util.NewSecretToken(emailVerificationTokenSize)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, err = server.store.CreateEmailVerification(ctx, db.CreateEmailVerificationParams{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: util.HashSecretToken(verificationToken),
+		ExpiresAt: now.Add(server.config.EMAIL_VERIFICATION_DURATION).Unix(),
+		CreatedAt: now.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	return server.notifier.EmailVerification(ctx, user.Email, verificationToken, server.config.EMAIL_VERIFICATION_DURATION)
+}
+
+//Used after signing up or changing the email. The change stands even when the mail can't
+//be sent, the user can ask for another one
+func (server *Server) trySendEmailVerification(ctx *gin.Context, user db.User) {
+	if err := server.sendEmailVerification(ctx, user); err != nil {
+		log.Printf("couldn't send email verification to user %d: %v", user.ID, err)
+	}
+}
+
+//Verify an email with a mailed token. No login is needed since the mail may be opened on another device
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		TokenHash: util.HashSecretToken(req.Token),
+		Actor:     auditActor(ctx, 0),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidVerificationToken) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Email has been verified", userResponseBuilder(user)))
+}
+
+//Mail the logged in user a new verification token, the earlier ones keep working until they expire.
+//Resends are throttled like password resets
+func (server *Server) resendEmailVerification(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.EmailVerified {
+		ctx.JSON(http.StatusConflict, errorResponse(errors.New("Email is already verified")))
+		return
+	}
+	if !server.throttleEmailVerification(ctx, user.ID) {
+		return
+	}
+	if err := server.sendEmailVerification(ctx, user); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Verification token has been sent", nil))
+}
+
+//Stops users who haven't verified their email when REQUIRE_EMAIL_VERIFICATION is set
+func (server *Server) requireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !server.config.REQUIRE_EMAIL_VERIFICATION {
+			ctx.Next()
+			return
+		}
+		authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+		user, err := server.store.GetUser(ctx, authPayload.UserID)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !user.EmailVerified {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorCodeResponse("email_not_verified", errEmailNotVerified))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/mail"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user := generateRandomUser()
+	user.EmailVerified = true
+	verificationToken, err := util.NewSecretToken(emailVerificationTokenSize)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": verificationToken},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.VerifyEmailTxParams) (db.User, error) {
+						require.Equal(t, util.HashSecretToken(verificationToken), arg.TokenHash)
+						require.NotEmpty(t, arg.Actor.RequestID)
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data userDetailsResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.True(t, response.Data.EmailVerified)
+			},
+		},
+		{
+			name: "InvalidToken",
+			body: gin.H{"token": verificationToken},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrInvalidVerificationToken)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingToken",
+			body: gin.H{},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			body: gin.H{"token": verificationToken},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/verify-email", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestResendEmailVerificationAPI(t *testing.T) {
+	user := generateRandomUser()
+	verified := user
+	verified.EmailVerified = true
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, maker token.Maker)
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				expectLoginAllowed(store)
+				expectLoginFailure(store, user.Email)
+				store.EXPECT().CreateEmailVerification(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateEmailVerificationParams) (db.EmailVerification, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, user.Email, arg.Email)
+						require.Len(t, arg.TokenHash, 64)
+						require.Greater(t, arg.ExpiresAt, arg.CreatedAt)
+						return db.EmailVerification{ID: 1}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, mailer.messages, 1)
+				require.Equal(t, user.Email, mailer.messages[0].To)
+				require.Regexp(t, mailedToken, mailer.messages[0].Body)
+			},
+		},
+		{
+			name: "AlreadyVerified",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(verified, nil)
+				store.EXPECT().CreateEmailVerification(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Empty(t, mailer.messages)
+			},
+		},
+		{
+			name: "Throttled",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().GetLoginLock(gomock.Any(), gomock.Eq(db.GetLoginLockParams{
+					AccountKey: fmt.Sprintf("verify:user:%d", user.ID),
+					IpKey:      "verify:" + ipThrottleKey(""),
+				})).Times(1).Return(time.Now().Add(time.Minute).Unix(), nil)
+				store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateEmailVerification(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "email_verification_locked")
+				require.Empty(t, mailer.messages)
+			},
+		},
+		{
+			name:      "Unauthorized",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+			mailer := &mailRecorder{}
+			server.notifier = mail.NewNotifier(mailer)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/verify-email/resend", nil)
+			require.NoError(t, err)
+			testCase.setupAuth(t, request, server.tokenMaker)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder, mailer)
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	user := generateRandomUser()
+	verified := user
+	verified.EmailVerified = true
+	account := randomAccount()
+	account.UserID = user.ID
+
+	testCases := []struct {
+		name          string
+		required      bool
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "NotRequired",
+			required: false,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+				expectAudit(t, store, user.ID, db.AuditAccountCreate, db.AuditTargetAccount, fmt.Sprint(account.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:     "Verified",
+			required: true,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(verified, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+				expectAudit(t, store, user.ID, db.AuditAccountCreate, db.AuditTargetAccount, fmt.Sprint(account.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:     "NotVerified",
+			required: true,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+
+				var response map[string]string
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, "email_not_verified", response["error_code"])
+			},
+		},
+		{
+			name:     "InternalServerError",
+			required: true,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(db.User{}, sql.ErrConnDone)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+			server.config.REQUIRE_EMAIL_VERIFICATION = testCase.required
+
+			data, err := json.Marshal(gin.H{
+				"name":     account.Name,
+				"currency": account.Currency,
+			})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
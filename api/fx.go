@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/fx"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+//Used when FX_QUOTE_DURATION is not configured
+const defaultQuoteDuration = 30 * time.Second
+
+var errFxDisabled = errors.New("Cross currency transfers are not enabled")
+
+//The amount is converted from its own currency
+type createFxQuoteRequest struct {
+	Amount     util.Money `json:"amount"`
+	ToCurrency string     `json:"to_currency" binding:"required,currency"`
+}
+
+type fxQuoteResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Rate      string     `json:"rate"`
+	Amount    util.Money `json:"amount"`
+	ToAmount  util.Money `json:"to_amount"`
+	ExpiresAt int64      `json:"expires_at"`
+	CreatedAt int64      `json:"created_at"`
+}
+
+func fxQuoteResponseBuilder(quote db.FxQuote) fxQuoteResponse {
+	return fxQuoteResponse{
+		ID:        quote.ID,
+		Rate:      quote.Rate,
+		Amount:    util.NewMoney(quote.Amount, quote.FromCurrency),
+		ToAmount:  util.NewMoney(quote.ToAmount, quote.ToCurrency),
+		ExpiresAt: quote.ExpiresAt,
+		CreatedAt: quote.CreatedAt,
+	}
+}
+
+//Locks an exchange rate for a short time so the user knows the exact amount that will be credited
+func (server *Server) createFxQuote(ctx *gin.Context) {
+	var req createFxQuoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !checkPositiveAmount(ctx, req.Amount) {
+		return
+	}
+	if req.Amount.Currency == req.ToCurrency {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("to_currency must differ from the amount's currency")))
+		return
+	}
+
+	rate, toAmount, ok := server.convert(ctx, req.Amount.Amount, req.Amount.Currency, req.ToCurrency)
+	if !ok {
+		return
+	}
+
+	duration := server.config.FX_QUOTE_DURATION
+	if duration <= 0 {
+		duration = defaultQuoteDuration
+	}
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	now := time.Now()
+	quote, err := server.store.CreateFxQuote(ctx, db.CreateFxQuoteParams{
+		ID:           uuid.New(),
+		UserID:       authPayload.UserID,
+		FromCurrency: req.Amount.Currency,
+		ToCurrency:   req.ToCurrency,
+		Rate:         rate,
+		Amount:       req.Amount.Amount,
+		ToAmount:     toAmount,
+		ExpiresAt:    now.Add(duration).Unix(),
+		CreatedAt:    now.Unix(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusCreated, responseHandler(200, "Quote has been created", fxQuoteResponseBuilder(quote)))
+}
+
+//Converts the amount with the current rate, returns the rate as a decimal string and the converted amount
+func (server *Server) convert(ctx *gin.Context, amount int64, from, to string) (string, int64, bool) {
+	if server.fxProvider == nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errFxDisabled))
+		return "", 0, false
+	}
+	rate, err := server.fxProvider.Rate(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateUnavailable) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return "", 0, false
+		}
+		if errors.Is(err, fx.ErrQuoteUnavailable) {
+			ctx.JSON(http.StatusServiceUnavailable, errorResponse(err))
+			return "", 0, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return "", 0, false
+	}
+	toAmount, err := fx.Convert(amount, from, to, rate)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return "", 0, false
+	}
+	if toAmount <= 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Amount is too small to convert")))
+		return "", 0, false
+	}
+	return fx.FormatRate(rate), toAmount, true
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/fx"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func testRateProvider(t *testing.T) fx.FXRateProvider {
+	provider, err := fx.NewTableRateProvider("USD", map[string]string{
+		"EUR": "0.5",
+		"CAD": "1.25",
+	}, time.Now())
+	require.NoError(t, err)
+	return provider
+}
+
+func TestCreateFxQuoteAPI(t *testing.T) {
+	userID := int64(7)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		disableFx     bool
+		staleFx       bool
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"amount":      gin.H{"amount": 1000, "currency": "USD"},
+				"to_currency": "EUR",
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateFxQuote(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateFxQuoteParams) (db.FxQuote, error) {
+						require.Equal(t, userID, arg.UserID)
+						require.Equal(t, "0.50000000", arg.Rate)
+						require.Equal(t, int64(1000), arg.Amount)
+						require.Equal(t, int64(500), arg.ToAmount)
+						require.Greater(t, arg.ExpiresAt, arg.CreatedAt)
+						return db.FxQuote{ID: arg.ID}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "SameCurrency",
+			body: gin.H{
+				"amount":      gin.H{"amount": 1000, "currency": "USD"},
+				"to_currency": "USD",
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateFxQuote(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "RateUnavailable",
+			body: gin.H{
+				"amount":      gin.H{"amount": 1000, "currency": "USD"},
+				"to_currency": "INR",
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateFxQuote(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "FxDisabled",
+			body: gin.H{
+				"amount":      gin.H{"amount": 1000, "currency": "USD"},
+				"to_currency": "EUR",
+			},
+			disableFx: true,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateFxQuote(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "StaleRates",
+			body: gin.H{
+				"amount":      gin.H{"amount": 1000, "currency": "USD"},
+				"to_currency": "EUR",
+			},
+			staleFx: true,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateFxQuote(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			body: gin.H{
+				"amount":      gin.H{"amount": 1000, "currency": "USD"},
+				"to_currency": "EUR",
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateFxQuote(gomock.Any(), gomock.Any()).Times(1).Return(db.FxQuote{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+
+			server := NewTestServer(t, store)
+			if !testCase.disableFx {
+				server.fxProvider = testRateProvider(t)
+			}
+			if testCase.staleFx {
+				//Published two hours ago, older than the hour allowed
+				provider, err := fx.NewTableRateProvider("USD", map[string]string{"EUR": "0.5"}, time.Now().Add(-2*time.Hour))
+				require.NoError(t, err)
+				server.fxProvider = fx.NewMaxAgeRateProvider(provider, time.Hour)
+			}
+			body, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/fx/quotes", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, userID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateCrossCurrencyTransferAPI(t *testing.T) {
+	fromAccount := randomAccountWithCurrency("USD")
+	toAccount := randomAccountWithCurrency("EUR")
+	quote := db.FxQuote{
+		ID:           uuid.New(),
+		UserID:       fromAccount.UserID,
+		FromCurrency: "USD",
+		ToCurrency:   "EUR",
+		Rate:         "0.45000000",
+		Amount:       1000,
+		ToAmount:     450,
+		ExpiresAt:    time.Now().Add(time.Minute).Unix(),
+	}
+	expiredQuote := quote
+	expiredQuote.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+
+	testCases := []struct {
+		name          string
+		quoteID       string
+		disableFx     bool
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "CurrentRate",
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), EqCreateUserParams(db.TransferTxParams{
+					FromAccountID: fromAccount.ID,
+					ToAccountID:   toAccount.ID,
+					Amount:        1000,
+					ToAmount:      500,
+					Rate:          "0.50000000",
+					Actor:         db.AuditActor{ActorID: fromAccount.UserID},
+				})).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:    "LockedQuote",
+			quoteID: quote.ID.String(),
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(quote, nil)
+				store.EXPECT().TransferTx(gomock.Any(), EqCreateUserParams(db.TransferTxParams{
+					FromAccountID: fromAccount.ID,
+					ToAccountID:   toAccount.ID,
+					Amount:        1000,
+					ToAmount:      quote.ToAmount,
+					Rate:          quote.Rate,
+					QuoteID:       quote.ID,
+					Actor:         db.AuditActor{ActorID: fromAccount.UserID},
+				})).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:    "ExpiredQuote",
+			quoteID: quote.ID.String(),
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(expiredQuote, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:    "QuoteUsedConcurrently",
+			quoteID: quote.ID.String(),
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(quote, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrQuoteUnavailable)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:    "QuoteNotFound",
+			quoteID: quote.ID.String(),
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(db.FxQuote{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "FxDisabled",
+			disableFx: true,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+			testCase.builStubs(store)
+
+			server := NewTestServer(t, store)
+			if !testCase.disableFx {
+				server.fxProvider = testRateProvider(t)
+			}
+			body, err := json.Marshal(gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": 1000, "currency": "USD"},
+				"quote_id":        testCase.quoteID,
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyHeaderKey    = "Idempotency-Key"
+	idempotencyReplayHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+	idempotencyRetryKey     = "idempotency_retry"
+)
+
+//Captures the response body so it can be stored against the idempotency key
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+//Replays the stored response when a request is retried with the same Idempotency-Key header.
+//Must be used after authMiddleware since keys are scoped to the user. Keys can be reused once
+//they are older than ttl, zero keeps them until they are deleted
+func idempotencyMiddleware(store db.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyHeaderKey)
+		if len(key) == 0 {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			err := errors.New("idempotency key is too long")
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+		//The request path includes the ids so a key can't be replayed against another resource
+		path := ctx.Request.URL.Path
+		hash := requestHash(ctx.Request.Method, path, body)
+
+		now := time.Now()
+		arg := db.CreateIdempotencyKeyParams{
+			Key:         key,
+			UserID:      authPayload.UserID,
+			RequestPath: path,
+			RequestHash: hash,
+			CreatedAt:   now.Unix(),
+		}
+		if ttl > 0 {
+			arg.ExpiredBefore = now.Add(-ttl).Unix()
+		}
+		_, err = store.CreateIdempotencyKey(ctx, arg)
+		if err == sql.ErrNoRows {
+			//Key was already used, replay the original response instead of executing the request again
+			replayIdempotentRequest(ctx, store, authPayload.UserID, key, path, hash)
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: ctx.Writer, body: &bytes.Buffer{}}
+		ctx.Writer = writer
+		//A key left without a response would answer every retry with a conflict
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				releaseIdempotencyKey(ctx, store, authPayload.UserID, key)
+				panic(recovered)
+			}
+		}()
+		ctx.Next()
+
+		//Server errors are not stored so the client can retry with the same key
+		if ctx.Writer.Status() >= http.StatusInternalServerError || ctx.GetBool(idempotencyRetryKey) {
+			releaseIdempotencyKey(ctx, store, authPayload.UserID, key)
+			return
+		}
+		err = store.UpdateIdempotencyKeyResponse(ctx, db.UpdateIdempotencyKeyResponseParams{
+			ResponseCode: int32(ctx.Writer.Status()),
+			ResponseBody: writer.body.Bytes(),
+			UserID:       authPayload.UserID,
+			Key:          key,
+		})
+		if err != nil {
+			//The request has already taken effect, so the key is kept as processing and retries
+			//get a conflict until it expires rather than running the request a second time
+			log.Printf("couldn't store the response for idempotency key %q: %v", key, err)
+		}
+	}
+}
+
+//Marks the response as one the client is expected to retry with the same key once the
+//request is completed, like a transfer missing its two factor code, so it isn't stored
+func allowIdempotentRetry(ctx *gin.Context) {
+	ctx.Set(idempotencyRetryKey, true)
+}
+
+//Deletes a key that has no response stored so the request can be retried with it
+func releaseIdempotencyKey(ctx *gin.Context, store db.Store, userID int64, key string) {
+	err := store.DeleteIdempotencyKey(ctx, db.DeleteIdempotencyKeyParams{
+		UserID: userID,
+		Key:    key,
+	})
+	if err != nil {
+		log.Printf("couldn't release idempotency key %q: %v", key, err)
+	}
+}
+
+//Deletes idempotency keys once they are older than ttl, until the context is cancelled
+func ExpireIdempotencyKeys(ctx context.Context, store db.Querier, ttl, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := store.DeleteExpiredIdempotencyKeys(ctx, time.Now().Add(-ttl).Unix())
+			if err != nil {
+				log.Printf("couldn't delete expired idempotency keys: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("deleted %d expired idempotency keys", deleted)
+			}
+		}
+	}
+}
+
+func replayIdempotentRequest(ctx *gin.Context, store db.Store, userID int64, key, path, hash string) {
+	saved, err := store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		UserID: userID,
+		Key:    key,
+	})
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if saved.RequestPath != path || saved.RequestHash != hash {
+		err := errors.New("idempotency key has already been used with a different request")
+		ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, errorResponse(err))
+		return
+	}
+	if saved.ResponseCode == 0 {
+		err := errors.New("a request with this idempotency key is still being processed")
+		ctx.AbortWithStatusJSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+	ctx.Header(idempotencyReplayHeader, "true")
+	ctx.Data(int(saved.ResponseCode), gin.MIMEJSON+"; charset=utf-8", saved.ResponseBody)
+	ctx.Abort()
+}
+
+//Hashes the method, path and body. JSON bodies are hashed in a canonical form so a retry that
+//only changes whitespace or key order is still the same request
+func requestHash(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte(path))
+	hash.Write(canonicalJSON(body))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+//Re-encodes a JSON body with sorted keys and no whitespace. Numbers keep their original text
+//so large values don't lose precision. Bodies that aren't JSON are returned unchanged
+func canonicalJSON(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return body
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	account := randomAccount()
+	key := "a7b3c9d2-create-account"
+	body, err := json.Marshal(gin.H{
+		"name":     account.Name,
+		"currency": account.Currency,
+	})
+	require.NoError(t, err)
+	hash := requestHash(http.MethodPost, "/accounts", body)
+	savedBody := []byte(`{"code":200,"message":"Account has been created"}`)
+
+	testCases := []struct {
+		name          string
+		key           string
+		setupAuth     func(t *testing.T, request *http.Request, maker token.Maker)
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "FirstRequest",
+			key:  key,
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateIdempotencyKeyParams) (db.IdempotencyKey, error) {
+						require.Equal(t, key, arg.Key)
+						require.Equal(t, account.UserID, arg.UserID)
+						require.Equal(t, "/accounts", arg.RequestPath)
+						require.Equal(t, hash, arg.RequestHash)
+						require.Equal(t, arg.CreatedAt-int64(time.Hour/time.Second), arg.ExpiredBefore)
+						return db.IdempotencyKey{}, nil
+					})
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+				store.EXPECT().AppendAuditLog(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateIdempotencyKeyResponseParams) error {
+						require.Equal(t, int32(http.StatusCreated), arg.ResponseCode)
+						require.NotEmpty(t, arg.ResponseBody)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Empty(t, recorder.Header().Get(idempotencyReplayHeader))
+			},
+		},
+		{
+			name: "Replay",
+			key:  key,
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{
+					UserID: account.UserID,
+					Key:    key,
+				})).Times(1).Return(db.IdempotencyKey{
+					Key:          key,
+					UserID:       account.UserID,
+					RequestPath:  "/accounts",
+					RequestHash:  hash,
+					ResponseCode: http.StatusCreated,
+					ResponseBody: savedBody,
+				}, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get(idempotencyReplayHeader))
+				require.Equal(t, savedBody, recorder.Body.Bytes())
+			},
+		},
+		{
+			name: "DifferentRequestBody",
+			key:  key,
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{
+					Key:          key,
+					UserID:       account.UserID,
+					RequestPath:  "/accounts",
+					RequestHash:  "another-hash",
+					ResponseCode: http.StatusCreated,
+					ResponseBody: savedBody,
+				}, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "InProgress",
+			key:  key,
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{
+					Key:         key,
+					UserID:      account.UserID,
+					RequestPath: "/accounts",
+					RequestHash: hash,
+				}, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "ServerErrorReleasesKey",
+			key:  key,
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrConnDone)
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{
+					UserID: account.UserID,
+					Key:    key,
+				})).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "StoreResponseFailsKeepsKey",
+			key:  key,
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+				store.EXPECT().AppendAuditLog(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+				//The account was created, releasing the key would let a retry create another one
+				store.EXPECT().DeleteIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "PanicReleasesKey",
+			key:  key,
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, _ db.CreateAccountParams) (db.Account, error) {
+						panic("store failure")
+					})
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{
+					UserID: account.UserID,
+					Key:    key,
+				})).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "NoKey",
+			key:  "",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+				store.EXPECT().AppendAuditLog(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+
+			server := NewTestServer(t, store)
+			request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewBuffer(body))
+			require.NoError(t, err)
+
+			testCase.setupAuth(t, request, server.tokenMaker)
+			if len(testCase.key) > 0 {
+				request.Header.Set(idempotencyHeaderKey, testCase.key)
+			}
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestIdempotencyKeyScopedToPath(t *testing.T) {
+	account := randomAccount()
+	key := "c41e8f0a-close-account"
+	usedPath := fmt.Sprintf("/accounts/%d/close", account.ID)
+	requestPath := fmt.Sprintf("/accounts/%d/close", account.ID+1)
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateIdempotencyKeyParams) (db.IdempotencyKey, error) {
+			require.Equal(t, requestPath, arg.RequestPath)
+			require.Equal(t, requestHash(http.MethodPost, requestPath, nil), arg.RequestHash)
+			return db.IdempotencyKey{}, sql.ErrNoRows
+		})
+	store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{
+		Key:          key,
+		UserID:       account.UserID,
+		RequestPath:  usedPath,
+		RequestHash:  requestHash(http.MethodPost, usedPath, nil),
+		ResponseCode: http.StatusOK,
+		ResponseBody: []byte(`{"code":200,"message":"Account has been closed"}`),
+	}, nil)
+	store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := NewTestServer(t, store)
+	request, err := http.NewRequest(http.MethodPost, requestPath, http.NoBody)
+	require.NoError(t, err)
+	addAuthorizationHeader(t, request, server.tokenMaker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+	request.Header.Set(idempotencyHeaderKey, key)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+}
+
+func TestIdempotencyStepUpReleasesKey(t *testing.T) {
+	currency := "EUR"
+	threshold := int64(1000)
+	fromAccount := randomAccountWithCurrency(currency)
+	toAccount := randomAccountWithCurrency(currency)
+	credential := randomTotpCredential(t, fromAccount.UserID)
+	key := "5d0b7e21-large-transfer"
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+	store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(fromAccount.UserID)).Times(1).Return(credential, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{
+		UserID: fromAccount.UserID,
+		Key:    key,
+	})).Times(1).Return(nil)
+
+	server := NewTestServer(t, store)
+	server.stepUpThresholds = map[string]int64{currency: threshold}
+
+	data, err := json.Marshal(gin.H{
+		"from_account_id": fromAccount.ID,
+		"to_account_id":   toAccount.ID,
+		"amount":          gin.H{"amount": threshold + 1, "currency": currency},
+	})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorizationHeader(t, request, server.tokenMaker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+	request.Header.Set(idempotencyHeaderKey, key)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "two_factor_code_required")
+}
+
+func TestRequestHashCanonical(t *testing.T) {
+	hash := requestHash(http.MethodPost, "/transfers", []byte(`{"to_account_id":2,"from_account_id":1,"amount":{"amount":"10.50","currency":"EUR"}}`))
+
+	//Whitespace and key order don't change the request
+	same := requestHash(http.MethodPost, "/transfers", []byte("{\n  \"amount\": {\"currency\": \"EUR\", \"amount\": \"10.50\"},\n  \"from_account_id\": 1,\n  \"to_account_id\": 2\n}"))
+	require.Equal(t, hash, same)
+
+	//A different value does
+	other := requestHash(http.MethodPost, "/transfers", []byte(`{"to_account_id":3,"from_account_id":1,"amount":{"amount":"10.50","currency":"EUR"}}`))
+	require.NotEqual(t, hash, other)
+
+	//Bodies that aren't JSON are hashed as they are
+	require.NotEqual(t, requestHash(http.MethodPost, "/transfers", []byte("{")), requestHash(http.MethodPost, "/transfers", []byte("{ ")))
+	require.Equal(t, requestHash(http.MethodPost, "/transfers", nil), requestHash(http.MethodPost, "/transfers", []byte{}))
+}
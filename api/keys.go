@@ -0,0 +1,78 @@
+package api
+
+import (
+	"crypto"
+	"fmt"
+	"net/http"
+
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+//Token formats for TOKEN_TYPE. The first two share SECRET_KEY with every verifier, the
+//others sign with TOKEN_PRIVATE_KEY_FILE and publish the public key at /.well-known/keys
+const (
+	tokenTypePaseto       = "paseto"
+	tokenTypeJWT          = "jwt"
+	tokenTypePasetoPublic = "paseto-v4-public"
+	tokenTypeJWTEdDSA     = "jwt-eddsa"
+	tokenTypeJWTRS256     = "jwt-rs256"
+)
+
+type listKeysResponse struct {
+	Keys []token.JWK `json:"keys"`
+}
+
+//Creates the token maker selected in the config. An asymmetric maker without a private key
+//only verifies tokens, which is how downstream services run
+func newTokenMaker(config util.Config) (token.Maker, error) {
+	switch config.TOKEN_TYPE {
+	case "", tokenTypePaseto:
+		return token.NewPasetoMaker(config.SECRET_KEY)
+	case tokenTypeJWT:
+		return token.NewJWTMaker(config.SECRET_KEY)
+	}
+
+	var privateKey crypto.PrivateKey
+	var publicKey crypto.PublicKey
+	var err error
+	if len(config.TOKEN_PRIVATE_KEY_FILE) > 0 {
+		privateKey, err = token.LoadPrivateKey(config.TOKEN_PRIVATE_KEY_FILE)
+	} else if len(config.TOKEN_PUBLIC_KEY_FILE) > 0 {
+		publicKey, err = token.LoadPublicKey(config.TOKEN_PUBLIC_KEY_FILE)
+	} else {
+		err = fmt.Errorf("%s tokens need a private or public key file", config.TOKEN_TYPE)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.TOKEN_TYPE {
+	case tokenTypePasetoPublic:
+		if privateKey != nil {
+			return token.NewPasetoPublicMaker(privateKey)
+		}
+		return token.NewPasetoPublicVerifier(publicKey)
+	case tokenTypeJWTEdDSA, tokenTypeJWTRS256:
+		alg := "EdDSA"
+		if config.TOKEN_TYPE == tokenTypeJWTRS256 {
+			alg = "RS256"
+		}
+		if privateKey != nil {
+			return token.NewJWTPublicMaker(alg, privateKey)
+		}
+		return token.NewJWTVerifier(alg, publicKey)
+	}
+	return nil, fmt.Errorf("Unknown token type %q", config.TOKEN_TYPE)
+}
+
+//Publish the keys tokens are signed with as a JSON Web Key Set. Nothing is listed
+//for shared secret tokens since those keys must never leave the server
+func (server *Server) listKeys(ctx *gin.Context) {
+	response := listKeysResponse{Keys: []token.JWK{}}
+	if maker, ok := server.tokenMaker.(token.PublicKeyMaker); ok {
+		response.Keys = append(response.Keys, maker.PublicKeys()...)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+//Writes a fresh Ed25519 key pair as PEM files and returns their paths
+func writeTestKeyPair(t *testing.T) (privateKeyFile string, publicKeyFile string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privateKeyFile = filepath.Join(dir, "token.key")
+	publicKeyFile = filepath.Join(dir, "token.pub")
+	err = os.WriteFile(privateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+	require.NoError(t, err)
+	err = os.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)
+	require.NoError(t, err)
+	return
+}
+
+func TestNewTokenMaker(t *testing.T) {
+	privateKeyFile, publicKeyFile := writeTestKeyPair(t)
+	secret := util.GenerateString(32)
+
+	for _, tokenType := range []string{tokenTypePasetoPublic, tokenTypeJWTEdDSA} {
+		t.Run(tokenType, func(t *testing.T) {
+			maker, err := newTokenMaker(util.Config{TOKEN_TYPE: tokenType, TOKEN_PRIVATE_KEY_FILE: privateKeyFile})
+			require.NoError(t, err)
+			verifier, err := newTokenMaker(util.Config{TOKEN_TYPE: tokenType, TOKEN_PUBLIC_KEY_FILE: publicKeyFile})
+			require.NoError(t, err)
+
+			accessToken, _, err := maker.CreateToken(1, uuid.New(), util.RoleCustomer, util.RoleScopes(util.RoleCustomer), time.Minute)
+			require.NoError(t, err)
+			_, err = verifier.VerifyToken(accessToken)
+			require.NoError(t, err)
+
+			_, _, err = verifier.CreateToken(1, uuid.New(), util.RoleCustomer, util.RoleScopes(util.RoleCustomer), time.Minute)
+			require.ErrorIs(t, err, token.ErrVerifierOnly)
+		})
+	}
+
+	_, err := newTokenMaker(util.Config{TOKEN_TYPE: tokenTypeJWT, SECRET_KEY: secret})
+	require.NoError(t, err)
+
+	//An Ed25519 key can't sign RS256 tokens
+	_, err = newTokenMaker(util.Config{TOKEN_TYPE: tokenTypeJWTRS256, TOKEN_PRIVATE_KEY_FILE: privateKeyFile})
+	require.Error(t, err)
+
+	_, err = newTokenMaker(util.Config{TOKEN_TYPE: tokenTypePasetoPublic})
+	require.Error(t, err)
+
+	_, err = newTokenMaker(util.Config{TOKEN_TYPE: "unknown", TOKEN_PRIVATE_KEY_FILE: privateKeyFile})
+	require.Error(t, err)
+}
+
+func TestListKeysAPI(t *testing.T) {
+	privateKeyFile, _ := writeTestKeyPair(t)
+
+	testCases := []struct {
+		name          string
+		config        util.Config
+		checkResponse func(t *testing.T, keys []token.JWK)
+	}{
+		{
+			name:   "SharedSecret",
+			config: util.Config{SECRET_KEY: util.GenerateString(32)},
+			checkResponse: func(t *testing.T, keys []token.JWK) {
+				require.Empty(t, keys)
+			},
+		},
+		{
+			name:   "PasetoPublic",
+			config: util.Config{TOKEN_TYPE: tokenTypePasetoPublic, TOKEN_PRIVATE_KEY_FILE: privateKeyFile},
+			checkResponse: func(t *testing.T, keys []token.JWK) {
+				require.Len(t, keys, 1)
+				require.Equal(t, "OKP", keys[0].Kty)
+				require.Equal(t, "Ed25519", keys[0].Crv)
+				require.NotEmpty(t, keys[0].X)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server, err := NewServer(testCase.config, nil)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodGet, "/.well-known/keys", nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var response listKeysResponse
+			err = json.Unmarshal(recorder.Body.Bytes(), &response)
+			require.NoError(t, err)
+			testCase.checkResponse(t, response.Keys)
+		})
+	}
+}
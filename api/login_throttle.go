@@ -0,0 +1,207 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	//The same for unknown emails and wrong passwords so logins can't be used to find accounts
+	errInvalidLogin = errors.New("Email or password is incorrect")
+	errLoginLocked  = errors.New("Too many failed login attempts, try again later")
+	//Wrong two factor codes outside of logins lock the user's second factor on their own
+	errSecondFactorLocked  = errors.New("Too many wrong two factor codes, try again later")
+	errPasswordResetLocked = errors.New("Too many password reset requests, try again later")
+	errVerificationLocked  = errors.New("Too many verification emails requested, try again later")
+)
+
+//Failed logins past freeAttempts lock the key for backoff, doubling with every further failure
+//until it reaches lockout
+type loginThrottle struct {
+	freeAttempts int32
+	backoff      time.Duration
+	lockout      time.Duration
+}
+
+//How long a key is locked after its nth failure in a row
+func (throttle loginThrottle) delay(failures int32) time.Duration {
+	if throttle.backoff <= 0 || failures <= throttle.freeAttempts {
+		return 0
+	}
+	delay := throttle.backoff
+	for i := throttle.freeAttempts + 1; i < failures && delay < throttle.lockout; i++ {
+		delay *= 2
+	}
+	if throttle.lockout > 0 && delay > throttle.lockout {
+		delay = throttle.lockout
+	}
+	return delay
+}
+
+func (throttle loginThrottle) lockedOut(failures int32) bool {
+	return throttle.lockout > 0 && throttle.delay(failures) >= throttle.lockout
+}
+
+//Failures are counted per email whether or not it is registered, otherwise only registered
+//emails would ever be locked
+func accountThrottleKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+func secondFactorThrottleKey(userID int64) string {
+	return "2fa:" + strconv.FormatInt(userID, 10)
+}
+
+//Counts failures with the same limits as logins by email
+func (server *Server) accountThrottle() loginThrottle {
+	return loginThrottle{
+		freeAttempts: server.config.LOGIN_FREE_ATTEMPTS,
+		backoff:      server.config.LOGIN_BACKOFF,
+		lockout:      server.config.LOGIN_LOCKOUT_DURATION,
+	}
+}
+
+//Writes a 429 and returns false while the email or the client ip is locked
+func (server *Server) checkLoginLock(ctx *gin.Context, email string) bool {
+	return server.checkThrottleLock(ctx, accountThrottleKey(email), ipThrottleKey(ctx.ClientIP()), "login_locked", errLoginLocked)
+}
+
+//Writes a 429 and returns false while either key is locked
+func (server *Server) checkThrottleLock(ctx *gin.Context, accountKey, ipKey, code string, lockErr error) bool {
+	lockedUntil, err := server.store.GetLoginLock(ctx, db.GetLoginLockParams{
+		AccountKey: accountKey,
+		IpKey:      ipKey,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if wait := lockedUntil - time.Now().Unix(); wait > 0 {
+		ctx.Header("Retry-After", strconv.FormatInt(wait, 10))
+		ctx.JSON(http.StatusTooManyRequests, errorCodeResponse(code, lockErr))
+		return false
+	}
+	return true
+}
+
+//Counts a failed login against the email and the client ip. The user is zero when the email
+//isn't registered. Errors are only logged, the login has failed either way
+func (server *Server) recordLoginFailure(ctx *gin.Context, email string, user db.User) {
+	targetType, targetID := db.AuditTargetEmail, email
+	if user.ID != 0 {
+		targetType, targetID = db.AuditTargetUser, strconv.FormatInt(user.ID, 10)
+	}
+	accountThrottle := server.accountThrottle()
+	server.throttleLogin(ctx, accountThrottleKey(email), accountThrottle, targetType, targetID)
+
+	//Many users can share an ip, so it gets more attempts before it is slowed down
+	ipThrottle := accountThrottle
+	ipThrottle.freeAttempts = server.config.LOGIN_IP_FREE_ATTEMPTS
+	server.throttleLogin(ctx, ipThrottleKey(ctx.ClientIP()), ipThrottle, db.AuditTargetIP, ctx.ClientIP())
+}
+
+//Records the failure for one key and locks it once it is past its free attempts. The failure
+//that first reaches the full lockout is written to the audit log for support staff
+func (server *Server) throttleLogin(ctx *gin.Context, key string, throttle loginThrottle, targetType, targetID string) {
+	now := time.Now()
+	failure, err := server.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+		Key:          key,
+		Now:          now.Unix(),
+		ForgetBefore: now.Add(-server.config.LOGIN_FAILURE_WINDOW).Unix(),
+	})
+	if err != nil {
+		log.Printf("couldn't record a failed login for %s: %v", key, err)
+		return
+	}
+	delay := throttle.delay(failure.Failures)
+	if delay == 0 {
+		return
+	}
+	lockedUntil := now.Add(delay).Unix()
+	err = server.store.LockLogin(ctx, db.LockLoginParams{
+		LockedUntil: lockedUntil,
+		Key:         key,
+	})
+	if err != nil {
+		log.Printf("couldn't lock logins for %s: %v", key, err)
+		return
+	}
+	if throttle.lockedOut(failure.Failures) && !throttle.lockedOut(failure.Failures-1) {
+		server.recordAudit(ctx, 0, db.AuditUserLoginLockout, targetType, targetID, nil, gin.H{
+			"failures":     failure.Failures,
+			"locked_until": lockedUntil,
+		})
+	}
+}
+
+//Every password reset request counts like a failed login, against the email and the client ip
+//under their own keys so resets can't lock anyone out of logging in. Writes a 429 and returns
+//false once either is locked
+func (server *Server) throttlePasswordReset(ctx *gin.Context, email string) bool {
+	accountKey := "reset:" + accountThrottleKey(email)
+	ipKey := "reset:" + ipThrottleKey(ctx.ClientIP())
+	if !server.checkThrottleLock(ctx, accountKey, ipKey, "password_reset_locked", errPasswordResetLocked) {
+		return false
+	}
+	accountThrottle := server.accountThrottle()
+	server.throttleLogin(ctx, accountKey, accountThrottle, db.AuditTargetEmail, email)
+
+	ipThrottle := accountThrottle
+	ipThrottle.freeAttempts = server.config.LOGIN_IP_FREE_ATTEMPTS
+	server.throttleLogin(ctx, ipKey, ipThrottle, db.AuditTargetIP, ctx.ClientIP())
+	return true
+}
+
+//Verification emails are limited per user and per client ip the same way as password resets,
+//so an account can't be used to flood an inbox. Writes a 429 and returns false once either is locked
+func (server *Server) throttleEmailVerification(ctx *gin.Context, userID int64) bool {
+	accountKey := "verify:user:" + strconv.FormatInt(userID, 10)
+	ipKey := "verify:" + ipThrottleKey(ctx.ClientIP())
+	if !server.checkThrottleLock(ctx, accountKey, ipKey, "email_verification_locked", errVerificationLocked) {
+		return false
+	}
+	accountThrottle := server.accountThrottle()
+	server.throttleLogin(ctx, accountKey, accountThrottle, db.AuditTargetUser, strconv.FormatInt(userID, 10))
+
+	ipThrottle := accountThrottle
+	ipThrottle.freeAttempts = server.config.LOGIN_IP_FREE_ATTEMPTS
+	server.throttleLogin(ctx, ipKey, ipThrottle, db.AuditTargetIP, ctx.ClientIP())
+	return true
+}
+
+//A successful login clears the email's failures, the ip's keep counting down on their own
+func (server *Server) resetLoginThrottle(ctx *gin.Context, email string) {
+	server.resetThrottle(ctx, accountThrottleKey(email))
+}
+
+func (server *Server) resetThrottle(ctx *gin.Context, key string) {
+	if err := server.store.ResetLoginThrottle(ctx, key); err != nil {
+		log.Printf("couldn't reset failures for %s: %v", key, err)
+	}
+}
+
+var (
+	dummyPasswordOnce sync.Once
+	dummyPasswordHash string
+)
+
+//Logins for unknown emails are checked against this so they take as long as a wrong password
+func checkDummyPassword(password string) {
+	dummyPasswordOnce.Do(func() {
+		dummyPasswordHash, _ = util.HashPassword(util.GenerateString(16))
+	})
+	util.CheckPassword(password, dummyPasswordHash)
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+//Stubs the lock check of a login that isn't throttled
+func expectLoginAllowed(store *mock_db.MockStore) {
+	store.EXPECT().GetLoginLock(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+}
+
+//Stubs counting a failure for the email and the client ip, neither past its free attempts
+func expectLoginFailure(store *mock_db.MockStore, email string) {
+	store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(_ interface{}, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+			return db.LoginThrottle{Key: arg.Key, Failures: 1}, nil
+		})
+	store.EXPECT().LockLogin(gomock.Any(), gomock.Any()).Times(0)
+}
+
+func TestLoginThrottleDelay(t *testing.T) {
+	throttle := loginThrottle{
+		freeAttempts: 3,
+		backoff:      time.Second,
+		lockout:      time.Minute,
+	}
+	testCases := []struct {
+		failures  int32
+		delay     time.Duration
+		lockedOut bool
+	}{
+		{failures: 1, delay: 0},
+		{failures: 3, delay: 0},
+		{failures: 4, delay: time.Second},
+		{failures: 5, delay: 2 * time.Second},
+		{failures: 9, delay: 32 * time.Second},
+		{failures: 10, delay: time.Minute, lockedOut: true},
+		{failures: 1000, delay: time.Minute, lockedOut: true},
+	}
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.delay, throttle.delay(testCase.failures), "failures %d", testCase.failures)
+		require.Equal(t, testCase.lockedOut, throttle.lockedOut(testCase.failures), "failures %d", testCase.failures)
+	}
+
+	//Without a backoff failures are only counted
+	require.Zero(t, loginThrottle{}.delay(1000))
+}
+
+func TestLoginLockout(t *testing.T) {
+	user := generateRandomUser()
+	email := user.Email
+
+	testCases := []struct {
+		name          string
+		failures      map[string]int32
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Backoff",
+			failures: map[string]int32{accountThrottleKey(email): 4, ipThrottleKey(""): 4},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().LockLogin(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.LockLoginParams) error {
+						require.Equal(t, accountThrottleKey(email), arg.Key)
+						require.InDelta(t, time.Now().Add(time.Second).Unix(), arg.LockedUntil, 1)
+						return nil
+					})
+				expectAudit(t, store, 0, db.AuditUserLoginFailed, db.AuditTargetEmail, email)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "AccountLockout",
+			failures: map[string]int32{accountThrottleKey(email): 10, ipThrottleKey(""): 10},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().LockLogin(gomock.Any(), gomock.Any()).Times(1)
+				expectAudit(t, store, 0, db.AuditUserLoginLockout, db.AuditTargetEmail, email)
+				expectAudit(t, store, 0, db.AuditUserLoginFailed, db.AuditTargetEmail, email)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "StillLockedOut",
+			failures: map[string]int32{accountThrottleKey(email): 11, ipThrottleKey(""): 11},
+			buildStubs: func(store *mock_db.MockStore) {
+				//Only the failure that starts the lockout is audited
+				store.EXPECT().LockLogin(gomock.Any(), gomock.Any()).Times(1)
+				expectAudit(t, store, 0, db.AuditUserLoginFailed, db.AuditTargetEmail, email)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "IPLockout",
+			failures: map[string]int32{accountThrottleKey(email): 1, ipThrottleKey(""): 27},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().LockLogin(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.LockLoginParams) error {
+						require.Equal(t, ipThrottleKey(""), arg.Key)
+						return nil
+					})
+				expectAudit(t, store, 0, db.AuditUserLoginLockout, db.AuditTargetIP, "")
+				expectAudit(t, store, 0, db.AuditUserLoginFailed, db.AuditTargetEmail, email)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			expectLoginAllowed(store)
+			store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(email)).Times(1).Return(db.User{}, sql.ErrNoRows)
+			store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(2).
+				DoAndReturn(func(_ interface{}, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+					require.Less(t, arg.ForgetBefore, arg.Now)
+					return db.LoginThrottle{Key: arg.Key, Failures: testCase.failures[arg.Key]}, nil
+				})
+			testCase.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			server.config.LOGIN_FREE_ATTEMPTS = 3
+			server.config.LOGIN_IP_FREE_ATTEMPTS = 20
+			server.config.LOGIN_BACKOFF = time.Second
+			server.config.LOGIN_LOCKOUT_DURATION = time.Minute
+			server.config.LOGIN_FAILURE_WINDOW = time.Hour
+
+			data, err := json.Marshal(loginUserRequest{Email: email, Password: user.Password})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginThrottleClientIP(t *testing.T) {
+	email := "nobody@example.com"
+
+	testCases := []struct {
+		name           string
+		trustedProxies []string
+		ip             string
+	}{
+		{
+			name:           "NoTrustedProxies",
+			trustedProxies: nil,
+			ip:             "10.0.0.5",
+		},
+		{
+			name:           "TrustedProxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			ip:             "203.0.113.7",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			store.EXPECT().GetLoginLock(gomock.Any(), gomock.Eq(db.GetLoginLockParams{
+				AccountKey: accountThrottleKey(email),
+				IpKey:      ipThrottleKey(testCase.ip),
+			})).Times(1).Return(time.Now().Add(time.Minute).Unix(), nil)
+
+			server := NewTestServer(t, store)
+			server.config.TRUSTED_PROXIES = testCase.trustedProxies
+			require.NoError(t, server.setupRouter())
+
+			data, err := json.Marshal(loginUserRequest{Email: email, Password: "secret123"})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.RemoteAddr = "10.0.0.5:41234"
+			request.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package api
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
 
 	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
 	"github.com/faisal-a-n/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -13,14 +15,28 @@ import (
 
 func NewTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		SECRET_KEY:            util.GenerateString(32),
-		ACCESS_TOKEN_DURATION: time.Minute,
+		SECRET_KEY:                  util.GenerateString(32),
+		ACCESS_TOKEN_DURATION:       time.Minute,
+		PASSWORD_RESET_DURATION:     time.Minute,
+		EMAIL_VERIFICATION_DURATION: time.Hour,
+		LOGIN_CHALLENGE_DURATION:    time.Minute,
+		IDEMPOTENCY_KEY_TTL:         time.Hour,
 	}
 	server, err := NewServer(config, store)
 	require.NoError(t, err)
+
+	//Most tests don't care about revocation, so they don't need to stub the lookup
+	server.revocation = NewRevocationCache(allowAllRevocations{}, time.Minute, time.Minute)
+	require.NoError(t, server.setupRouter())
 	return server
 }
 
+type allowAllRevocations struct{}
+
+func (allowAllRevocations) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	return false, nil
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.ReleaseMode)
 	os.Exit(m.Run())
@@ -1,27 +1,41 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
 	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
 func addAuthorizationHeader(t *testing.T, request *http.Request, maker token.Maker,
 	userID int64, authKey string, authType string, durtation time.Duration) {
-	access_token, _, err := maker.CreateToken(userID, durtation)
+	access_token, _, err := maker.CreateToken(userID, uuid.New(), util.RoleCustomer, util.RoleScopes(util.RoleCustomer), durtation)
 	require.NoError(t, err)
 	require.NotEmpty(t, access_token)
 
 	request.Header.Add(authKey, fmt.Sprintf("%s %s", authType, access_token))
 }
 
+//Same as addAuthorizationHeader for a token with a role and scopes other than a customer's
+func addRoleAuthorizationHeader(t *testing.T, request *http.Request, maker token.Maker,
+	userID int64, role string, scopes []string) {
+	access_token, _, err := maker.CreateToken(userID, uuid.New(), role, scopes, time.Minute)
+	require.NoError(t, err)
+
+	request.Header.Add(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationType, access_token))
+}
+
 func TestMiddleware(t *testing.T) {
 	userID := int64(1)
 	testCases := []struct {
@@ -82,7 +96,7 @@ func TestMiddleware(t *testing.T) {
 			server := NewTestServer(t, nil)
 
 			authPath := "/auth"
-			server.router.GET(authPath, authMiddleware(server.tokenMaker), func(ctx *gin.Context) {
+			server.router.GET(authPath, authMiddleware(server.tokenMaker, server.revocation), func(ctx *gin.Context) {
 				ctx.JSON(http.StatusOK, gin.H{})
 			})
 
@@ -96,3 +110,80 @@ func TestMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthorizationMiddleware(t *testing.T) {
+	userID := int64(1)
+	testCases := []struct {
+		name          string
+		role          string
+		scopes        []string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			role:   util.RoleAdmin,
+			scopes: util.RoleScopes(util.RoleAdmin),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "WrongRole",
+			role:   util.RoleSupport,
+			scopes: util.RoleScopes(util.RoleSupport),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingScope",
+			role:   util.RoleAdmin,
+			scopes: []string{util.ScopeAccountsRead, util.ScopeAdminRead},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := NewTestServer(t, nil)
+
+			authPath := "/admin-only"
+			server.router.GET(authPath,
+				authMiddleware(server.tokenMaker, server.revocation),
+				requireRole(util.RoleAdmin),
+				requireScopes(util.ScopeAdminWrite),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			addRoleAuthorizationHeader(t, request, server.tokenMaker, userID, testCase.role, testCase.scopes)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReadOnlyToken(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+	store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+
+	body, err := json.Marshal(gin.H{"name": "savings", "currency": "USD"})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
+	require.NoError(t, err)
+	addRoleAuthorizationHeader(t, request, server.tokenMaker, 1, util.RoleCustomer, []string{util.ScopeAccountsRead})
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
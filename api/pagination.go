@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const cursorPrefix = "id:"
+
+var errInvalidCursor = errors.New("Invalid cursor")
+
+//Cursors are opaque to clients, they wrap the id of the last row of the previous page
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	value := string(data)
+	if !strings.HasPrefix(value, cursorPrefix) {
+		return 0, errInvalidCursor
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(value, cursorPrefix), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errInvalidCursor
+	}
+	return id, nil
+}
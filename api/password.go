@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+//Random bytes in a password reset token
+const passwordResetTokenSize = 32
+
+//How long the token and mail for a password reset may take once the request has been answered
+const passwordResetTimeout = 30 * time.Second
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+//Changing the password revokes every access token issued before it, including the one the
+//request was made with, so the session it came from gets a new one
+type changePasswordResponse struct {
+	AccessToken       string    `json:"access_token"`
+	AccessTokenExpiry time.Time `json:"access_token_expires_at"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+//Change the password of the logged in user and log out every other device
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err := util.CheckPassword(req.CurrentPassword, user.Password); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("Current password is incorrect")))
+		return
+	}
+	hash, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err = server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		UserID:    user.ID,
+		Password:  hash,
+		SessionID: authPayload.SessionID,
+		Actor:     auditActor(ctx, user.ID),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocation.RevokeUser(user.ID)
+
+	scopes := util.AllowedScopes(user.Role, authPayload.Scopes)
+	accessToken, payload, err := server.tokenMaker.CreateToken(user.ID, authPayload.SessionID, user.Role, scopes, server.config.ACCESS_TOKEN_DURATION)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := changePasswordResponse{
+		AccessToken:       accessToken,
+		AccessTokenExpiry: payload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Password has been changed", response))
+}
+
+//Mail a password reset token. The response is the same, and as quick, whether or not the
+//email is registered so the endpoint can't be used to find out who has an account
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	const message = "If the email is registered a password reset token has been sent to it"
+	if !server.throttlePasswordReset(ctx, req.Email) {
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusOK, responseHandler(200, message, nil))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	//The token and the mail are handled after responding, a registered email would otherwise
+	//take measurably longer to answer than an unknown one
+	server.background.Add(1)
+	go func() {
+		defer server.background.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetTimeout)
+		defer cancel()
+		if err := server.sendPasswordReset(ctx, user); err != nil {
+			log.Printf("couldn't send a password reset to user %d: %v", user.ID, err)
+		}
+	}()
+	ctx.JSON(http.StatusOK, responseHandler(200, message, nil))
+}
+
+//Replaces the user's reset tokens with a new one and mails it to them
+func (server *Server) sendPasswordReset(ctx context.Context, user db.User) error {
+	resetToken, err := util.NewSecretToken(passwordResetTokenSize)
+	if err != nil {
+		return err
+	}
+	//Only the latest mail can be used, older links stop working
+	now := time.Now()
+	err = server.store.RevokePasswordResetTokens(ctx, db.RevokePasswordResetTokensParams{
+		UsedAt: now.Unix(),
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = server.store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: util.HashSecretToken(resetToken),
+		ExpiresAt: now.Add(server.config.PASSWORD_RESET_DURATION).Unix(),
+		CreatedAt: now.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	return server.notifier.PasswordReset(ctx, user.Email, resetToken, server.config.PASSWORD_RESET_DURATION)
+}
+
+//Set a new password with a mailed reset token. Every session of the user is logged out
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	hash, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash: util.HashSecretToken(req.Token),
+		Password:  hash,
+		Actor:     auditActor(ctx, 0),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidResetToken) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocation.RevokeUser(user.ID)
+	ctx.JSON(http.StatusOK, responseHandler(200, "Password has been reset", nil))
+}
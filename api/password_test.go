@@ -0,0 +1,388 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/mail"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+//Keeps sent mail so tests can read the tokens out of it
+type mailRecorder struct {
+	messages []mail.Message
+	err      error
+}
+
+func (recorder *mailRecorder) Send(ctx context.Context, msg mail.Message) error {
+	if recorder.err != nil {
+		return recorder.err
+	}
+	recorder.messages = append(recorder.messages, msg)
+	return nil
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	user := generateRandomUser()
+	plainPassword := user.Password
+	hash, err := util.HashPassword(plainPassword)
+	require.NoError(t, err)
+	user.Password = hash
+	sessionID := uuid.New()
+	newPassword := util.GenerateString(10)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"current_password": plainPassword,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ChangePasswordTxParams) (db.User, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, sessionID, arg.SessionID)
+						require.Equal(t, user.ID, arg.Actor.ActorID)
+						require.NoError(t, util.CheckPassword(newPassword, arg.Password))
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data changePasswordResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				//The new token belongs to the same session and outlives the revocation
+				payload, err := server.tokenMaker.VerifyToken(response.Data.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, sessionID, payload.SessionID)
+				revoked, err := server.revocation.IsRevoked(context.Background(), payload)
+				require.NoError(t, err)
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "WrongCurrentPassword",
+			body: gin.H{
+				"current_password": plainPassword + "x",
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NewPasswordTooShort",
+			body: gin.H{
+				"current_password": plainPassword,
+				"new_password":     "abc",
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			body: gin.H{
+				"current_password": plainPassword,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPut, "/users/me/password", bytes.NewReader(data))
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken(user.ID, sessionID, user.Role, util.RoleScopes(user.Role), time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationType, accessToken))
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+var mailedToken = regexp.MustCompile(`(?m)^[A-Za-z0-9_-]{43}$`)
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user := generateRandomUser()
+
+	testCases := []struct {
+		name          string
+		email         string
+		mailErr       error
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder)
+	}{
+		{
+			name:  "OK",
+			email: user.Email,
+			buildStubs: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
+				expectLoginFailure(store, user.Email)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().RevokePasswordResetTokens(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RevokePasswordResetTokensParams) error {
+						require.Equal(t, user.ID, arg.UserID)
+						require.NotZero(t, arg.UsedAt)
+						return nil
+					})
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreatePasswordResetTokenParams) (db.PasswordResetToken, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, arg.CreatedAt+int64(time.Minute.Seconds()), arg.ExpiresAt)
+						return db.PasswordResetToken{ID: 1, UserID: user.ID, TokenHash: arg.TokenHash}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, mailer.messages, 1)
+				require.Equal(t, user.Email, mailer.messages[0].To)
+				require.Regexp(t, mailedToken, mailer.messages[0].Body)
+			},
+		},
+		{
+			name:  "UnknownEmail",
+			email: user.Email,
+			buildStubs: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
+				expectLoginFailure(store, user.Email)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				//Same answer as for a registered email
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, mailer.messages)
+			},
+		},
+		{
+			name:  "InvalidEmail",
+			email: "user",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "Throttled",
+			email: user.Email,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetLoginLock(gomock.Any(), gomock.Eq(db.GetLoginLockParams{
+					AccountKey: "reset:" + accountThrottleKey(user.Email),
+					IpKey:      "reset:" + ipThrottleKey(""),
+				})).Times(1).Return(time.Now().Add(time.Minute).Unix(), nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Empty(t, mailer.messages)
+			},
+		},
+		{
+			name:    "MailFailed",
+			email:   user.Email,
+			mailErr: errors.New("mail server is down"),
+			buildStubs: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
+				expectLoginFailure(store, user.Email)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().RevokePasswordResetTokens(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				//Answered before the mail was sent, so the failure is only logged
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, mailer.messages)
+			},
+		},
+		{
+			name:  "StoreFailed",
+			email: user.Email,
+			buildStubs: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
+				expectLoginFailure(store, user.Email)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().RevokePasswordResetTokens(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, mailer.messages)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+			mailer := &mailRecorder{err: testCase.mailErr}
+			server.notifier = mail.NewNotifier(mailer)
+
+			data, err := json.Marshal(gin.H{"email": testCase.email})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			server.background.Wait()
+			testCase.checkResponse(t, recorder, mailer)
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user := generateRandomUser()
+	resetToken, err := util.NewSecretToken(passwordResetTokenSize)
+	require.NoError(t, err)
+	newPassword := util.GenerateString(10)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ResetPasswordTxParams) (db.User, error) {
+						require.Equal(t, util.HashSecretToken(resetToken), arg.TokenHash)
+						require.NoError(t, util.CheckPassword(newPassword, arg.Password))
+						require.NotEmpty(t, arg.Actor.RequestID)
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidToken",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrInvalidResetToken)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				data, err := io.ReadAll(recorder.Body)
+				require.NoError(t, err)
+				require.Contains(t, string(data), db.ErrInvalidResetToken.Error())
+			},
+		},
+		{
+			name: "MissingToken",
+			body: gin.H{
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
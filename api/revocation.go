@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/google/uuid"
+)
+
+//Decides whether an access token was revoked before it expired
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, payload *token.Payload) (bool, error)
+}
+
+//Checks revocations against postgres. A token is revoked when its session was blocked
+//or the user changed their password after it was issued
+type storeRevocationChecker struct {
+	store db.Querier
+}
+
+func NewStoreRevocationChecker(store db.Querier) RevocationChecker {
+	return &storeRevocationChecker{store: store}
+}
+
+func (checker *storeRevocationChecker) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	state, err := checker.store.GetTokenRevocation(ctx, db.GetTokenRevocationParams{
+		ID:       payload.UserID,
+		FamilyID: payload.SessionID,
+	})
+	if err != nil {
+		//The user no longer exists
+		if err == sql.ErrNoRows {
+			return true, nil
+		}
+		return false, err
+	}
+	return state.SessionBlocked || payload.IssuedAt.UnixMilli() < state.PasswordChangedAt, nil
+}
+
+type cachedRevocation struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+//Caches revocation checks in memory so most requests don't reach the database.
+//Revocations made through this server apply at once, ones made by other replicas
+//apply when the cached result expires
+type RevocationCache struct {
+	next      RevocationChecker
+	ttl       time.Duration
+	retention time.Duration
+
+	mu        sync.Mutex
+	checked   map[uuid.UUID]cachedRevocation
+	sessions  map[uuid.UUID]time.Time
+	users     map[int64]time.Time
+	lastSweep time.Time
+}
+
+//Creates a cache in front of next. Results are kept for ttl and local revocations for retention,
+//which should be at least the access token duration since older tokens have expired anyway
+func NewRevocationCache(next RevocationChecker, ttl, retention time.Duration) *RevocationCache {
+	return &RevocationCache{
+		next:      next,
+		ttl:       ttl,
+		retention: retention,
+		checked:   make(map[uuid.UUID]cachedRevocation),
+		sessions:  make(map[uuid.UUID]time.Time),
+		users:     make(map[int64]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func (cache *RevocationCache) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	now := time.Now()
+
+	cache.mu.Lock()
+	revoked, ok := cache.lookup(payload, now)
+	cache.mu.Unlock()
+	if ok {
+		return revoked, nil
+	}
+
+	revoked, err := cache.next.IsRevoked(ctx, payload)
+	if err != nil {
+		return false, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.checked[payload.ID] = cachedRevocation{revoked: revoked, expiresAt: now.Add(cache.ttl)}
+	if now.Sub(cache.lastSweep) > cache.ttl {
+		cache.sweep(now)
+	}
+	return revoked, nil
+}
+
+func (cache *RevocationCache) lookup(payload *token.Payload, now time.Time) (revoked bool, ok bool) {
+	if _, found := cache.sessions[payload.SessionID]; found {
+		return true, true
+	}
+	if revokedAt, found := cache.users[payload.UserID]; found && payload.IssuedAt.Before(revokedAt) {
+		return true, true
+	}
+	if entry, found := cache.checked[payload.ID]; found && now.Before(entry.expiresAt) {
+		return entry.revoked, true
+	}
+	return false, false
+}
+
+//Drops expired results and revocations old enough that every token they cover has expired
+func (cache *RevocationCache) sweep(now time.Time) {
+	for id, entry := range cache.checked {
+		if !now.Before(entry.expiresAt) {
+			delete(cache.checked, id)
+		}
+	}
+	for id, revokedAt := range cache.sessions {
+		if now.Sub(revokedAt) > cache.retention {
+			delete(cache.sessions, id)
+		}
+	}
+	for id, revokedAt := range cache.users {
+		if now.Sub(revokedAt) > cache.retention {
+			delete(cache.users, id)
+		}
+	}
+	cache.lastSweep = now
+}
+
+//Revokes every access token issued for the session
+func (cache *RevocationCache) RevokeSession(sessionID uuid.UUID) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.sessions[sessionID] = time.Now()
+}
+
+//Revokes every access token issued to the user until now
+func (cache *RevocationCache) RevokeUser(userID int64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.users[userID] = time.Now()
+}
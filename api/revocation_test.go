@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+//Counts lookups so tests can tell when the cache answered
+type countingRevocations struct {
+	revoked bool
+	err     error
+	calls   int
+}
+
+func (checker *countingRevocations) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	checker.calls++
+	return checker.revoked, checker.err
+}
+
+func randomPayload(t *testing.T) *token.Payload {
+	payload, err := token.NewPayload(generateRandomUser().ID, uuid.New(), util.RoleCustomer, util.RoleScopes(util.RoleCustomer), time.Minute)
+	require.NoError(t, err)
+	return payload
+}
+
+func TestStoreRevocationChecker(t *testing.T) {
+	payload := randomPayload(t)
+
+	testCases := []struct {
+		name        string
+		state       db.GetTokenRevocationRow
+		err         error
+		checkResult func(t *testing.T, revoked bool, err error)
+	}{
+		{
+			name:  "Valid",
+			state: db.GetTokenRevocationRow{PasswordChangedAt: payload.IssuedAt.Add(-time.Hour).UnixMilli()},
+			checkResult: func(t *testing.T, revoked bool, err error) {
+				require.NoError(t, err)
+				require.False(t, revoked)
+			},
+		},
+		{
+			name:  "SessionBlocked",
+			state: db.GetTokenRevocationRow{SessionBlocked: true},
+			checkResult: func(t *testing.T, revoked bool, err error) {
+				require.NoError(t, err)
+				require.True(t, revoked)
+			},
+		},
+		{
+			name:  "PasswordChanged",
+			state: db.GetTokenRevocationRow{PasswordChangedAt: payload.IssuedAt.Add(time.Minute).UnixMilli()},
+			checkResult: func(t *testing.T, revoked bool, err error) {
+				require.NoError(t, err)
+				require.True(t, revoked)
+			},
+		},
+		{
+			name:  "PasswordChangedJustAfter",
+			state: db.GetTokenRevocationRow{PasswordChangedAt: payload.IssuedAt.Add(time.Millisecond).UnixMilli()},
+			checkResult: func(t *testing.T, revoked bool, err error) {
+				require.NoError(t, err)
+				require.True(t, revoked)
+			},
+		},
+		{
+			name:  "IssuedJustAfterChange",
+			state: db.GetTokenRevocationRow{PasswordChangedAt: payload.IssuedAt.Add(-time.Millisecond).UnixMilli()},
+			checkResult: func(t *testing.T, revoked bool, err error) {
+				require.NoError(t, err)
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "UserNotFound",
+			err:  sql.ErrNoRows,
+			checkResult: func(t *testing.T, revoked bool, err error) {
+				require.NoError(t, err)
+				require.True(t, revoked)
+			},
+		},
+		{
+			name: "InternalError",
+			err:  sql.ErrConnDone,
+			checkResult: func(t *testing.T, revoked bool, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			store.EXPECT().GetTokenRevocation(gomock.Any(), gomock.Eq(db.GetTokenRevocationParams{
+				ID:       payload.UserID,
+				FamilyID: payload.SessionID,
+			})).Times(1).Return(testCase.state, testCase.err)
+
+			revoked, err := NewStoreRevocationChecker(store).IsRevoked(context.Background(), payload)
+			testCase.checkResult(t, revoked, err)
+		})
+	}
+}
+
+func TestRevocationCache(t *testing.T) {
+	next := &countingRevocations{}
+	cache := NewRevocationCache(next, time.Minute, time.Minute)
+	payload := randomPayload(t)
+
+	//The second check is answered from the cache
+	for i := 0; i < 2; i++ {
+		revoked, err := cache.IsRevoked(context.Background(), payload)
+		require.NoError(t, err)
+		require.False(t, revoked)
+	}
+	require.Equal(t, 1, next.calls)
+
+	cache.RevokeSession(payload.SessionID)
+	revoked, err := cache.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	//Revoking a user only affects tokens issued before it
+	older := randomPayload(t)
+	cache.RevokeUser(older.UserID)
+	newer, err := token.NewPayload(older.UserID, uuid.New(), util.RoleCustomer, util.RoleScopes(util.RoleCustomer), time.Minute)
+	require.NoError(t, err)
+
+	revoked, err = cache.IsRevoked(context.Background(), older)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = cache.IsRevoked(context.Background(), newer)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	//Errors are not cached
+	next.err = sql.ErrConnDone
+	_, err = cache.IsRevoked(context.Background(), randomPayload(t))
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestMiddlewareRevocation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		checker       *countingRevocations
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "Revoked",
+			checker: &countingRevocations{revoked: true},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:    "CheckFailed",
+			checker: &countingRevocations{err: sql.ErrConnDone},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := NewTestServer(t, nil)
+
+			authPath := "/auth"
+			server.router.GET(authPath, authMiddleware(server.tokenMaker, testCase.checker), func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{})
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, 1, authorizationHeaderKey, authorizationType, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/scheduler"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountID   int64      `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID     int64      `json:"to_account_id" binding:"required_without=ToAccountNumber,excluded_with=ToAccountNumber,omitempty,min=1"`
+	ToAccountNumber string     `json:"to_account_number" binding:"omitempty,account_number"`
+	Amount          util.Money `json:"amount"`
+	StartAt         int64      `json:"start_at" binding:"required,min=1"`
+	Recurrence      string     `json:"recurrence"`
+	EndAt           int64      `json:"end_at" binding:"omitempty,min=1"`
+	TotpCode        string     `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+type scheduledTransferResponse struct {
+	ID            int64      `json:"id"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        util.Money `json:"amount"`
+	Recurrence    string     `json:"recurrence"`
+	ScheduledAt   int64      `json:"scheduled_at"`
+	NextAttemptAt int64      `json:"next_attempt_at"`
+	EndAt         int64      `json:"end_at"`
+	Attempts      int32      `json:"attempts"`
+	Status        string     `json:"status"`
+	CreatedAt     int64      `json:"created_at"`
+}
+
+type scheduledTransferDetailsResponse struct {
+	scheduledTransferResponse
+	Runs []db.ScheduledTransferRun `json:"runs"`
+}
+
+func scheduledTransferResponseBuilder(scheduled db.ScheduledTransfer) scheduledTransferResponse {
+	return scheduledTransferResponse{
+		ID:            scheduled.ID,
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        util.NewMoney(scheduled.Amount, scheduled.Currency),
+		Recurrence:    scheduled.Recurrence,
+		ScheduledAt:   scheduled.ScheduledAt,
+		NextAttemptAt: scheduled.NextAttemptAt,
+		EndAt:         scheduled.EndAt,
+		Attempts:      scheduled.Attempts,
+		Status:        scheduled.Status,
+		CreatedAt:     scheduled.CreatedAt,
+	}
+}
+
+//Schedule a one-off transfer for a later date or a standing order with a recurrence rule
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !checkPositiveAmount(ctx, req.Amount) {
+		return
+	}
+	if req.StartAt < time.Now().Unix() {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("start_at must not be in the past")))
+		return
+	}
+	if req.EndAt > 0 && req.EndAt < req.StartAt {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("end_at must not be before start_at")))
+		return
+	}
+	recurrence := ""
+	startAt := req.StartAt
+	if len(req.Recurrence) > 0 {
+		rule, err := scheduler.ParseRule(req.Recurrence)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		//The first payment is the first day matching the rule, which can be later than start_at
+		first := rule.First(time.Unix(req.StartAt, 0))
+		if req.EndAt > 0 && first.Unix() > req.EndAt {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("end_at is before the first occurrence")))
+			return
+		}
+		startAt = first.Unix()
+		recurrence = rule.Anchor(first).String()
+	}
+
+	fromAccount, fromCheck := server.checkCurrency(ctx, req.FromAccountID, req.Amount.Currency)
+	if !fromCheck {
+		return
+	}
+	toAccount, toCheck := server.getTransferTarget(ctx, req.ToAccountID, req.ToAccountNumber)
+	if !toCheck {
+		return
+	}
+	if _, toCheck = checkAccountCurrency(ctx, toAccount, req.Amount.Currency); !toCheck {
+		return
+	}
+	if !checkOwnership(ctx, fromAccount) {
+		return
+	}
+	if !server.checkTransferStepUp(ctx, fromAccount.UserID, req.Amount, req.TotpCode) {
+		return
+	}
+
+	scheduled, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		UserID:        fromAccount.UserID,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   toAccount.ID,
+		Amount:        req.Amount.Amount,
+		Currency:      req.Amount.Currency,
+		Recurrence:    recurrence,
+		ScheduledAt:   startAt,
+		NextAttemptAt: startAt,
+		EndAt:         req.EndAt,
+		CreatedAt:     time.Now().Unix(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusCreated, responseHandler(200, "Transfer has been scheduled", scheduledTransferResponseBuilder(scheduled)))
+}
+
+//List the user's scheduled transfers
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req getAccountsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	list, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		UserID: authPayload.UserID,
+		Limit:  req.Count,
+		Offset: (req.PageID - 1) * req.Count,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]scheduledTransferResponse, len(list))
+	for i, scheduled := range list {
+		response[i] = scheduledTransferResponseBuilder(scheduled)
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", response))
+}
+
+//Get a scheduled transfer with the outcome of every execution
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	scheduled, ok := server.getOwnScheduledTransfer(ctx)
+	if !ok {
+		return
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, scheduled.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := scheduledTransferDetailsResponse{
+		scheduledTransferResponse: scheduledTransferResponseBuilder(scheduled),
+		Runs:                      runs,
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", response))
+}
+
+//Cancel a scheduled transfer, runs that already happened are kept
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	scheduled, ok := server.getOwnScheduledTransfer(ctx)
+	if !ok {
+		return
+	}
+
+	cancelled, err := server.store.CancelScheduledTransfer(ctx, db.CancelScheduledTransferParams{
+		ID:     scheduled.ID,
+		UserID: scheduled.UserID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := fmt.Errorf("Scheduled transfer is already %s", scheduled.Status)
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Scheduled transfer has been cancelled", scheduledTransferResponseBuilder(cancelled)))
+}
+
+func (server *Server) getOwnScheduledTransfer(ctx *gin.Context) (db.ScheduledTransfer, bool) {
+	var req getAccountReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return db.ScheduledTransfer{}, false
+	}
+
+	scheduled, err := server.store.GetScheduledTransfer(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No scheduled transfer with this id")))
+			return db.ScheduledTransfer{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.ScheduledTransfer{}, false
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	if scheduled.UserID != authPayload.UserID {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("Scheduled transfer does not belong to the user")))
+		return db.ScheduledTransfer{}, false
+	}
+	return scheduled, true
+}
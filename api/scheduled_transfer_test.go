@@ -0,0 +1,310 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	fromAccount := randomAccountWithCurrency("USD")
+	toAccount := randomAccountWithCurrency("USD")
+	startAt := time.Date(2100, time.January, 31, 9, 0, 0, 0, time.UTC).Unix()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"value": "12.50", "currency": "USD"},
+				"start_at":        startAt,
+				"recurrence":      "freq=monthly",
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, fromAccount.UserID, arg.UserID)
+						require.Equal(t, int64(1250), arg.Amount)
+						require.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=31", arg.Recurrence)
+						require.Equal(t, startAt, arg.ScheduledAt)
+						require.Equal(t, startAt, arg.NextAttemptAt)
+						return db.ScheduledTransfer{ID: 1, Amount: arg.Amount, Currency: arg.Currency, Status: db.ScheduledTransferActive}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "FirstMatchingDay",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"value": "12.50", "currency": "USD"},
+				"start_at":        startAt,
+				"recurrence":      "FREQ=MONTHLY;BYMONTHDAY=30",
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						//The 30th of January has passed, February has no 30th
+						first := time.Date(2100, time.February, 28, 9, 0, 0, 0, time.UTC).Unix()
+						require.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=30", arg.Recurrence)
+						require.Equal(t, first, arg.ScheduledAt)
+						require.Equal(t, first, arg.NextAttemptAt)
+						return db.ScheduledTransfer{ID: 1, Amount: arg.Amount, Currency: arg.Currency, Status: db.ScheduledTransferActive}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "EndBeforeFirstOccurrence",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"value": "12.50", "currency": "USD"},
+				"start_at":        startAt,
+				"end_at":          startAt + 24*60*60,
+				"recurrence":      "FREQ=MONTHLY;BYMONTHDAY=15",
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "StartInPast",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": 100, "currency": "USD"},
+				"start_at":        time.Now().Add(-time.Hour).Unix(),
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "EndBeforeStart",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": 100, "currency": "USD"},
+				"start_at":        startAt,
+				"end_at":          startAt - 1,
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidRecurrence",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": 100, "currency": "USD"},
+				"start_at":        startAt,
+				"recurrence":      "FREQ=SECONDLY",
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": 100, "currency": "USD"},
+				"start_at":        startAt,
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(randomAccountWithCurrency("EUR"), nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+
+			server := NewTestServer(t, store)
+			body, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/scheduled-transfers", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCancelScheduledTransferAPI(t *testing.T) {
+	scheduled := db.ScheduledTransfer{
+		ID:       5,
+		UserID:   9,
+		Amount:   100,
+		Currency: "USD",
+		Status:   db.ScheduledTransferActive,
+	}
+	cancelled := scheduled
+	cancelled.Status = db.ScheduledTransferCancelled
+
+	testCases := []struct {
+		name          string
+		userID        int64
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			userID: scheduled.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(scheduled, nil)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Eq(db.CancelScheduledTransferParams{
+					ID:     scheduled.ID,
+					UserID: scheduled.UserID,
+				})).Times(1).Return(cancelled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "AlreadyFinished",
+			userID: scheduled.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(cancelled, nil)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Any()).Times(1).Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:   "DifferentOwner",
+			userID: scheduled.UserID + 1,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(scheduled, nil)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "NotFound",
+			userID: scheduled.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+
+			server := NewTestServer(t, store)
+			url := fmt.Sprintf("/scheduled-transfers/%d", scheduled.ID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, testCase.userID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetScheduledTransferAPI(t *testing.T) {
+	scheduled := db.ScheduledTransfer{ID: 5, UserID: 9, Amount: 100, Currency: "USD", Status: db.ScheduledTransferActive}
+	runs := []db.ScheduledTransferRun{
+		{ID: 1, ScheduledTransferID: scheduled.ID, Status: db.ScheduledRunRetrying, Attempt: 1, Error: db.ErrInsufficientFunds.Error()},
+		{ID: 2, ScheduledTransferID: scheduled.ID, Status: db.ScheduledRunSucceeded, Attempt: 2, TransactionID: 30},
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(scheduled, nil)
+	store.EXPECT().ListScheduledTransferRuns(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(runs, nil)
+
+	server := NewTestServer(t, store)
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/scheduled-transfers/%d", scheduled.ID), nil)
+	require.NoError(t, err)
+	addAuthorizationHeader(t, request, server.tokenMaker, scheduled.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Data struct {
+			ID   int64                     `json:"id"`
+			Runs []db.ScheduledTransferRun `json:"runs"`
+		} `json:"data"`
+	}
+	err = json.Unmarshal(recorder.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Equal(t, scheduled.ID, response.Data.ID)
+	require.Equal(t, runs, response.Data.Runs)
+}
@@ -2,8 +2,11 @@ package api
 
 import (
 	"fmt"
+	"sync"
 
 	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/fx"
+	"github.com/faisal-a-n/simplebank/mail"
 	"github.com/faisal-a-n/simplebank/token"
 	"github.com/faisal-a-n/simplebank/util"
 	"github.com/gin-gonic/gin"
@@ -17,44 +20,141 @@ type Server struct {
 	router     *gin.Engine
 	tokenMaker token.Maker
 	config     util.Config
+	fxProvider fx.FXRateProvider
+	revocation *RevocationCache
+	notifier   mail.Notifier
+	//Minor units per currency above which transfers need a totp code
+	stepUpThresholds map[string]int64
+	//Work handlers leave running after they respond, tests wait for it before checking the results
+	background sync.WaitGroup
 }
 
 //Create new server and setup routing
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.SECRET_KEY)
+	tokenMaker, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("Cannot create token maker: %v", err)
 	}
 	server := &Server{store: store, tokenMaker: tokenMaker, config: config}
+	server.revocation = NewRevocationCache(NewStoreRevocationChecker(store), config.REVOCATION_CACHE_TTL, config.ACCESS_TOKEN_DURATION)
+
+	switch config.SESSION_POLICY {
+	case "", sessionPolicyMultiple, sessionPolicySingle:
+	default:
+		return nil, fmt.Errorf("Unknown session policy %q", config.SESSION_POLICY)
+	}
+
+	//Cross currency transfers are disabled when no rates are configured
+	if len(config.FX_RATES_FILE) > 0 {
+		server.fxProvider, err = fx.NewFileRateProvider(config.FX_RATES_FILE)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot load exchange rates: %v", err)
+		}
+		if config.FX_RATES_MAX_AGE > 0 {
+			server.fxProvider = fx.NewMaxAgeRateProvider(server.fxProvider, config.FX_RATES_MAX_AGE)
+		}
+	}
+
+	server.stepUpThresholds, err = parseStepUpThresholds(config.TRANSFER_2FA_THRESHOLDS)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot parse transfer 2fa thresholds: %v", err)
+	}
+
+	mailer := mail.NewLogSender()
+	if len(config.MAIL_DIR) > 0 {
+		mailer, err = mail.NewFileSender(config.MAIL_DIR)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot create mail sender: %v", err)
+		}
+	}
+	server.notifier = mail.NewNotifier(mailer)
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("account_number", validAccountNumber)
+		v.RegisterStructValidation(validMoney, util.Money{})
 	}
 
-	server.setupRouter()
+	if err := server.setupRouter(); err != nil {
+		return nil, fmt.Errorf("Cannot set trusted proxies: %v", err)
+	}
 
 	return server, nil
 }
 
-func (server *Server) setupRouter() {
+func (server *Server) setupRouter() error {
 	router := gin.Default()
+	//The client ip is used to throttle logins, so X-Forwarded-For is only read from our own proxies
+	if err := router.SetTrustedProxies(server.config.TRUSTED_PROXIES); err != nil {
+		return err
+	}
+	router.Use(requestIDMiddleware())
 
 	//add routes to router
 
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/users/login/2fa", server.loginTwoFactor)
+	router.POST("/users/password/forgot", server.forgotPassword)
+	router.POST("/users/password/reset", server.resetPassword)
+	router.POST("/users/verify-email", server.verifyEmail)
 
 	router.POST("/token/refresh", server.renewToken)
 
-	authGroup := router.Group("/").Use(authMiddleware(server.tokenMaker))
-
-	authGroup.POST("/accounts", server.createAccount)
-	authGroup.GET("/accounts/:id", server.getAccount)
-	authGroup.GET("/accounts", server.getAccounts)
-
-	authGroup.POST("/transfers", server.createTransfer)
+	router.GET("/currencies", server.listCurrencies)
+	router.GET("/.well-known/keys", server.listKeys)
+
+	authGroup := router.Group("/", authMiddleware(server.tokenMaker, server.revocation))
+
+	//Sessions belong to whoever holds the token, so they need no scope
+	authGroup.POST("/users/logout", server.logoutUser)
+	authGroup.POST("/users/logout-all", server.logoutAllSessions)
+	authGroup.GET("/sessions", server.listSessions)
+	authGroup.GET("/users/me", server.getProfile)
+	authGroup.PATCH("/users/me", server.updateProfile)
+	authGroup.PUT("/users/me/password", server.changePassword)
+	authGroup.POST("/users/verify-email/resend", server.resendEmailVerification)
+	authGroup.POST("/users/me/2fa/totp", server.enrollTotp)
+	authGroup.POST("/users/me/2fa/totp/confirm", server.confirmTotp)
+	authGroup.POST("/users/me/2fa/disable", server.disableTwoFactor)
+	authGroup.DELETE("/sessions/:id", server.deleteSession)
+
+	readGroup := authGroup.Group("/", requireScopes(util.ScopeAccountsRead))
+	readGroup.GET("/accounts/:id", server.getAccount)
+	readGroup.GET("/accounts", server.getAccounts)
+	readGroup.GET("/accounts/:id/transfers", server.listAccountTransfers)
+	readGroup.GET("/accounts/:id/statement", server.getStatement)
+	readGroup.GET("/transfers/:id", server.getTransfer)
+	readGroup.GET("/scheduled-transfers", server.listScheduledTransfers)
+	readGroup.GET("/scheduled-transfers/:id", server.getScheduledTransfer)
+
+	accountsGroup := authGroup.Group("/", requireScopes(util.ScopeAccountsWrite))
+	accountsGroup.POST("/accounts", server.requireVerifiedEmail(), idempotencyMiddleware(server.store, server.config.IDEMPOTENCY_KEY_TTL), server.createAccount)
+	accountsGroup.POST("/accounts/:id/close", idempotencyMiddleware(server.store, server.config.IDEMPOTENCY_KEY_TTL), server.closeAccount)
+	accountsGroup.POST("/accounts/:id/reopen", server.reopenAccount)
+
+	transfersGroup := authGroup.Group("/", requireScopes(util.ScopeTransfersWrite))
+	transfersGroup.POST("/transfers", server.requireVerifiedEmail(), idempotencyMiddleware(server.store, server.config.IDEMPOTENCY_KEY_TTL), server.createTransfer)
+	transfersGroup.POST("/fx/quotes", server.createFxQuote)
+	transfersGroup.POST("/scheduled-transfers", server.requireVerifiedEmail(), idempotencyMiddleware(server.store, server.config.IDEMPOTENCY_KEY_TTL), server.createScheduledTransfer)
+	transfersGroup.DELETE("/scheduled-transfers/:id", server.cancelScheduledTransfer)
+
+	//Support staff can look things up, only admins change anything
+	adminGroup := authGroup.Group("/admin", requireRole(util.RoleSupport, util.RoleAdmin), requireScopes(util.ScopeAdminRead))
+	adminGroup.GET("/users", server.searchUsers)
+	adminGroup.GET("/accounts/:id", server.adminGetAccount)
+	adminGroup.GET("/audit-log", server.listAuditLog)
+	adminGroup.GET("/audit-log/verify", server.verifyAuditLog)
+
+	adminWriteGroup := adminGroup.Group("/", requireRole(util.RoleAdmin), requireScopes(util.ScopeAdminWrite))
+	adminWriteGroup.PUT("/users/:id/role", server.updateUserRole)
+	adminWriteGroup.POST("/users/:id/sessions/block", server.blockUserSessions)
+	adminWriteGroup.POST("/accounts/:id/freeze", server.freezeAccount)
+	adminWriteGroup.POST("/accounts/:id/unfreeze", server.unfreezeAccount)
+	adminWriteGroup.POST("/accounts/:id/adjustments", server.adjustBalance)
 
 	server.router = router
+	return nil
 }
 
 func (server *Server) Start(address string) error {
@@ -68,6 +168,13 @@ func errorResponse(err error) gin.H {
 	}
 }
 
+//Same as errorResponse with a stable code clients can branch on instead of the message
+func errorCodeResponse(code string, err error) gin.H {
+	response := errorResponse(err)
+	response["error_code"] = code
+	return response
+}
+
 func responseHandler(code int, message string, data interface{}) gin.H {
 	return gin.H{
 		"code":    code,
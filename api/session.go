@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+//Login policies for SESSION_POLICY. With the single policy a login logs out every other device
+const (
+	sessionPolicyMultiple = "multiple"
+	sessionPolicySingle   = "single"
+)
+
+//A session keeps its family id across token refreshes, so that is the id clients see
+type sessionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	CreatedAt int64     `json:"created_at"`
+	ExpiresAt int64     `json:"expires_at"`
+}
+
+type deleteSessionRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+func sessionResponseBuilder(session db.Session) sessionResponse {
+	return sessionResponse{
+		ID:        session.FamilyID,
+		UserAgent: session.UserAgent,
+		ClientIp:  session.ClientIp,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+	}
+}
+
+//List the devices the user is logged in on
+func (server *Server) listSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	list, err := server.store.ListActiveSessions(ctx, db.ListActiveSessionsParams{
+		UserID:    authPayload.UserID,
+		ExpiresAt: time.Now().Unix(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]sessionResponse, len(list))
+	for i, session := range list {
+		response[i] = sessionResponseBuilder(session)
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", response))
+}
+
+//Log out a single device
+func (server *Server) deleteSession(ctx *gin.Context) {
+	var req deleteSessionRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	rows, err := server.store.BlockSessionFamily(ctx, db.BlockSessionFamilyParams{
+		UserID:   authPayload.UserID,
+		FamilyID: uuid.MustParse(req.ID),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No session with this id")))
+		return
+	}
+	server.revocation.RevokeSession(uuid.MustParse(req.ID))
+	ctx.JSON(http.StatusOK, responseHandler(200, "Session has been revoked", nil))
+}
+
+//Log out the device the access token was issued to
+func (server *Server) logoutUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	_, err := server.store.BlockSessionFamily(ctx, db.BlockSessionFamilyParams{
+		UserID:   authPayload.UserID,
+		FamilyID: authPayload.SessionID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocation.RevokeSession(authPayload.SessionID)
+	ctx.JSON(http.StatusOK, responseHandler(200, "You have logged out", nil))
+}
+
+//Log out every device
+func (server *Server) logoutAllSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	err := server.store.UpdateSession(ctx, db.UpdateSessionParams{
+		IsBlocked: true,
+		UserID:    authPayload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocation.RevokeUser(authPayload.UserID)
+	ctx.JSON(http.StatusOK, responseHandler(200, "You have logged out of every device", nil))
+}
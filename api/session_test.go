@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/goccy/go-json"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSessionsAPI(t *testing.T) {
+	user := generateRandomUser()
+	sessions := []db.Session{randomSession(user.ID), randomSession(user.ID)}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, maker token.Maker)
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListActiveSessions(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListActiveSessionsParams) ([]db.Session, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						return sessions, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data []sessionResponse `json:"data"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Len(t, response.Data, len(sessions))
+				require.Equal(t, sessions[0].FamilyID, response.Data[0].ID)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListActiveSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListActiveSessions(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/sessions", nil)
+			require.NoError(t, err)
+
+			testCase.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDeleteSessionAPI(t *testing.T) {
+	user := generateRandomUser()
+	familyID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		id            string
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   familyID.String(),
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().BlockSessionFamily(gomock.Any(), gomock.Eq(db.BlockSessionFamilyParams{
+					UserID:   user.ID,
+					FamilyID: familyID,
+				})).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			id:   familyID.String(),
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().BlockSessionFamily(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   "not-a-uuid",
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().BlockSessionFamily(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/sessions/%s", testCase.id)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLogoutUserAPI(t *testing.T) {
+	user := generateRandomUser()
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+
+	sessionID := uuid.New()
+	accessToken, payload, err := server.tokenMaker.CreateToken(user.ID, sessionID, util.RoleCustomer, util.RoleScopes(util.RoleCustomer), time.Minute)
+	require.NoError(t, err)
+
+	store.EXPECT().BlockSessionFamily(gomock.Any(), gomock.Eq(db.BlockSessionFamilyParams{
+		UserID:   user.ID,
+		FamilyID: sessionID,
+	})).Times(1).Return(int64(1), nil)
+
+	request, err := http.NewRequest(http.MethodPost, "/users/logout", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationType, accessToken))
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	//The access token stops working straight away
+	revoked, err := server.revocation.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestLogoutAllSessionsAPI(t *testing.T) {
+	user := generateRandomUser()
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	store.EXPECT().UpdateSession(gomock.Any(), gomock.Eq(db.UpdateSessionParams{
+		IsBlocked: true,
+		UserID:    user.ID,
+	})).Times(1).Return(nil)
+
+	server := NewTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodPost, "/users/logout-all", nil)
+	require.NoError(t, err)
+
+	addAuthorizationHeader(t, request, server.tokenMaker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func randomSession(userID int64) db.Session {
+	id := uuid.New()
+	return db.Session{
+		ID:        id,
+		UserID:    userID,
+		FamilyID:  id,
+		UserAgent: "Mozilla/5.0",
+		ClientIp:  "127.0.0.1",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		CreatedAt: time.Now().Unix(),
+	}
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+)
+
+//Returned when retiring a key would leave nothing to sign tokens with
+var ErrLastSigningKey = errors.New("Can't retire the only active signing key")
+
+func configTokenType(config util.Config) string {
+	if len(config.TOKEN_TYPE) == 0 {
+		return tokenTypePaseto
+	}
+	return config.TOKEN_TYPE
+}
+
+func isSharedSecret(tokenType string) bool {
+	return tokenType == tokenTypePaseto || tokenType == tokenTypeJWT
+}
+
+//Loads the keys for the configured token type. The key from the config is used while the table
+//has no active keys. Verifier only servers never hold private keys, they load the public halves
+func (server *Server) LoadKeys(ctx context.Context) error {
+	maker, ok := server.tokenMaker.(token.KeyringMaker)
+	if !ok {
+		return nil
+	}
+	tokenType := configTokenType(server.config)
+	verifierOnly := !isSharedSecret(tokenType) && len(server.config.TOKEN_PRIVATE_KEY_FILE) == 0
+
+	rows, err := server.store.ListActiveSigningKeys(ctx, tokenType)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		//Nothing added yet or every key retired, either way the config key is the one to trust
+		key, err := configKey(server.config, tokenType)
+		if err != nil {
+			return err
+		}
+		return maker.SetKeys([]token.Key{key})
+	}
+	keys := make([]token.Key, len(rows))
+	for i, row := range rows {
+		if verifierOnly {
+			keys[i], err = verifyingKeyFromRow(row)
+		} else {
+			keys[i], err = signingKeyFromRow(row, server.config.TOKEN_KEY_ENCRYPTION_KEY)
+		}
+		if err != nil {
+			return fmt.Errorf("key %s: %v", row.ID, err)
+		}
+	}
+	return maker.SetKeys(keys)
+}
+
+//Reloads the signing keys on an interval so keys added or retired with the keys command reach
+//every running server without a restart
+func (server *Server) WatchKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := server.LoadKeys(ctx); err != nil {
+				log.Printf("couldn't reload signing keys: %v", err)
+			}
+		}
+	}
+}
+
+func signingKeyFromRow(row db.SigningKey, encryptionKey string) (token.Key, error) {
+	key := token.Key{ID: row.ID, NotBefore: time.Unix(row.NotBefore, 0)}
+	secret, err := openSigningSecret(row.Secret, encryptionKey)
+	if err != nil {
+		return key, err
+	}
+	if isSharedSecret(row.TokenType) {
+		key.Secret = []byte(secret)
+		return key, nil
+	}
+
+	privateKey, err := token.ParsePrivateKey([]byte(secret))
+	if err != nil {
+		return key, err
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return key, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	key.PrivateKey = privateKey
+	key.PublicKey = signer.Public()
+	return key, nil
+}
+
+func verifyingKeyFromRow(row db.SigningKey) (token.Key, error) {
+	key := token.Key{ID: row.ID, NotBefore: time.Unix(row.NotBefore, 0)}
+	if len(row.PublicKey) == 0 {
+		return key, errors.New("no public key is stored, run keys encrypt")
+	}
+	publicKey, err := token.ParsePublicKey([]byte(row.PublicKey))
+	if err != nil {
+		return key, err
+	}
+	key.PublicKey = publicKey
+	return key, nil
+}
+
+//Stored secrets start with this, rows written before secrets were encrypted don't
+const encryptedSecretPrefix = "enc:"
+
+func sealSigningSecret(secret string, encryptionKey string) (string, error) {
+	if len(encryptionKey) == 0 {
+		return "", errors.New("TOKEN_KEY_ENCRYPTION_KEY is needed to store signing keys")
+	}
+	sealed, err := util.EncryptSecret(encryptionKey, secret)
+	if err != nil {
+		return "", err
+	}
+	return encryptedSecretPrefix + sealed, nil
+}
+
+func openSigningSecret(stored string, encryptionKey string) (string, error) {
+	if !strings.HasPrefix(stored, encryptedSecretPrefix) {
+		return stored, nil
+	}
+	if len(encryptionKey) == 0 {
+		return "", errors.New("TOKEN_KEY_ENCRYPTION_KEY is needed to read signing keys")
+	}
+	return util.DecryptSecret(encryptionKey, strings.TrimPrefix(stored, encryptedSecretPrefix))
+}
+
+//The PEM encoded public half of a private key, shared secrets have none
+func signingPublicKey(tokenType string, secret string) (string, error) {
+	if isSharedSecret(tokenType) {
+		return "", nil
+	}
+	privateKey, err := token.ParsePrivateKey([]byte(secret))
+	if err != nil {
+		return "", err
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	data, err := token.EncodePublicKey(signer.Public())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func newSigningKeyParams(config util.Config, tokenType string, id string, secret string) (db.CreateSigningKeyParams, error) {
+	publicKey, err := signingPublicKey(tokenType, secret)
+	if err != nil {
+		return db.CreateSigningKeyParams{}, err
+	}
+	sealed, err := sealSigningSecret(secret, config.TOKEN_KEY_ENCRYPTION_KEY)
+	if err != nil {
+		return db.CreateSigningKeyParams{}, err
+	}
+	return db.CreateSigningKeyParams{
+		ID:        id,
+		TokenType: tokenType,
+		Secret:    sealed,
+		PublicKey: publicKey,
+	}, nil
+}
+
+//Adds a freshly generated key that starts signing after activateIn, which should be longer than
+//the key refresh so every server verifies it first. The first key added for a token type stores
+//the config key as the legacy key so tokens issued before rotation stay valid
+func AddSigningKey(ctx context.Context, store db.Querier, config util.Config, activateIn time.Duration) (db.SigningKey, error) {
+	tokenType := configTokenType(config)
+	existing, err := store.ListSigningKeys(ctx, tokenType)
+	if err != nil {
+		return db.SigningKey{}, err
+	}
+
+	now := time.Now()
+	if len(existing) == 0 {
+		secret, err := configSigningSecret(config, tokenType)
+		if err != nil {
+			return db.SigningKey{}, err
+		}
+		arg, err := newSigningKeyParams(config, tokenType, token.LegacyKeyID, secret)
+		if err != nil {
+			return db.SigningKey{}, err
+		}
+		arg.CreatedAt = now.Unix()
+		if _, err = store.CreateSigningKey(ctx, arg); err != nil {
+			return db.SigningKey{}, err
+		}
+	}
+
+	secret, err := generateSigningSecret(tokenType)
+	if err != nil {
+		return db.SigningKey{}, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return db.SigningKey{}, err
+	}
+	arg, err := newSigningKeyParams(config, tokenType, hex.EncodeToString(id), secret)
+	if err != nil {
+		return db.SigningKey{}, err
+	}
+	arg.NotBefore = now.Add(activateIn).Unix()
+	arg.CreatedAt = now.Unix()
+	return store.CreateSigningKey(ctx, arg)
+}
+
+//Encrypts the keys stored before secrets were encrypted and stores their public halves, so
+//verifier only servers can load them. Returns how many keys were converted
+func EncryptSigningKeys(ctx context.Context, store db.Querier, config util.Config) (int, error) {
+	tokenType := configTokenType(config)
+	keys, err := store.ListSigningKeys(ctx, tokenType)
+	if err != nil {
+		return 0, err
+	}
+	converted := 0
+	for _, key := range keys {
+		if strings.HasPrefix(key.Secret, encryptedSecretPrefix) {
+			continue
+		}
+		arg, err := newSigningKeyParams(config, tokenType, key.ID, key.Secret)
+		if err != nil {
+			return converted, fmt.Errorf("key %s: %v", key.ID, err)
+		}
+		_, err = store.UpdateSigningKeySecret(ctx, db.UpdateSigningKeySecretParams{
+			Secret:    arg.Secret,
+			PublicKey: arg.PublicKey,
+			TokenType: tokenType,
+			ID:        key.ID,
+		})
+		if err != nil {
+			return converted, err
+		}
+		converted++
+	}
+	return converted, nil
+}
+
+//Retires a key so tokens it signed stop verifying. The only key that can already sign is
+//never retired since no server could issue tokens afterwards
+func RetireSigningKey(ctx context.Context, store db.Querier, config util.Config, id string) (db.SigningKey, error) {
+	tokenType := configTokenType(config)
+	keys, err := store.ListActiveSigningKeys(ctx, tokenType)
+	if err != nil {
+		return db.SigningKey{}, err
+	}
+	now := time.Now().Unix()
+	remaining := 0
+	for _, key := range keys {
+		if key.ID != id && key.NotBefore <= now {
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		return db.SigningKey{}, ErrLastSigningKey
+	}
+
+	return store.RetireSigningKey(ctx, db.RetireSigningKeyParams{
+		RetiredAt: now,
+		TokenType: tokenType,
+		ID:        id,
+	})
+}
+
+//The key newTokenMaker starts with, so a server goes back to it once no key in the table is active
+func configKey(config util.Config, tokenType string) (token.Key, error) {
+	key := token.Key{ID: token.LegacyKeyID}
+	if isSharedSecret(tokenType) {
+		key.Secret = []byte(config.SECRET_KEY)
+		return key, nil
+	}
+	if len(config.TOKEN_PRIVATE_KEY_FILE) == 0 {
+		publicKey, err := token.LoadPublicKey(config.TOKEN_PUBLIC_KEY_FILE)
+		key.PublicKey = publicKey
+		return key, err
+	}
+	privateKey, err := token.LoadPrivateKey(config.TOKEN_PRIVATE_KEY_FILE)
+	if err != nil {
+		return key, err
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return key, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	key.PrivateKey = privateKey
+	key.PublicKey = signer.Public()
+	return key, nil
+}
+
+func configSigningSecret(config util.Config, tokenType string) (string, error) {
+	if isSharedSecret(tokenType) {
+		return config.SECRET_KEY, nil
+	}
+	if len(config.TOKEN_PRIVATE_KEY_FILE) == 0 {
+		return "", fmt.Errorf("%s keys can only be rotated with a private key file", tokenType)
+	}
+	data, err := os.ReadFile(config.TOKEN_PRIVATE_KEY_FILE)
+	if err != nil {
+		return "", err
+	}
+	//Checked here so a bad file is never stored as a key
+	if _, err := token.ParsePrivateKey(data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func generateSigningSecret(tokenType string) (string, error) {
+	var privateKey crypto.PrivateKey
+	var err error
+	switch tokenType {
+	case tokenTypePaseto, tokenTypeJWT:
+		//24 random bytes encode to the 32 characters the paseto maker needs
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(secret), nil
+	case tokenTypePasetoPublic, tokenTypeJWTEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	case tokenTypeJWTRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return "", fmt.Errorf("Unknown token type %q", tokenType)
+	}
+	if err != nil {
+		return "", err
+	}
+	data, err := token.EncodePrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
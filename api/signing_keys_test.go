@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKeys(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+
+	//Signed with the config key before any keys were added
+	oldToken, _, err := server.tokenMaker.CreateToken(1, uuid.New(), util.RoleCustomer, util.RoleScopes(util.RoleCustomer), time.Minute)
+	require.NoError(t, err)
+
+	current := db.SigningKey{ID: "current", TokenType: tokenTypePaseto, Secret: util.GenerateString(32), NotBefore: time.Now().Add(-time.Minute).Unix()}
+	next := db.SigningKey{ID: "next", TokenType: tokenTypePaseto, Secret: util.GenerateString(32), NotBefore: time.Now().Add(time.Hour).Unix()}
+	legacy := db.SigningKey{ID: token.LegacyKeyID, TokenType: tokenTypePaseto, Secret: server.config.SECRET_KEY}
+
+	store.EXPECT().ListActiveSigningKeys(gomock.Any(), gomock.Eq(tokenTypePaseto)).Times(1).Return([]db.SigningKey{legacy, current, next}, nil)
+	require.NoError(t, server.LoadKeys(context.Background()))
+
+	_, err = server.tokenMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	//The current key signs, the one that is not active yet only verifies
+	newToken, _, err := server.tokenMaker.CreateToken(1, uuid.New(), util.RoleCustomer, util.RoleScopes(util.RoleCustomer), time.Minute)
+	require.NoError(t, err)
+	_, err = server.tokenMaker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	store.EXPECT().ListActiveSigningKeys(gomock.Any(), gomock.Eq(tokenTypePaseto)).Times(1).Return([]db.SigningKey{current}, nil)
+	require.NoError(t, server.LoadKeys(context.Background()))
+
+	//Retiring the legacy key invalidates tokens signed with it but not newer ones
+	_, err = server.tokenMaker.VerifyToken(oldToken)
+	require.Error(t, err)
+	_, err = server.tokenMaker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	//Once every key is retired the server goes back to the config key instead of keeping stale ones
+	store.EXPECT().ListActiveSigningKeys(gomock.Any(), gomock.Eq(tokenTypePaseto)).Times(1).Return([]db.SigningKey{}, nil)
+	require.NoError(t, server.LoadKeys(context.Background()))
+	_, err = server.tokenMaker.VerifyToken(newToken)
+	require.Error(t, err)
+	_, err = server.tokenMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+}
+
+func TestAddSigningKey(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	config := util.Config{TOKEN_TYPE: tokenTypeJWT, SECRET_KEY: util.GenerateString(32), TOKEN_KEY_ENCRYPTION_KEY: util.GenerateString(32)}
+	store := mock_db.NewMockStore(controller)
+
+	//The config key is stored as the legacy key the first time, secrets are only stored encrypted
+	store.EXPECT().ListSigningKeys(gomock.Any(), gomock.Eq(tokenTypeJWT)).Times(1).Return([]db.SigningKey{}, nil)
+	store.EXPECT().CreateSigningKey(gomock.Any(), gomock.Any()).Times(2).DoAndReturn(func(ctx context.Context, arg db.CreateSigningKeyParams) (db.SigningKey, error) {
+		require.Equal(t, tokenTypeJWT, arg.TokenType)
+		require.Empty(t, arg.PublicKey)
+		secret, err := openSigningSecret(arg.Secret, config.TOKEN_KEY_ENCRYPTION_KEY)
+		require.NoError(t, err)
+		require.NotEqual(t, secret, arg.Secret)
+		if arg.ID == token.LegacyKeyID {
+			require.Equal(t, config.SECRET_KEY, secret)
+			require.Zero(t, arg.NotBefore)
+		} else {
+			require.Len(t, secret, 32)
+			require.Greater(t, arg.NotBefore, time.Now().Unix())
+		}
+		return db.SigningKey{ID: arg.ID, TokenType: arg.TokenType, Secret: arg.Secret, NotBefore: arg.NotBefore}, nil
+	})
+
+	key, err := AddSigningKey(context.Background(), store, config, time.Hour)
+	require.NoError(t, err)
+	require.NotEqual(t, token.LegacyKeyID, key.ID)
+
+	_, err = signingKeyFromRow(key, config.TOKEN_KEY_ENCRYPTION_KEY)
+	require.NoError(t, err)
+	_, err = signingKeyFromRow(key, util.GenerateString(32))
+	require.Error(t, err)
+
+	//Keys are never stored in plaintext
+	config.TOKEN_KEY_ENCRYPTION_KEY = ""
+	store.EXPECT().ListSigningKeys(gomock.Any(), gomock.Eq(tokenTypeJWT)).Times(1).Return([]db.SigningKey{key}, nil)
+	_, err = AddSigningKey(context.Background(), store, config, time.Hour)
+	require.Error(t, err)
+}
+
+func TestLoadKeysVerifierOnly(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	server := NewTestServer(t, store)
+	_, publicKeyFile := writeTestKeyPair(t)
+	server.config.TOKEN_TYPE = tokenTypePasetoPublic
+	server.config.TOKEN_PUBLIC_KEY_FILE = publicKeyFile
+	verifier, err := newTokenMaker(server.config)
+	require.NoError(t, err)
+	server.tokenMaker = verifier
+
+	//A key added by a signing server, this one can't decrypt it
+	secret, err := generateSigningSecret(tokenTypePasetoPublic)
+	require.NoError(t, err)
+	arg, err := newSigningKeyParams(util.Config{TOKEN_KEY_ENCRYPTION_KEY: util.GenerateString(32)}, tokenTypePasetoPublic, "rotated", secret)
+	require.NoError(t, err)
+	row := db.SigningKey{ID: arg.ID, TokenType: arg.TokenType, Secret: arg.Secret, PublicKey: arg.PublicKey}
+
+	signingKey, err := signingKeyFromRow(db.SigningKey{ID: row.ID, TokenType: row.TokenType, Secret: secret}, "")
+	require.NoError(t, err)
+	signer := &token.PasetoPublicMaker{}
+	require.NoError(t, signer.SetKeys([]token.Key{signingKey}))
+	accessToken, _, err := signer.CreateToken(1, uuid.New(), util.RoleCustomer, util.RoleScopes(util.RoleCustomer), time.Minute)
+	require.NoError(t, err)
+
+	_, err = server.tokenMaker.VerifyToken(accessToken)
+	require.Error(t, err)
+
+	store.EXPECT().ListActiveSigningKeys(gomock.Any(), gomock.Eq(tokenTypePasetoPublic)).Times(1).Return([]db.SigningKey{row}, nil)
+	require.NoError(t, server.LoadKeys(context.Background()))
+
+	_, err = server.tokenMaker.VerifyToken(accessToken)
+	require.NoError(t, err)
+	_, _, err = server.tokenMaker.CreateToken(1, uuid.New(), util.RoleCustomer, util.RoleScopes(util.RoleCustomer), time.Minute)
+	require.ErrorIs(t, err, token.ErrVerifierOnly)
+
+	//Keys stored before public keys were kept have to be converted first
+	row.PublicKey = ""
+	store.EXPECT().ListActiveSigningKeys(gomock.Any(), gomock.Eq(tokenTypePasetoPublic)).Times(1).Return([]db.SigningKey{row}, nil)
+	require.Error(t, server.LoadKeys(context.Background()))
+	_, err = server.tokenMaker.VerifyToken(accessToken)
+	require.NoError(t, err)
+
+	//With every key retired only the config public key verifies
+	store.EXPECT().ListActiveSigningKeys(gomock.Any(), gomock.Eq(tokenTypePasetoPublic)).Times(1).Return([]db.SigningKey{}, nil)
+	require.NoError(t, server.LoadKeys(context.Background()))
+	_, err = server.tokenMaker.VerifyToken(accessToken)
+	require.Error(t, err)
+}
+
+func TestEncryptSigningKeys(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	config := util.Config{TOKEN_TYPE: tokenTypeJWTEdDSA, TOKEN_KEY_ENCRYPTION_KEY: util.GenerateString(32)}
+	store := mock_db.NewMockStore(controller)
+
+	secret, err := generateSigningSecret(tokenTypeJWTEdDSA)
+	require.NoError(t, err)
+	encrypted, err := newSigningKeyParams(config, tokenTypeJWTEdDSA, "encrypted", secret)
+	require.NoError(t, err)
+	keys := []db.SigningKey{
+		{ID: token.LegacyKeyID, TokenType: tokenTypeJWTEdDSA, Secret: secret},
+		{ID: encrypted.ID, TokenType: tokenTypeJWTEdDSA, Secret: encrypted.Secret, PublicKey: encrypted.PublicKey},
+	}
+
+	//Only the plaintext key is converted
+	store.EXPECT().ListSigningKeys(gomock.Any(), gomock.Eq(tokenTypeJWTEdDSA)).Times(1).Return(keys, nil)
+	store.EXPECT().UpdateSigningKeySecret(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(func(ctx context.Context, arg db.UpdateSigningKeySecretParams) (db.SigningKey, error) {
+		require.Equal(t, token.LegacyKeyID, arg.ID)
+		require.Equal(t, tokenTypeJWTEdDSA, arg.TokenType)
+		require.Equal(t, encrypted.PublicKey, arg.PublicKey)
+		opened, err := openSigningSecret(arg.Secret, config.TOKEN_KEY_ENCRYPTION_KEY)
+		require.NoError(t, err)
+		require.Equal(t, secret, opened)
+		return db.SigningKey{ID: arg.ID, TokenType: arg.TokenType, Secret: arg.Secret, PublicKey: arg.PublicKey}, nil
+	})
+
+	converted, err := EncryptSigningKeys(context.Background(), store, config)
+	require.NoError(t, err)
+	require.Equal(t, 1, converted)
+}
+
+func TestGenerateSigningSecret(t *testing.T) {
+	for _, tokenType := range []string{tokenTypePasetoPublic, tokenTypeJWTEdDSA, tokenTypeJWTRS256} {
+		secret, err := generateSigningSecret(tokenType)
+		require.NoError(t, err)
+
+		key, err := signingKeyFromRow(db.SigningKey{ID: "key", TokenType: tokenType, Secret: secret}, "")
+		require.NoError(t, err)
+		require.NotNil(t, key.PrivateKey)
+		require.NotNil(t, key.PublicKey)
+
+		publicKey, err := signingPublicKey(tokenType, secret)
+		require.NoError(t, err)
+		verifyingKey, err := verifyingKeyFromRow(db.SigningKey{ID: "key", TokenType: tokenType, PublicKey: publicKey})
+		require.NoError(t, err)
+		require.Equal(t, key.PublicKey, verifyingKey.PublicKey)
+		require.Nil(t, verifyingKey.PrivateKey)
+	}
+
+	_, err := generateSigningSecret("unknown")
+	require.Error(t, err)
+}
+
+func TestRetireSigningKey(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	config := util.Config{SECRET_KEY: util.GenerateString(32)}
+	store := mock_db.NewMockStore(controller)
+	active := []db.SigningKey{
+		{ID: token.LegacyKeyID, TokenType: tokenTypePaseto},
+		{ID: "next", TokenType: tokenTypePaseto, NotBefore: time.Now().Add(time.Hour).Unix()},
+	}
+
+	//The key waiting to activate can't sign yet, so the legacy key has to stay
+	store.EXPECT().ListActiveSigningKeys(gomock.Any(), gomock.Eq(tokenTypePaseto)).Times(1).Return(active, nil)
+	store.EXPECT().RetireSigningKey(gomock.Any(), gomock.Any()).Times(0)
+	_, err := RetireSigningKey(context.Background(), store, config, token.LegacyKeyID)
+	require.ErrorIs(t, err, ErrLastSigningKey)
+
+	active[1].NotBefore = time.Now().Add(-time.Hour).Unix()
+	store.EXPECT().ListActiveSigningKeys(gomock.Any(), gomock.Eq(tokenTypePaseto)).Times(1).Return(active, nil)
+	store.EXPECT().RetireSigningKey(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(func(ctx context.Context, arg db.RetireSigningKeyParams) (db.SigningKey, error) {
+		require.Equal(t, token.LegacyKeyID, arg.ID)
+		require.Equal(t, tokenTypePaseto, arg.TokenType)
+		require.NotZero(t, arg.RetiredAt)
+		return db.SigningKey{ID: arg.ID, RetiredAt: arg.RetiredAt}, nil
+	})
+	key, err := RetireSigningKey(context.Background(), store, config, token.LegacyKeyID)
+	require.NoError(t, err)
+	require.NotZero(t, key.RetiredAt)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/export"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mimeCSV = "text/csv"
+	mimeOFX = "application/x-ofx"
+)
+
+type statementExporter struct {
+	extension string
+	write     func(w io.Writer, statement db.StatementTxResult) error
+}
+
+//Download formats offered on the statement route, camt.053 is served for XML
+var statementExporters = map[string]statementExporter{
+	mimeCSV:      {extension: "csv", write: export.WriteCSV},
+	mimeOFX:      {extension: "ofx", write: export.WriteOFX},
+	gin.MIMEXML:  {extension: "xml", write: export.WriteCAMT053},
+	gin.MIMEXML2: {extension: "xml", write: export.WriteCAMT053},
+}
+
+type getStatementReq struct {
+	From int64 `form:"from" binding:"omitempty,min=0"`
+	To   int64 `form:"to" binding:"omitempty,min=0"`
+}
+
+type statementLineResponse struct {
+	ID                    int64      `json:"id"`
+	Amount                util.Money `json:"amount"`
+	Balance               util.Money `json:"balance"`
+	CreatedAt             int64      `json:"created_at"`
+	TransactionID         int64      `json:"transaction_id,omitempty"`
+	CounterpartyAccountID int64      `json:"counterparty_account_id,omitempty"`
+}
+
+type statementResponse struct {
+	Account        accountResponse         `json:"account"`
+	FromTime       int64                   `json:"from_time"`
+	ToTime         int64                   `json:"to_time"`
+	OpeningBalance util.Money              `json:"opening_balance"`
+	ClosingBalance util.Money              `json:"closing_balance"`
+	Lines          []statementLineResponse `json:"entries"`
+}
+
+func statementResponseBuilder(statement db.StatementTxResult) statementResponse {
+	currency := statement.Account.Currency
+	response := statementResponse{
+		Account:        accountResponseBuilder(statement.Account),
+		FromTime:       statement.FromTime,
+		ToTime:         statement.ToTime,
+		OpeningBalance: util.NewMoney(statement.OpeningBalance, currency),
+		ClosingBalance: util.NewMoney(statement.ClosingBalance, currency),
+		Lines:          make([]statementLineResponse, len(statement.Lines)),
+	}
+	for i, line := range statement.Lines {
+		response.Lines[i] = statementLineResponse{
+			ID:                    line.ID,
+			Amount:                util.NewMoney(line.Amount, currency),
+			Balance:               util.NewMoney(line.Balance, currency),
+			CreatedAt:             line.CreatedAt,
+			TransactionID:         line.TransactionID,
+			CounterpartyAccountID: line.CounterpartyAccountID,
+		}
+	}
+	return response
+}
+
+//Get account statement with running balances for a period.
+//The Accept header picks between JSON and the CSV, OFX and camt.053 downloads
+func (server *Server) getStatement(ctx *gin.Context) {
+	format := ctx.NegotiateFormat(gin.MIMEJSON, mimeCSV, mimeOFX, gin.MIMEXML, gin.MIMEXML2)
+	if format == "" {
+		ctx.JSON(http.StatusNotAcceptable, errorResponse(errors.New("Statement is available as JSON, CSV, OFX or camt.053 XML")))
+		return
+	}
+
+	var uri getAccountReq
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+	var req getStatementReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.StatementTxParams{
+		AccountID: uri.ID,
+		FromTime:  req.From,
+		ToTime:    req.To,
+	}
+	//Period end is exclusive, so default to the next second to include everything posted so far
+	if arg.ToTime == 0 {
+		arg.ToTime = time.Now().Unix() + 1
+	}
+	if arg.FromTime >= arg.ToTime {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("from must be before to")))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No account with this id")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !checkOwnership(ctx, account) {
+		return
+	}
+
+	statement, err := server.store.StatementTx(ctx, arg)
+	if err != nil {
+		//Drifted balances stay wrong until the ledger is reconciled, so retrying won't help
+		if errors.Is(err, db.ErrLedgerMismatch) {
+			ctx.JSON(http.StatusConflict, errorCodeResponse("ledger_mismatch", err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	exporter, ok := statementExporters[format]
+	if !ok {
+		ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", statementResponseBuilder(statement)))
+		return
+	}
+	var buffer bytes.Buffer
+	if err := exporter.write(&buffer, statement); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	filename := fmt.Sprintf("statement-%d-%d-%d.%s", statement.Account.ID, statement.FromTime, statement.ToTime, exporter.extension)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, format+"; charset=utf-8", buffer.Bytes())
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"time"
 
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,8 +17,10 @@ type renewTokenRequest struct {
 }
 
 type renewTokenResponse struct {
-	AccessToken       string    `json:"access_token"`
-	AccessTokenExpiry time.Time `json:"access_token_expires_at"`
+	AccessToken        string    `json:"access_token"`
+	AccessTokenExpiry  time.Time `json:"access_token_expires_at"`
+	RefreshToken       string    `json:"refresh_token"`
+	RefreshTokenExpiry time.Time `json:"refresh_token_expires_at"`
 }
 
 func (server *Server) renewToken(ctx *gin.Context) {
@@ -56,15 +60,77 @@ func (server *Server) renewToken(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, errorResponse(fmt.Errorf("Refresh token mismatch")))
 		return
 	}
+	if session.ConsumedAt != 0 {
+		server.revokeSessionFamily(ctx, session)
+		return
+	}
+
+	//Role changes apply on the next refresh, the scopes never grow past the ones granted at login
+	user, err := server.store.GetUser(ctx, refreshToken.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	scopes := util.AllowedScopes(user.Role, refreshToken.Scopes)
 
-	access_token, payload, err := server.tokenMaker.CreateToken(refreshToken.UserID, server.config.ACCESS_TOKEN_DURATION)
+	access_token, payload, err := server.tokenMaker.CreateToken(refreshToken.UserID, session.FamilyID, user.Role, scopes, server.config.ACCESS_TOKEN_DURATION)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	refresh_token, refreshTokenPayload, err := server.tokenMaker.CreateToken(refreshToken.UserID, session.FamilyID, user.Role, scopes, server.config.REFRESH_TOKEN_DURATION)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	_, err = server.store.RotateSessionTx(ctx, db.RotateSessionTxParams{
+		ID:         session.ID,
+		ConsumedAt: refreshTokenPayload.IssuedAt.Unix(),
+		Session: db.CreateSessionParams{
+			ID:           refreshTokenPayload.ID,
+			RefreshToken: refresh_token,
+			UserAgent:    ctx.Request.UserAgent(),
+			ClientIp:     ctx.ClientIP(),
+			ExpiresAt:    refreshTokenPayload.ExpiredAt.Unix(),
+			CreatedAt:    refreshTokenPayload.IssuedAt.Unix(),
+		},
+	})
+	if err != nil {
+		//Lost a race with another refresh using the same token
+		if errors.Is(err, db.ErrSessionReused) {
+			server.revokeSessionFamily(ctx, session)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.recordAudit(ctx, user.ID, db.AuditSessionRefresh, db.AuditTargetSession, session.FamilyID.String(), gin.H{
+		"session_id": session.ID,
+		"role":       refreshToken.Role,
+	}, gin.H{
+		"session_id": refreshTokenPayload.ID,
+		"role":       user.Role,
+	})
 	response := renewTokenResponse{
-		AccessToken:       access_token,
-		AccessTokenExpiry: payload.ExpiredAt,
+		AccessToken:        access_token,
+		AccessTokenExpiry:  payload.ExpiredAt,
+		RefreshToken:       refresh_token,
+		RefreshTokenExpiry: refreshTokenPayload.ExpiredAt,
 	}
 	ctx.JSON(http.StatusCreated, responseHandler(200, "Token refreshed", response))
 }
+
+//A refresh token that was already exchanged is being replayed, so either the client or an attacker
+//holds a stolen copy. Every session descended from the same login is blocked to log both out
+func (server *Server) revokeSessionFamily(ctx *gin.Context, session db.Session) {
+	_, err := server.store.BlockSessionFamily(ctx, db.BlockSessionFamilyParams{
+		UserID:   session.UserID,
+		FamilyID: session.FamilyID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocation.RevokeSession(session.FamilyID)
+	ctx.JSON(http.StatusUnauthorized, errorResponse(db.ErrSessionReused))
+}
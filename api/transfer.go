@@ -4,99 +4,252 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	db "github.com/faisal-a-n/simplebank/db/sqlc"
 	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+//The receiving account is given either by id or by account number. The amount carries its
+//currency and can be sent in minor units, as a decimal value or both
 type createTransferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,min=1"`
-	Currency      string `json:"currency" binding:"required,currency"`
+	FromAccountID   int64      `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID     int64      `json:"to_account_id" binding:"required_without=ToAccountNumber,excluded_with=ToAccountNumber,omitempty,min=1"`
+	ToAccountNumber string     `json:"to_account_number" binding:"omitempty,account_number"`
+	Amount          util.Money `json:"amount"`
+	QuoteID         string     `json:"quote_id" binding:"omitempty,uuid"`
+	TotpCode        string     `json:"totp_code" binding:"omitempty,len=6,numeric"`
 }
 
-type transferEntries struct {
-	fromEntry db.Entry
-	toEntry   db.Entry
+type entryResponse struct {
+	ID        int64      `json:"id"`
+	AccountID int64      `json:"account_id"`
+	Amount    util.Money `json:"amount"`
+	CreatedAt int64      `json:"created_at"`
+}
+
+type transferResponse struct {
+	ID            int64      `json:"id"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	FromEntryID   int64      `json:"from_entry_id"`
+	ToEntryID     int64      `json:"to_entry_id"`
+	Amount        util.Money `json:"amount"`
+	ToAmount      util.Money `json:"to_amount"`
+	Rate          string     `json:"rate"`
+	CreatedAt     int64      `json:"created_at"`
+}
+
+type createTransferResponse struct {
+	Transfer    transferResponse `json:"transfer"`
+	FromAccount accountResponse  `json:"from_account"`
+	ToAccount   accountResponse  `json:"to_account"`
+	FromEntry   entryResponse    `json:"from_entry"`
+	ToEntry     entryResponse    `json:"to_entry"`
+}
+
+func entryResponseBuilder(entry db.Entry, currency string) entryResponse {
+	return entryResponse{
+		ID:        entry.ID,
+		AccountID: entry.AccountID,
+		Amount:    util.NewMoney(entry.Amount, currency),
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+func transferResponseBuilder(transfer db.Transaction) transferResponse {
+	return transferResponse{
+		ID:            transfer.ID,
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		FromEntryID:   transfer.FromEntryID,
+		ToEntryID:     transfer.ToEntryID,
+		Amount:        util.NewMoney(transfer.Amount, transfer.FromCurrency),
+		ToAmount:      util.NewMoney(transfer.ToAmount, transfer.ToCurrency),
+		Rate:          transfer.Rate,
+		CreatedAt:     transfer.CreatedAt,
+	}
+}
+
+//Only positive amounts can be moved between accounts
+func checkPositiveAmount(ctx *gin.Context, amount util.Money) bool {
+	if amount.IsZero() || amount.IsNegative() {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("amount must be greater than zero")))
+		return false
+	}
+	return true
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
 	var req createTransferRequest
-	var err error
-	if err = ctx.ShouldBindJSON(&req); err != nil {
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if !checkPositiveAmount(ctx, req.Amount) {
+		return
+	}
 
-	fromAccount, fromCheck := server.checkCurrency(ctx, req.FromAccountID, req.Currency)
+	fromAccount, fromCheck := server.checkCurrency(ctx, req.FromAccountID, req.Amount.Currency)
 	if !fromCheck {
 		return
 	}
-	_, toCheck := server.checkCurrency(ctx, req.ToAccountID, req.Currency)
+	toAccount, toCheck := server.getTransferTarget(ctx, req.ToAccountID, req.ToAccountNumber)
 	if !toCheck {
 		return
 	}
 
-	if !checkOwnershipAndBalance(ctx, fromAccount, req.Amount) {
+	if !checkOwnership(ctx, fromAccount) {
+		return
+	}
+	if !server.checkTransferStepUp(ctx, fromAccount.UserID, req.Amount, req.TotpCode) {
 		return
 	}
 
-	fromEntryParams := buildEntryParams(req.FromAccountID, -req.Amount)
-	toEntryParams := buildEntryParams(req.ToAccountID, req.Amount)
-	entries := transferEntries{}
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	arg := db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   toAccount.ID,
+		Amount:        req.Amount.Amount,
+		CreatedAt:     time.Now().Unix(),
+		Actor:         auditActor(ctx, authPayload.UserID),
+	}
 
-	entries.fromEntry, err = server.store.CreateEntry(ctx, fromEntryParams)
-	if err != nil {
+	if toAccount.Currency != fromAccount.Currency {
+		if !server.setExchange(ctx, &arg, req, toAccount.Currency) {
+			return
+		}
+	} else if len(req.QuoteID) > 0 {
+		err := errors.New("Quotes can only be used for cross currency transfers")
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
-	entries.toEntry, err = server.store.CreateEntry(ctx, toEntryParams)
+
+	result, err := server.store.TransferTx(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		if status, code := accountErrorCode(err); status != 0 {
+			ctx.JSON(status, errorCodeResponse(code, err))
+			return
+		}
+		if errors.Is(err, db.ErrQuoteUnavailable) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	response := createTransferResponse{
+		Transfer:    transferResponseBuilder(result.Transaction),
+		FromAccount: accountResponseBuilder(result.FromAccount),
+		ToAccount:   accountResponseBuilder(result.ToAccount),
+		FromEntry:   entryResponseBuilder(result.FromEntry, result.FromAccount.Currency),
+		ToEntry:     entryResponseBuilder(result.ToEntry, result.ToAccount.Currency),
+	}
+	ctx.JSON(http.StatusCreated, responseHandler(200, "Transaction has been made", response))
+}
 
-	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
-		FromEntryID:   entries.fromEntry.ID,
-		ToEntryID:     entries.toEntry.ID,
-		Amount:        req.Amount,
-		CreatedAt:     time.Now().Unix(),
+//Sets the converted amount and rate for a cross currency transfer, either from the
+//user's locked quote or from the current rate when no quote is given
+func (server *Server) setExchange(ctx *gin.Context, arg *db.TransferTxParams, req createTransferRequest, toCurrency string) bool {
+	if len(req.QuoteID) == 0 {
+		rate, toAmount, ok := server.convert(ctx, req.Amount.Amount, req.Amount.Currency, toCurrency)
+		if !ok {
+			return false
+		}
+		arg.Rate = rate
+		arg.ToAmount = toAmount
+		return true
 	}
 
-	transaction, err := server.store.TransferTx(ctx, arg)
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	quote, err := server.store.GetFxQuote(ctx, uuid.MustParse(req.QuoteID))
 	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("Quote doesn't exist")))
+			return false
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return false
+	}
+	if quote.UserID != authPayload.UserID {
+		ctx.JSON(http.StatusNotFound, errorResponse(errors.New("Quote doesn't exist")))
+		return false
+	}
+	if quote.FromCurrency != req.Amount.Currency || quote.ToCurrency != toCurrency || quote.Amount != req.Amount.Amount {
+		err := fmt.Errorf("quote is for %d [%s] to [%s]", quote.Amount, quote.FromCurrency, quote.ToCurrency)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return false
+	}
+	if quote.UsedAt != 0 || quote.ExpiresAt <= time.Now().Unix() {
+		ctx.JSON(http.StatusConflict, errorResponse(db.ErrQuoteUnavailable))
+		return false
 	}
-	ctx.JSON(http.StatusCreated, responseHandler(200, "Transaction has been made", transaction))
+
+	arg.Rate = quote.Rate
+	arg.ToAmount = quote.ToAmount
+	arg.QuoteID = quote.ID
+	return true
 }
 
-func buildEntryParams(accountID int64, amount int64) db.CreateEntryParams {
-	return db.CreateEntryParams{
-		AccountID: accountID,
-		Amount:    amount,
-		CreatedAt: time.Now().Unix(),
+//Gets an account that takes part in a transfer
+func (server *Server) getTransferAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	return checkTransferAccount(ctx, account, err, strconv.FormatInt(accountID, 10))
+}
+
+//Gets the account a transfer is sent to by its account number
+func (server *Server) getTransferAccountByNumber(ctx *gin.Context, number string) (db.Account, bool) {
+	account, err := server.store.GetAccountByNumber(ctx, number)
+	return checkTransferAccount(ctx, account, err, number)
+}
+
+//The receiving account is given by id or by account number, the binder makes sure it's exactly one
+func (server *Server) getTransferTarget(ctx *gin.Context, accountID int64, number string) (db.Account, bool) {
+	if number != "" {
+		return server.getTransferAccountByNumber(ctx, number)
 	}
+	return server.getTransferAccount(ctx, accountID)
 }
 
-func (server *Server) checkCurrency(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
-	account, err := server.store.GetAccount(ctx, accountID)
+func checkTransferAccount(ctx *gin.Context, account db.Account, err error, ref string) (db.Account, bool) {
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(fmt.Errorf("Provided account [%d] doesn't exist", accountID)))
+			ctx.JSON(http.StatusNotFound, errorResponse(fmt.Errorf("Provided account [%s] doesn't exist", ref)))
 			return db.Account{}, false
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return db.Account{}, false
 	}
+	//Frozen and closed accounts can neither send nor receive
+	if err := db.AccountStatusError(account.Status); err != nil {
+		status, code := accountErrorCode(err)
+		ctx.JSON(status, errorCodeResponse(code, fmt.Errorf("Provided account [%s] is %s", ref, account.Status)))
+		return db.Account{}, false
+	}
+	return account, true
+}
+
+func (server *Server) checkCurrency(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, ok := server.getTransferAccount(ctx, accountID)
+	if !ok {
+		return db.Account{}, false
+	}
+	return checkAccountCurrency(ctx, account, currency)
+}
+
+func checkAccountCurrency(ctx *gin.Context, account db.Account, currency string) (db.Account, bool) {
 	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: [%s] vs [%s]", accountID, account.Currency, currency)
+		err := fmt.Errorf("account [%d] currency mismatch: [%s] vs [%s]", account.ID, account.Currency, currency)
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return db.Account{}, false
 	}
@@ -104,17 +257,145 @@ func (server *Server) checkCurrency(ctx *gin.Context, accountID int64, currency
 	return account, true
 }
 
-func checkOwnershipAndBalance(ctx *gin.Context, account db.Account, amount int64) bool {
+func checkOwnership(ctx *gin.Context, account db.Account) bool {
 	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
 
 	if account.UserID != authPayload.UserID {
 		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("Account does not belong to the user")))
 		return false
 	}
+	return true
+}
 
-	if account.Balance < amount {
-		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("Account does not have enough balance")))
-		return false
+type getTransferReq struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listTransfersReq struct {
+	Direction string `form:"direction" binding:"omitempty,oneof=in out all"`
+	From      int64  `form:"from" binding:"omitempty,min=0"`
+	To        int64  `form:"to" binding:"omitempty,min=0"`
+	MinAmount int64  `form:"min_amount" binding:"omitempty,min=1"`
+	MaxAmount int64  `form:"max_amount" binding:"omitempty,min=1"`
+	Cursor    string `form:"cursor"`
+	Count     int32  `form:"count" binding:"omitempty,min=5,max=50"`
+}
+
+type listTransfersResponse struct {
+	Transfers  []transferResponse `json:"transfers"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+const defaultTransfersPageSize = 15
+
+//Get a transfer that touches one of the user's accounts
+func (server *Server) getTransfer(ctx *gin.Context) {
+	var req getTransferReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
 	}
-	return true
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+
+	transfer, err := server.store.GetTransactionForUser(ctx, db.GetTransactionForUserParams{
+		ID:     req.ID,
+		UserID: authPayload.UserID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No transfer with this id")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", transferResponseBuilder(transfer)))
+}
+
+//List transfers of an account, newest first
+func (server *Server) listAccountTransfers(ctx *gin.Context) {
+	var uri getAccountReq
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("Invalid ID")))
+		return
+	}
+	var req listTransfersReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg, err := buildListTransfersParams(uri.ID, req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No account with this id")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !checkOwnership(ctx, account) {
+		return
+	}
+
+	transfers, err := server.store.ListAccountTransactions(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := listTransfersResponse{Transfers: make([]transferResponse, len(transfers))}
+	for i, transfer := range transfers {
+		response.Transfers[i] = transferResponseBuilder(transfer)
+	}
+	if len(transfers) == int(arg.Count) {
+		response.NextCursor = encodeCursor(transfers[len(transfers)-1].ID)
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", response))
+}
+
+func buildListTransfersParams(accountID int64, req listTransfersReq) (db.ListAccountTransactionsParams, error) {
+	arg := db.ListAccountTransactionsParams{
+		AccountID: accountID,
+		Direction: req.Direction,
+		FromTime:  req.From,
+		ToTime:    req.To,
+		MinAmount: req.MinAmount,
+		MaxAmount: req.MaxAmount,
+		Cursor:    math.MaxInt64,
+		Count:     req.Count,
+	}
+	if arg.Direction == "" {
+		arg.Direction = "all"
+	}
+	if arg.ToTime == 0 {
+		arg.ToTime = math.MaxInt64
+	}
+	if arg.MaxAmount == 0 {
+		arg.MaxAmount = math.MaxInt64
+	}
+	if arg.Count == 0 {
+		arg.Count = defaultTransfersPageSize
+	}
+	if arg.FromTime > arg.ToTime {
+		return arg, errors.New("from must not be after to")
+	}
+	if arg.MinAmount > arg.MaxAmount {
+		return arg, errors.New("min_amount must not be greater than max_amount")
+	}
+	if len(req.Cursor) > 0 {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return arg, err
+		}
+		arg.Cursor = cursor
+	}
+	return arg, nil
 }
@@ -15,6 +15,7 @@ import (
 	mock_db "github.com/faisal-a-n/simplebank/db/mock"
 	db "github.com/faisal-a-n/simplebank/db/sqlc"
 	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
@@ -30,6 +31,8 @@ func (e eqCreateTxParamas) Matches(x interface{}) bool {
 		return false
 	}
 	e.arg.CreatedAt = arg.CreatedAt
+	e.arg.Actor.RequestID = arg.Actor.RequestID
+	e.arg.Actor.ClientIp = arg.Actor.ClientIp
 
 	return reflect.DeepEqual(e.arg, arg)
 }
@@ -60,8 +63,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			body: gin.H{
 				"from_account_id": fromAccount.ID,
 				"to_account_id":   toAccount.ID,
-				"amount":          txAmount,
-				"currency":        currency,
+				"amount":          gin.H{"amount": txAmount, "currency": currency},
 			},
 			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
 				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
@@ -70,41 +72,242 @@ func TestCreateTransferAPI(t *testing.T) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
 
-				fromEntry := db.Entry{
-					ID:        1,
-					AccountID: fromAccount.ID,
-					Amount:    -txAmount,
-					CreatedAt: time.Now().Unix(),
+				args := db.TransferTxParams{
+					FromAccountID: fromAccount.ID,
+					ToAccountID:   toAccount.ID,
+					Amount:        txAmount,
+					CreatedAt:     time.Now().Unix(),
+					Actor:         db.AuditActor{ActorID: fromAccount.UserID},
 				}
-				toEntry := db.Entry{
-					ID:        2,
-					AccountID: toAccount.ID,
-					Amount:    txAmount,
-					CreatedAt: time.Now().Unix(),
+				store.EXPECT().TransferTx(gomock.Any(), EqCreateUserParams(args)).Times(1).Return(db.TransferTxResult{Transaction: db.Transaction{ID: 1}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountNumber",
+			body: gin.H{
+				"from_account_id":   fromAccount.ID,
+				"to_account_number": toAccount.Number,
+				"amount":            gin.H{"amount": txAmount, "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(toAccount.Number)).Times(1).Return(toAccount, nil)
+
+				args := db.TransferTxParams{
+					FromAccountID: fromAccount.ID,
+					ToAccountID:   toAccount.ID,
+					Amount:        txAmount,
+					Actor:         db.AuditActor{ActorID: fromAccount.UserID},
 				}
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(1).Return(fromEntry, nil)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(1).Return(toEntry, nil)
+				store.EXPECT().TransferTx(gomock.Any(), EqCreateUserParams(args)).Times(1).Return(db.TransferTxResult{Transaction: db.Transaction{ID: 1}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountNumberNotFound",
+			body: gin.H{
+				"from_account_id":   fromAccount.ID,
+				"to_account_number": toAccount.Number,
+				"amount":            gin.H{"amount": txAmount, "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(toAccount.Number)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "MistypedAccountNumber",
+			body: gin.H{
+				"from_account_id":   fromAccount.ID,
+				"to_account_number": "SB23000012345687",
+				"amount":            gin.H{"amount": txAmount, "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountIDAndNumber",
+			body: gin.H{
+				"from_account_id":   fromAccount.ID,
+				"to_account_id":     toAccount.ID,
+				"to_account_number": toAccount.Number,
+				"amount":            gin.H{"amount": txAmount, "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingToAccount",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"amount":          gin.H{"amount": txAmount, "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DecimalValue",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"value": "0.10", "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+
 				args := db.TransferTxParams{
 					FromAccountID: fromAccount.ID,
 					ToAccountID:   toAccount.ID,
-					FromEntryID:   1,
-					ToEntryID:     2,
 					Amount:        txAmount,
-					CreatedAt:     time.Now().Unix(),
+					Actor:         db.AuditActor{ActorID: fromAccount.UserID},
 				}
-				store.EXPECT().TransferTx(gomock.Any(), EqCreateUserParams(args)).Times(1).Return(db.TransferTxResult{}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), EqCreateUserParams(args)).Times(1).Return(db.TransferTxResult{Transaction: db.Transaction{ID: 1}}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusCreated, recorder.Code)
 			},
 		},
+		{
+			name: "AmountDoesNotMatchValue",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": txAmount, "value": "10.00", "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TooManyDecimals",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"value": "0.105", "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingAmount",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeAmount",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": -txAmount, "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedCurrency",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": txAmount, "currency": "XYZ"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
 		{
 			name: "DifferentAccountOwner",
 			body: gin.H{
 				"from_account_id": fromAccount.ID,
 				"to_account_id":   toAccount.ID,
-				"amount":          txAmount,
-				"currency":        currency,
+				"amount":          gin.H{"amount": txAmount, "currency": currency},
 			},
 			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
 				//Change the token id
@@ -113,8 +316,6 @@ func TestCreateTransferAPI(t *testing.T) {
 			builStubs: func(store *mock_db.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -126,8 +327,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			body: gin.H{
 				"from_account_id": fromAccount.ID,
 				"to_account_id":   toAccount.ID,
-				"amount":          math.MaxInt64,
-				"currency":        currency,
+				"amount":          gin.H{"amount": math.MaxInt64, "currency": currency},
 			},
 			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
 				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
@@ -135,12 +335,74 @@ func TestCreateTransferAPI(t *testing.T) {
 			builStubs: func(store *mock_db.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountClosed",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": txAmount, "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				closed := toAccount
+				closed.Status = db.AccountStatusClosed
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(closed, nil)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error_code":"account_closed"`)
+			},
+		},
+		{
+			name: "FromAccountFrozen",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": txAmount, "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				frozen := fromAccount
+				frozen.Status = db.AccountStatusFrozen
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(frozen, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error_code":"account_frozen"`)
+			},
+		},
+		{
+			name: "ClosedDuringTransfer",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          gin.H{"amount": txAmount, "currency": currency},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrAccountClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"error_code":"account_closed"`)
 			},
 		},
 		{
@@ -148,8 +410,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			body: gin.H{
 				"from_account_id": fromAccount.ID,
 				"to_account_id":   toAccount.ID,
-				"amount":          txAmount,
-				"currency":        mismatchCurrency,
+				"amount":          gin.H{"amount": txAmount, "currency": mismatchCurrency},
 			},
 			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
 				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
@@ -157,8 +418,6 @@ func TestCreateTransferAPI(t *testing.T) {
 			builStubs: func(store *mock_db.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -170,8 +429,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			body: gin.H{
 				"from_account_id": fromAccount.ID,
 				"to_account_id":   toAccount.ID,
-				"amount":          txAmount,
-				"currency":        mismatchCurrency,
+				"amount":          gin.H{"amount": txAmount, "currency": mismatchCurrency},
 			},
 			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
 				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
@@ -179,8 +437,6 @@ func TestCreateTransferAPI(t *testing.T) {
 			builStubs: func(store *mock_db.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, sql.ErrNoRows)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -192,8 +448,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			body: gin.H{
 				"from_account_id": fromAccount.ID,
 				"to_account_id":   toAccount.ID,
-				"amount":          txAmount,
-				"currency":        mismatchCurrency,
+				"amount":          gin.H{"amount": txAmount, "currency": mismatchCurrency},
 			},
 			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
 				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
@@ -201,8 +456,6 @@ func TestCreateTransferAPI(t *testing.T) {
 			builStubs: func(store *mock_db.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, sql.ErrConnDone)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -214,8 +467,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			body: gin.H{
 				"from_account_id": "fromAccount.ID",
 				"to_account_id":   toAccount.ID,
-				"amount":          txAmount,
-				"currency":        mismatchCurrency,
+				"amount":          gin.H{"amount": txAmount, "currency": mismatchCurrency},
 			},
 			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
 				addAuthorizationHeader(t, request, maker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
@@ -223,8 +475,6 @@ func TestCreateTransferAPI(t *testing.T) {
 			builStubs: func(store *mock_db.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -256,3 +506,218 @@ func TestCreateTransferAPI(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTransferAPI(t *testing.T) {
+	account := randomAccount()
+	transfer := randomTransfer(account.ID, util.GenerateRandomInt(1000, 1))
+
+	testCases := []struct {
+		name          string
+		transferID    int64
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			transferID: transfer.ID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetTransactionForUser(gomock.Any(), gomock.Eq(db.GetTransactionForUserParams{
+					ID:     transfer.ID,
+					UserID: account.UserID,
+				})).Times(1).Return(transfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "NotFound",
+			transferID: transfer.ID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetTransactionForUser(gomock.Any(), gomock.Any()).Times(1).Return(db.Transaction{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidID",
+			transferID: 0,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetTransactionForUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+
+			server := NewTestServer(t, store)
+			url := fmt.Sprintf("/transfers/%d", testCase.transferID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, account.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAccountTransfersAPI(t *testing.T) {
+	account := randomAccount()
+	n := 5
+	transfers := make([]db.Transaction, n)
+	for i := 0; i < n; i++ {
+		transfers[i] = randomTransfer(account.ID, util.GenerateRandomInt(1000, 1))
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		userID        int64
+		builStubs     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			query:  "direction=out&min_amount=10&count=5",
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountTransactions(gomock.Any(), gomock.Eq(db.ListAccountTransactionsParams{
+					AccountID: account.ID,
+					Direction: "out",
+					FromTime:  0,
+					ToTime:    math.MaxInt64,
+					MinAmount: 10,
+					MaxAmount: math.MaxInt64,
+					Cursor:    math.MaxInt64,
+					Count:     5,
+				})).Times(1).Return(transfers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data listTransfersResponse `json:"data"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Len(t, response.Data.Transfers, n)
+				require.Equal(t, encodeCursor(transfers[n-1].ID), response.Data.NextCursor)
+			},
+		},
+		{
+			name:   "WithCursor",
+			query:  "cursor=" + encodeCursor(42),
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountTransactions(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListAccountTransactionsParams) ([]db.Transaction, error) {
+						require.Equal(t, int64(42), arg.Cursor)
+						require.Equal(t, "all", arg.Direction)
+						return transfers[:1], nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "next_cursor")
+			},
+		},
+		{
+			name:   "InvalidCursor",
+			query:  "cursor=not-a-cursor",
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountTransactions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidDateRange",
+			query:  "from=200&to=100",
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountTransactions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidDirection",
+			query:  "direction=sideways",
+			userID: account.UserID,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListAccountTransactions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "DifferentAccountOwner",
+			userID: account.UserID + 1,
+			builStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountTransactions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.builStubs(store)
+
+			server := NewTestServer(t, store)
+			url := fmt.Sprintf("/accounts/%d/transfers?%s", account.ID, testCase.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, testCase.userID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func randomTransfer(fromAccountID, toAccountID int64) db.Transaction {
+	amount := util.GenerateAmount()
+	currency := util.GenerateCurrency()
+	return db.Transaction{
+		ID:            util.GenerateRandomInt(1000, 1),
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		FromEntryID:   util.GenerateRandomInt(1000, 1),
+		ToEntryID:     util.GenerateRandomInt(1000, 1),
+		Amount:        amount,
+		ToAmount:      amount,
+		Rate:          "1",
+		FromCurrency:  currency,
+		ToCurrency:    currency,
+		CreatedAt:     time.Now().Unix(),
+	}
+}
@@ -0,0 +1,429 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
+	"github.com/faisal-a-n/simplebank/totp"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	//Random bytes in a login challenge token
+	loginChallengeTokenSize = 32
+	//Wrong codes a challenge takes before the user has to log in with their password again
+	maxLoginChallengeAttempts = 5
+	recoveryCodeCount         = 10
+)
+
+var (
+	errInvalidSecondFactor = errors.New("Two factor code is invalid")
+	errInvalidChallenge    = errors.New("Login challenge is invalid or has expired")
+)
+
+//Returned by a password login when the user has two factor authentication enabled
+type loginChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ChallengeExpiry   time.Time `json:"challenge_expires_at"`
+}
+
+//Either a code from the authenticator app or one of the recovery codes
+type secondFactorRequest struct {
+	Code         string `json:"code" binding:"required_without=RecoveryCode,excluded_with=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type loginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	secondFactorRequest
+}
+
+type enrollTotpResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type confirmTotpRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+//Recovery codes are only ever shown here, they are stored hashed
+type confirmTotpResponse struct {
+	RecoveryCodes []string            `json:"recovery_codes"`
+	User          userDetailsResponse `json:"user"`
+}
+
+type disableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	secondFactorRequest
+}
+
+//Stores a challenge for a login whose password was correct and asks for the second factor
+func (server *Server) challengeLogin(ctx *gin.Context, user db.User, scopes []string) {
+	challengeToken, err := util.NewSecretToken(loginChallengeTokenSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	now := time.Now()
+	expiresAt := now.Add(server.config.LOGIN_CHALLENGE_DURATION)
+	_, err = server.store.CreateLoginChallenge(ctx, db.CreateLoginChallengeParams{
+		UserID:    user.ID,
+		TokenHash: util.HashSecretToken(challengeToken),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: expiresAt.Unix(),
+		CreatedAt: now.Unix(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := loginChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+		ChallengeExpiry:   expiresAt,
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Two factor code required", response))
+}
+
+//Second step of a login, answers the challenge with a totp or recovery code
+func (server *Server) loginTwoFactor(ctx *gin.Context) {
+	var req loginTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challenge, err := server.store.AttemptLoginChallenge(ctx, db.AttemptLoginChallengeParams{
+		TokenHash:   util.HashSecretToken(req.ChallengeToken),
+		Now:         time.Now().Unix(),
+		MaxAttempts: maxLoginChallengeAttempts,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidChallenge))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	user, err := server.store.GetUser(ctx, challenge.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !server.checkLoginLock(ctx, user.Email) {
+		return
+	}
+	//Wrong codes count against the email and the client ip like wrong passwords
+	if err := server.useSecondFactor(ctx, user.ID, req.secondFactorRequest); err != nil {
+		if errors.Is(err, errInvalidSecondFactor) {
+			server.recordLoginFailure(ctx, user.Email, user)
+			server.recordAudit(ctx, 0, db.AuditUserLoginFailed, db.AuditTargetUser, strconv.FormatInt(user.ID, 10), nil, nil)
+		}
+		secondFactorError(ctx, err)
+		return
+	}
+	//Two requests answering the same challenge can't both log in
+	_, err = server.store.UseLoginChallenge(ctx, db.UseLoginChallengeParams{
+		UsedAt: time.Now().Unix(),
+		ID:     challenge.ID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidChallenge))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	//The role may have changed since the password step
+	server.startSession(ctx, user, util.AllowedScopes(user.Role, strings.Fields(challenge.Scopes)))
+}
+
+//Checks a totp or recovery code. The response is written and false returned when it doesn't match.
+//Wrong codes are counted per user and lock the second factor like failed logins lock an email
+func (server *Server) checkSecondFactor(ctx *gin.Context, userID int64, req secondFactorRequest) bool {
+	key := secondFactorThrottleKey(userID)
+	if !server.checkThrottleLock(ctx, key, key, "two_factor_locked", errSecondFactorLocked) {
+		return false
+	}
+	if err := server.useSecondFactor(ctx, userID, req); err != nil {
+		if errors.Is(err, errInvalidSecondFactor) {
+			server.throttleLogin(ctx, key, server.accountThrottle(), db.AuditTargetUser, strconv.FormatInt(userID, 10))
+		}
+		secondFactorError(ctx, err)
+		return false
+	}
+	server.resetThrottle(ctx, key)
+	return true
+}
+
+//Uses a recovery code when one is given, otherwise the totp code
+func (server *Server) useSecondFactor(ctx *gin.Context, userID int64, req secondFactorRequest) error {
+	if len(req.RecoveryCode) > 0 {
+		return server.useRecoveryCode(ctx, userID, req.RecoveryCode)
+	}
+	return server.useTotpCode(ctx, userID, req.Code)
+}
+
+func secondFactorError(ctx *gin.Context, err error) {
+	if errors.Is(err, errInvalidSecondFactor) {
+		ctx.JSON(http.StatusUnauthorized, errorCodeResponse("invalid_two_factor_code", err))
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+}
+
+//Accepts a code from the user's confirmed authenticator. The step it matched is recorded
+//so the same code can't be used twice, even by two requests at once
+func (server *Server) useTotpCode(ctx *gin.Context, userID int64, code string) error {
+	credential, err := server.store.GetTotpCredential(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errInvalidSecondFactor
+		}
+		return err
+	}
+	if credential.ConfirmedAt == 0 {
+		return errInvalidSecondFactor
+	}
+	step, ok := totp.Validate(credential.Secret, code, time.Now(), credential.LastUsedStep)
+	if !ok {
+		return errInvalidSecondFactor
+	}
+	_, err = server.store.UseTotpStep(ctx, db.UseTotpStepParams{
+		LastUsedStep: step,
+		UserID:       userID,
+	})
+	if err == sql.ErrNoRows {
+		return errInvalidSecondFactor
+	}
+	return err
+}
+
+func (server *Server) useRecoveryCode(ctx *gin.Context, userID int64, code string) error {
+	_, err := server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		UsedAt:   time.Now().Unix(),
+		UserID:   userID,
+		CodeHash: util.HashSecretToken(normalizeRecoveryCode(code)),
+	})
+	if err == sql.ErrNoRows {
+		return errInvalidSecondFactor
+	}
+	return err
+}
+
+const recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+//Recovery codes are 10 random characters shown as two groups, e.g. 7hq2k-mzx4p
+func newRecoveryCode() (string, error) {
+	data := make([]byte, 10)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	for i, b := range data {
+		data[i] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+	}
+	return string(data[:5]) + "-" + string(data[5:]), nil
+}
+
+//Users may type recovery codes without the dash or in upper case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+//Start enrolling an authenticator app. The secret only works once it is confirmed with a code
+func (server *Server) enrollTotp(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.TwoFactorEnabled {
+		ctx.JSON(http.StatusConflict, errorResponse(db.ErrTwoFactorEnabled))
+		return
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	_, err = server.store.UpsertTotpCredential(ctx, db.UpsertTotpCredentialParams{
+		UserID:    user.ID,
+		Secret:    secret,
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(db.ErrTwoFactorEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := enrollTotpResponse{
+		Secret: secret,
+		URI:    totp.URI(server.config.TOTP_ISSUER, user.Email, secret),
+	}
+	ctx.JSON(http.StatusCreated, responseHandler(200, "Confirm the secret with a code from your authenticator app", response))
+}
+
+//Finish enrolling with a code from the app, which turns two factor authentication on
+func (server *Server) confirmTotp(ctx *gin.Context) {
+	var req confirmTotpRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	credential, err := server.store.GetTotpCredential(ctx, authPayload.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("No authenticator is being enrolled")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if credential.ConfirmedAt != 0 {
+		ctx.JSON(http.StatusConflict, errorResponse(db.ErrTwoFactorEnabled))
+		return
+	}
+	step, ok := totp.Validate(credential.Secret, req.Code, time.Now(), 0)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorCodeResponse("invalid_two_factor_code", errInvalidSecondFactor))
+		return
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		hashes[i] = util.HashSecretToken(normalizeRecoveryCode(codes[i]))
+	}
+	user, err := server.store.ConfirmTotpTx(ctx, db.ConfirmTotpTxParams{
+		UserID:             authPayload.UserID,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+		Actor:              auditActor(ctx, authPayload.UserID),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrTwoFactorEnabled) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := confirmTotpResponse{
+		RecoveryCodes: codes,
+		User:          userResponseBuilder(user),
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Two factor authentication is enabled", response))
+}
+
+//Turn two factor authentication off. Needs the password and a second factor so a stolen
+//access token alone can't remove it
+func (server *Server) disableTwoFactor(ctx *gin.Context) {
+	var req disableTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !user.TwoFactorEnabled {
+		ctx.JSON(http.StatusConflict, errorResponse(errors.New("Two factor authentication is not enabled")))
+		return
+	}
+	if err := util.CheckPassword(req.Password, user.Password); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("Password is incorrect")))
+		return
+	}
+	if !server.checkSecondFactor(ctx, user.ID, req.secondFactorRequest) {
+		return
+	}
+
+	user, err = server.store.DisableTwoFactorTx(ctx, db.DisableTwoFactorTxParams{
+		UserID: user.ID,
+		Actor:  auditActor(ctx, user.ID),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Two factor authentication is disabled", userResponseBuilder(user)))
+}
+
+//Parses currency:value pairs like EUR:1000 into minor units of each currency
+func parseStepUpThresholds(pairs []string) (map[string]int64, error) {
+	thresholds := make(map[string]int64, len(pairs))
+	for _, pair := range pairs {
+		currency, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("%q isn't a currency:value pair", pair)
+		}
+		threshold, err := util.ParseMoney(value, currency)
+		if err != nil {
+			return nil, err
+		}
+		thresholds[currency] = threshold.Amount
+	}
+	return thresholds, nil
+}
+
+//Transfers above the threshold set for their currency in TRANSFER_2FA_THRESHOLDS need a fresh code
+//from the user's authenticator, users without one can't make them. The response is written and false
+//returned when the check fails, it isn't stored against the idempotency key so the transfer can be
+//retried with a code
+func (server *Server) checkTransferStepUp(ctx *gin.Context, userID int64, amount util.Money, code string) (ok bool) {
+	defer func() {
+		if !ok {
+			allowIdempotentRetry(ctx)
+		}
+	}()
+	threshold, found := server.stepUpThresholds[amount.Currency]
+	if !found || threshold <= 0 || amount.Amount <= threshold {
+		return true
+	}
+	limit := util.NewMoney(threshold, amount.Currency)
+	credential, err := server.store.GetTotpCredential(ctx, userID)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if err == sql.ErrNoRows || credential.ConfirmedAt == 0 {
+		err := fmt.Errorf("Transfers above %s need two factor authentication to be enabled", limit)
+		ctx.JSON(http.StatusForbidden, errorCodeResponse("two_factor_required", err))
+		return false
+	}
+	if len(code) == 0 {
+		err := fmt.Errorf("Transfers above %s need a two factor code", limit)
+		ctx.JSON(http.StatusUnauthorized, errorCodeResponse("two_factor_code_required", err))
+		return false
+	}
+	return server.checkSecondFactor(ctx, userID, secondFactorRequest{Code: code})
+}
@@ -0,0 +1,794 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/totp"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomTotpCredential(t *testing.T, userID int64) db.TotpCredential {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	return db.TotpCredential{
+		UserID:      userID,
+		Secret:      secret,
+		ConfirmedAt: time.Now().Unix(),
+		CreatedAt:   time.Now().Unix(),
+	}
+}
+
+func currentTotpCode(t *testing.T, secret string) string {
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+//A code from an hour ago, long outside the accepted window
+func staleTotpCode(t *testing.T, secret string) string {
+	code, err := totp.Code(secret, totp.Step(time.Now().Add(-time.Hour)))
+	require.NoError(t, err)
+	return code
+}
+
+//Stubs the lock check of a user's second factor that isn't throttled
+func expectSecondFactorAllowed(store *mock_db.MockStore, userID int64) {
+	key := secondFactorThrottleKey(userID)
+	store.EXPECT().GetLoginLock(gomock.Any(), gomock.Eq(db.GetLoginLockParams{
+		AccountKey: key,
+		IpKey:      key,
+	})).Times(1).Return(int64(0), nil)
+}
+
+//Stubs counting a wrong second factor code, not past the free attempts
+func expectSecondFactorFailure(store *mock_db.MockStore, userID int64) {
+	store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ interface{}, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+			if arg.Key != secondFactorThrottleKey(userID) {
+				return db.LoginThrottle{}, fmt.Errorf("unexpected throttle key %q", arg.Key)
+			}
+			return db.LoginThrottle{Key: arg.Key, Failures: 1}, nil
+		})
+	store.EXPECT().LockLogin(gomock.Any(), gomock.Any()).Times(0)
+}
+
+func TestNewRecoveryCode(t *testing.T) {
+	code, err := newRecoveryCode()
+	require.NoError(t, err)
+	require.Regexp(t, "^[a-z2-7]{5}-[a-z2-7]{5}$", code)
+
+	//Codes typed without the dash or in upper case still match
+	require.Equal(t, normalizeRecoveryCode(code), normalizeRecoveryCode(" "+code[:5]+code[6:]))
+}
+
+func TestParseStepUpThresholds(t *testing.T) {
+	thresholds, err := parseStepUpThresholds([]string{"EUR:1000", " JPY:150000", "USD:99.50"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"EUR": 100000, "JPY": 150000, "USD": 9950}, thresholds)
+
+	thresholds, err = parseStepUpThresholds(nil)
+	require.NoError(t, err)
+	require.Empty(t, thresholds)
+
+	_, err = parseStepUpThresholds([]string{"EUR1000"})
+	require.Error(t, err)
+	_, err = parseStepUpThresholds([]string{"JPY:10.5"})
+	require.ErrorIs(t, err, util.ErrInvalidAmount)
+	_, err = parseStepUpThresholds([]string{"XXX:10"})
+	require.Error(t, err)
+}
+
+func TestLoginTwoFactorAPI(t *testing.T) {
+	user := generateRandomUser()
+	user.TwoFactorEnabled = true
+	credential := randomTotpCredential(t, user.ID)
+	challengeToken, err := util.NewSecretToken(loginChallengeTokenSize)
+	require.NoError(t, err)
+	challenge := db.LoginChallenge{
+		ID:     1,
+		UserID: user.ID,
+		Scopes: util.ScopeAccountsRead,
+	}
+	recoveryCode, err := newRecoveryCode()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          func() gin.H
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken, "code": currentTotpCode(t, credential.Secret)}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.AttemptLoginChallengeParams) (db.LoginChallenge, error) {
+						require.Equal(t, util.HashSecretToken(challengeToken), arg.TokenHash)
+						require.Equal(t, int32(maxLoginChallengeAttempts), arg.MaxAttempts)
+						return challenge, nil
+					})
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(credential, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UseTotpStepParams) (db.TotpCredential, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.InDelta(t, totp.Step(time.Now()), arg.LastUsedStep, 1)
+						return credential, nil
+					})
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(accountThrottleKey(user.Email))).Times(1)
+				expectAudit(t, store, user.ID, db.AuditUserLogin, db.AuditTargetUser, fmt.Sprint(user.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data loginReponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.NotEmpty(t, response.Data.AccessToken)
+				require.True(t, response.Data.User.TwoFactorEnabled)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken, "recovery_code": recoveryCode}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UseRecoveryCodeParams) (db.RecoveryCode, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, util.HashSecretToken(normalizeRecoveryCode(recoveryCode)), arg.CodeHash)
+						return db.RecoveryCode{ID: 1}, nil
+					})
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(accountThrottleKey(user.Email))).Times(1)
+				expectAudit(t, store, user.ID, db.AuditUserLogin, db.AuditTargetUser, fmt.Sprint(user.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken, "recovery_code": recoveryCode}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{}, sql.ErrNoRows)
+				expectLoginFailure(store, user.Email)
+				expectAudit(t, store, 0, db.AuditUserLoginFailed, db.AuditTargetUser, fmt.Sprint(user.ID))
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "WrongCode",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken, "code": staleTotpCode(t, credential.Secret)}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(credential, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginFailure(store, user.Email)
+				expectAudit(t, store, 0, db.AuditUserLoginFailed, db.AuditTargetUser, fmt.Sprint(user.ID))
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ReplayedCode",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken, "code": currentTotpCode(t, credential.Secret)}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				used := credential
+				used.LastUsedStep = totp.Step(time.Now()) + 1
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(used, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginFailure(store, user.Email)
+				expectAudit(t, store, 0, db.AuditUserLoginFailed, db.AuditTargetUser, fmt.Sprint(user.ID))
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Locked",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken, "code": currentTotpCode(t, credential.Secret)}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().GetLoginLock(gomock.Any(), gomock.Eq(db.GetLoginLockParams{
+					AccountKey: accountThrottleKey(user.Email),
+					IpKey:      ipThrottleKey(""),
+				})).Times(1).Return(time.Now().Add(time.Minute).Unix(), nil)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidChallenge",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken, "code": currentTotpCode(t, credential.Secret)}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginChallenge{}, sql.ErrNoRows)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ChallengeAlreadyUsed",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken, "recovery_code": recoveryCode}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{ID: 1}, nil)
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginChallenge{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "CodeAndRecoveryCode",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken, "code": "123456", "recovery_code": recoveryCode}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCode",
+			body: func() gin.H {
+				return gin.H{"challenge_token": challengeToken}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().AttemptLoginChallenge(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+
+			data, err := json.Marshal(testCase.body())
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/login/2fa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestEnrollTotpAPI(t *testing.T) {
+	user := generateRandomUser()
+	enabled := user
+	enabled.TwoFactorEnabled = true
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().UpsertTotpCredential(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpsertTotpCredentialParams) (db.TotpCredential, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Len(t, arg.Secret, 32)
+						return db.TotpCredential{UserID: user.ID, Secret: arg.Secret}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var response struct {
+					Data enrollTotpResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Len(t, response.Data.Secret, 32)
+				require.Contains(t, response.Data.URI, "secret="+response.Data.Secret)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(enabled, nil)
+				store.EXPECT().UpsertTotpCredential(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "ConfirmedMeanwhile",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().UpsertTotpCredential(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpCredential{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/2fa/totp", nil)
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestConfirmTotpAPI(t *testing.T) {
+	user := generateRandomUser()
+	enabled := user
+	enabled.TwoFactorEnabled = true
+	pending := randomTotpCredential(t, user.ID)
+	pending.ConfirmedAt = 0
+	confirmed := randomTotpCredential(t, user.ID)
+
+	testCases := []struct {
+		name          string
+		code          func() string
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: func() string { return currentTotpCode(t, pending.Secret) },
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(pending, nil)
+				store.EXPECT().ConfirmTotpTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ConfirmTotpTxParams) (db.User, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.InDelta(t, totp.Step(time.Now()), arg.Step, 1)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+						require.Equal(t, user.ID, arg.Actor.ActorID)
+						return enabled, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data confirmTotpResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Len(t, response.Data.RecoveryCodes, recoveryCodeCount)
+				require.True(t, response.Data.User.TwoFactorEnabled)
+			},
+		},
+		{
+			name: "WrongCode",
+			code: func() string { return staleTotpCode(t, pending.Secret) },
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(pending, nil)
+				store.EXPECT().ConfirmTotpTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolling",
+			code: func() string { return "123456" },
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(db.TotpCredential{}, sql.ErrNoRows)
+				store.EXPECT().ConfirmTotpTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyConfirmed",
+			code: func() string { return currentTotpCode(t, confirmed.Secret) },
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(confirmed, nil)
+				store.EXPECT().ConfirmTotpTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "ConfirmedMeanwhile",
+			code: func() string { return currentTotpCode(t, pending.Secret) },
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(pending, nil)
+				store.EXPECT().ConfirmTotpTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrTwoFactorEnabled)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: func() string { return "12345a" },
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+
+			data, err := json.Marshal(gin.H{"code": testCase.code()})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/me/2fa/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDisableTwoFactorAPI(t *testing.T) {
+	user := generateRandomUser()
+	password := user.Password
+	hash, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user.Password = hash
+	user.TwoFactorEnabled = true
+	disabled := user
+	disabled.TwoFactorEnabled = false
+	recoveryCode, err := newRecoveryCode()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"password": password, "recovery_code": recoveryCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				expectSecondFactorAllowed(store, user.ID)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{ID: 1}, nil)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(secondFactorThrottleKey(user.ID))).Times(1)
+				store.EXPECT().DisableTwoFactorTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.DisableTwoFactorTxParams) (db.User, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, user.ID, arg.Actor.ActorID)
+						return disabled, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WrongPassword",
+			body: gin.H{"password": password + ".", "recovery_code": recoveryCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DisableTwoFactorTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "WrongRecoveryCode",
+			body: gin.H{"password": password, "recovery_code": recoveryCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				expectSecondFactorAllowed(store, user.ID)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{}, sql.ErrNoRows)
+				expectSecondFactorFailure(store, user.ID)
+				store.EXPECT().DisableTwoFactorTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SecondFactorLocked",
+			body: gin.H{"password": password, "recovery_code": recoveryCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().GetLoginLock(gomock.Any(), gomock.Any()).Times(1).Return(time.Now().Add(time.Minute).Unix(), nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DisableTwoFactorTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "two_factor_locked")
+			},
+		},
+		{
+			name: "NotEnabled",
+			body: gin.H{"password": password, "recovery_code": recoveryCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(disabled, nil)
+				store.EXPECT().DisableTwoFactorTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "MissingSecondFactor",
+			body: gin.H{"password": password},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/me/2fa/disable", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestTransferStepUpAPI(t *testing.T) {
+	currency := "EUR"
+	threshold := int64(1000)
+	fromAccount := randomAccountWithCurrency(currency)
+	toAccount := randomAccountWithCurrency(currency)
+	credential := randomTotpCredential(t, fromAccount.UserID)
+	//No threshold is set for this currency
+	otherFromAccount := randomAccountWithCurrency("JPY")
+	otherFromAccount.UserID = fromAccount.UserID
+	otherToAccount := randomAccountWithCurrency("JPY")
+
+	testCases := []struct {
+		name          string
+		body          func() gin.H
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "BelowThreshold",
+			body: func() gin.H {
+				return gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": gin.H{"amount": threshold, "currency": currency}}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{Transaction: db.Transaction{ID: 1}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "OtherCurrency",
+			body: func() gin.H {
+				return gin.H{"from_account_id": otherFromAccount.ID, "to_account_id": otherToAccount.ID, "amount": gin.H{"amount": threshold + 1, "currency": "JPY"}}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(otherFromAccount.ID)).Times(1).Return(otherFromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(otherToAccount.ID)).Times(1).Return(otherToAccount, nil)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{Transaction: db.Transaction{ID: 1}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "AboveThresholdWithCode",
+			body: func() gin.H {
+				return gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": gin.H{"amount": threshold + 1, "currency": currency},
+					"totp_code": currentTotpCode(t, credential.Secret)}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(fromAccount.UserID)).Times(2).Return(credential, nil)
+				expectSecondFactorAllowed(store, fromAccount.UserID)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(1).Return(credential, nil)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(secondFactorThrottleKey(fromAccount.UserID))).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{Transaction: db.Transaction{ID: 1}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "AboveThresholdWithoutCode",
+			body: func() gin.H {
+				return gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": gin.H{"amount": threshold + 1, "currency": currency}}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(fromAccount.UserID)).Times(1).Return(credential, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "two_factor_code_required")
+			},
+		},
+		{
+			name: "AboveThresholdWithoutTwoFactor",
+			body: func() gin.H {
+				return gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": gin.H{"amount": threshold + 1, "currency": currency},
+					"totp_code": "123456"}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(fromAccount.UserID)).Times(1).Return(db.TotpCredential{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "two_factor_required")
+			},
+		},
+		{
+			name: "AboveThresholdReplayedCode",
+			body: func() gin.H {
+				return gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": gin.H{"amount": threshold + 1, "currency": currency},
+					"totp_code": currentTotpCode(t, credential.Secret)}
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().GetTotpCredential(gomock.Any(), gomock.Eq(fromAccount.UserID)).Times(2).Return(credential, nil)
+				expectSecondFactorAllowed(store, fromAccount.UserID)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpCredential{}, sql.ErrNoRows)
+				expectSecondFactorFailure(store, fromAccount.UserID)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "invalid_two_factor_code")
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+			server := NewTestServer(t, store)
+			server.stepUpThresholds = map[string]int64{currency: threshold}
+
+			data, err := json.Marshal(testCase.body())
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, fromAccount.UserID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
@@ -3,12 +3,16 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/token"
 	"github.com/faisal-a-n/simplebank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
@@ -18,11 +22,33 @@ type createUserRequest struct {
 	Password string `json:"password,onempty" binding:"required,min=6"`
 }
 
+var errEmailTaken = errors.New("User with the same email already exists")
+
 type userDetailsResponse struct {
-	ID        int64  `json:"id"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	CreatedAt int64  `json:"created_at"`
+	ID               int64                    `json:"id"`
+	Name             string                   `json:"name"`
+	Email            string                   `json:"email"`
+	Role             string                   `json:"role"`
+	EmailVerified    bool                     `json:"email_verified"`
+	TwoFactorEnabled bool                     `json:"two_factor_enabled"`
+	CreatedAt        int64                    `json:"created_at"`
+	Accounts         []accountSummaryResponse `json:"accounts,omitempty"`
+}
+
+type accountSummaryResponse struct {
+	ID       int64      `json:"id"`
+	Number   string     `json:"number"`
+	Currency string     `json:"currency"`
+	Balance  util.Money `json:"balance"`
+	Status   string     `json:"status"`
+}
+
+//At least one field has to be given, the other keeps its current value
+//Changing the email needs the current password, a stolen access token alone can't take the account over
+type updateProfileRequest struct {
+	Name            string `json:"name" binding:"required_without=Email,omitempty,alpha,min=6"`
+	Email           string `json:"email" binding:"omitempty,email"`
+	CurrentPassword string `json:"current_password" binding:"required_with=Email"`
 }
 
 func (server *Server) createUser(ctx *gin.Context) {
@@ -40,14 +66,14 @@ func (server *Server) createUser(ctx *gin.Context) {
 		Name:              req.Name,
 		Email:             req.Email,
 		Password:          hash,
-		PasswordChangedAt: time.Now().Unix(),
+		PasswordChangedAt: time.Now().UnixMilli(),
 		CreatedAt:         time.Now().Unix(),
 	}
 	user, err := server.store.CreateUser(ctx, args)
 	if err != nil {
 		if pqError, ok := err.(*pq.Error); ok {
 			if pqError.Code.Name() == "unique_violation" {
-				ctx.JSON(http.StatusInternalServerError, errorResponse(errors.New("User with the same email already exists")))
+				ctx.JSON(http.StatusConflict, errorResponse(errEmailTaken))
 				return
 			}
 		}
@@ -55,13 +81,17 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
+	server.trySendEmailVerification(ctx, user)
+
 	response := userResponseBuilder(user)
 	ctx.JSON(http.StatusCreated, responseHandler(200, "User created", response))
 }
 
+//Scopes narrows the tokens down, every scope of the user's role is granted when it is empty
 type loginUserRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password,onempty" binding:"required,min=6"`
+	Email    string   `json:"email" binding:"required,email"`
+	Password string   `json:"password,onempty" binding:"required,min=6"`
+	Scopes   []string `json:"scopes"`
 }
 
 type loginReponse struct {
@@ -78,34 +108,66 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if !server.checkLoginLock(ctx, req.Email) {
+		return
+	}
 	user, err := server.store.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("Email is not registered")))
+			checkDummyPassword(req.Password)
+			server.recordLoginFailure(ctx, req.Email, db.User{})
+			server.recordAudit(ctx, 0, db.AuditUserLoginFailed, db.AuditTargetEmail, req.Email, nil, nil)
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidLogin))
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 	if err := util.CheckPassword(req.Password, user.Password); err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("Invalid password entered")))
+		server.recordLoginFailure(ctx, req.Email, user)
+		server.recordAudit(ctx, 0, db.AuditUserLoginFailed, db.AuditTargetUser, strconv.FormatInt(user.ID, 10), nil, nil)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidLogin))
 		return
 	}
-	access_token, payload, err := server.tokenMaker.CreateToken(user.ID, server.config.ACCESS_TOKEN_DURATION)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	scopes := util.RoleScopes(user.Role)
+	if len(req.Scopes) > 0 {
+		scopes = util.AllowedScopes(user.Role, req.Scopes)
+		if len(scopes) != len(req.Scopes) {
+			ctx.JSON(http.StatusForbidden, errorResponse(errors.New("Requested scopes are not allowed for this user")))
+			return
+		}
+	}
+	//The password alone isn't enough, the login finishes once the challenge is answered
+	if user.TwoFactorEnabled {
+		server.challengeLogin(ctx, user, scopes)
 		return
 	}
+	server.startSession(ctx, user, scopes)
+}
 
-	err = server.store.UpdateSession(ctx, db.UpdateSessionParams{
-		IsBlocked: true,
-		UserID:    user.ID,
-	})
+//Issues the tokens for a successful login and records the session they belong to
+func (server *Server) startSession(ctx *gin.Context, user db.User, scopes []string) {
+	//With the single device policy logging in logs out every other session
+	if server.config.SESSION_POLICY == sessionPolicySingle {
+		err := server.store.UpdateSession(ctx, db.UpdateSessionParams{
+			IsBlocked: true,
+			UserID:    user.ID,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		server.revocation.RevokeUser(user.ID)
+	}
+
+	//Every token issued for this login and its refreshes carries the session family id
+	sessionID := uuid.New()
+	access_token, payload, err := server.tokenMaker.CreateToken(user.ID, sessionID, user.Role, scopes, server.config.ACCESS_TOKEN_DURATION)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	refresh_token, refreshTokenPayload, err := server.tokenMaker.CreateToken(user.ID, server.config.REFRESH_TOKEN_DURATION)
+	refresh_token, refreshTokenPayload, err := server.tokenMaker.CreateToken(user.ID, sessionID, user.Role, scopes, server.config.REFRESH_TOKEN_DURATION)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -113,6 +175,7 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	sessionArgs := db.CreateSessionParams{
 		ID:           refreshTokenPayload.ID,
 		UserID:       payload.UserID,
+		FamilyID:     sessionID,
 		RefreshToken: refresh_token,
 		UserAgent:    ctx.Request.UserAgent(),
 		ClientIp:     ctx.ClientIP(),
@@ -124,6 +187,12 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	//Only cleared once every factor has been checked, a right password alone doesn't count
+	server.resetLoginThrottle(ctx, user.Email)
+	server.recordAudit(ctx, user.ID, db.AuditUserLogin, db.AuditTargetUser, strconv.FormatInt(user.ID, 10), nil, gin.H{
+		"session_id": sessionID,
+		"scopes":     scopes,
+	})
 	response := loginReponse{
 		AccessToken:        access_token,
 		AccessTokenExpiry:  payload.ExpiredAt,
@@ -134,11 +203,105 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, responseHandler(200, "You have logged in successfully", response))
 }
 
-func userResponseBuilder(user db.User) userDetailsResponse {
-	return userDetailsResponse{
-		Name:      user.Name,
-		Email:     user.Email,
-		ID:        user.ID,
-		CreatedAt: user.CreatedAt,
+//Accounts are only given for the user's own profile
+func userResponseBuilder(user db.User, accounts ...db.Account) userDetailsResponse {
+	response := userDetailsResponse{
+		Name:             user.Name,
+		Email:            user.Email,
+		ID:               user.ID,
+		Role:             user.Role,
+		EmailVerified:    user.EmailVerified,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		CreatedAt:        user.CreatedAt,
+	}
+	for _, account := range accounts {
+		response.Accounts = append(response.Accounts, accountSummaryBuilder(account))
+	}
+	return response
+}
+
+func accountSummaryBuilder(account db.Account) accountSummaryResponse {
+	return accountSummaryResponse{
+		ID:       account.ID,
+		Number:   account.Number,
+		Currency: account.Currency,
+		Balance:  util.NewMoney(account.Balance, account.Currency),
+		Status:   account.Status,
+	}
+}
+
+//A user has at most one account per currency, so this is never reached in practice
+const maxProfileAccounts = 100
+
+//The caller's own profile, with a summary of their accounts when the token can read them
+func (server *Server) getProfile(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	var accounts []db.Account
+	if authPayload.HasScope(util.ScopeAccountsRead) {
+		accounts, err = server.store.ListAccountsForUser(ctx, db.ListAccountsForUserParams{
+			UserID: user.ID,
+			Limit:  maxProfileAccounts,
+			Offset: 0,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Data fetched", userResponseBuilder(user, accounts...)))
+}
+
+//Change the caller's name or email. A new email has to be verified again and a
+//verification mail is sent to it, the old one is told about the change
+func (server *Server) updateProfile(ctx *gin.Context) {
+	var req updateProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	arg := db.UpdateProfileTxParams{
+		UserID: user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+		Actor:  auditActor(ctx, user.ID),
+	}
+	if len(req.Name) > 0 {
+		arg.Name = req.Name
+	}
+	if len(req.Email) > 0 && req.Email != user.Email {
+		if err := util.CheckPassword(req.CurrentPassword, user.Password); err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("Current password is incorrect")))
+			return
+		}
+		arg.Email = req.Email
+	}
+
+	updated, err := server.store.UpdateProfileTx(ctx, arg)
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code.Name() == "unique_violation" {
+			ctx.JSON(http.StatusConflict, errorResponse(errEmailTaken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if updated.Email != user.Email {
+		server.trySendEmailVerification(ctx, updated)
+		if err := server.notifier.EmailChanged(ctx, user.Email, updated.Email); err != nil {
+			log.Printf("couldn't tell user %d their email was changed: %v", user.ID, err)
+		}
 	}
+	ctx.JSON(http.StatusOK, responseHandler(200, "Profile updated", userResponseBuilder(updated)))
 }
@@ -11,6 +11,8 @@ import (
 
 	mock_db "github.com/faisal-a-n/simplebank/db/mock"
 	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/mail"
+	"github.com/faisal-a-n/simplebank/token"
 	"github.com/faisal-a-n/simplebank/util"
 	"github.com/goccy/go-json"
 	"github.com/golang/mock/gomock"
@@ -32,6 +34,7 @@ func TestCreateUserAPI(t *testing.T) {
 			body: user,
 			buildStub: func(store *mock_db.MockStore) {
 				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().CreateEmailVerification(gomock.Any(), gomock.Any()).Times(1)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusCreated, recorder.Code)
@@ -90,7 +93,7 @@ func TestCreateUserAPI(t *testing.T) {
 				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, &pq.Error{Code: "23505"})
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Equal(t, http.StatusConflict, recorder.Code)
 			},
 		},
 		{
@@ -132,6 +135,7 @@ func TestCreateUserAPI(t *testing.T) {
 			require.NoError(t, err)
 
 			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
 		})
 	}
 }
@@ -146,6 +150,7 @@ func TestLoginUserAPI(t *testing.T) {
 	testCases := []struct {
 		name          string
 		body          loginUserRequest
+		sessionPolicy string
 		buildStub     func(store *mock_db.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
@@ -156,14 +161,125 @@ func TestLoginUserAPI(t *testing.T) {
 				Password: plainPassword,
 			},
 			buildStub: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserByEmail(gomock.Any(), registeredUser.Email).Times(1).Return(registeredUser, nil)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(accountThrottleKey(registeredUser.Email))).Times(1)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().UpdateSession(gomock.Any(), gomock.Any()).Times(0)
+				expectAudit(t, store, registeredUser.ID, db.AuditUserLogin, db.AuditTargetUser, fmt.Sprint(registeredUser.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "SingleSessionPolicy",
+			body: loginUserRequest{
+				Email:    registeredUser.Email,
+				Password: plainPassword,
+			},
+			sessionPolicy: sessionPolicySingle,
+			buildStub: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserByEmail(gomock.Any(), registeredUser.Email).Times(1).Return(registeredUser, nil)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(accountThrottleKey(registeredUser.Email))).Times(1)
+				store.EXPECT().UpdateSession(gomock.Any(), gomock.Eq(db.UpdateSessionParams{
+					IsBlocked: true,
+					UserID:    registeredUser.ID,
+				})).Times(1)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				expectAudit(t, store, registeredUser.ID, db.AuditUserLogin, db.AuditTargetUser, fmt.Sprint(registeredUser.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ReadOnlyScopes",
+			body: loginUserRequest{
+				Email:    registeredUser.Email,
+				Password: plainPassword,
+				Scopes:   []string{util.ScopeAccountsRead},
+			},
+			buildStub: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
 				store.EXPECT().GetUserByEmail(gomock.Any(), registeredUser.Email).Times(1).Return(registeredUser, nil)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(accountThrottleKey(registeredUser.Email))).Times(1)
 				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
-				store.EXPECT().UpdateSession(gomock.Any(), gomock.Any()).Times(1)
+				expectAudit(t, store, registeredUser.ID, db.AuditUserLogin, db.AuditTargetUser, fmt.Sprint(registeredUser.ID))
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
+		{
+			name: "TwoFactorChallenge",
+			body: loginUserRequest{
+				Email:    registeredUser.Email,
+				Password: plainPassword,
+				Scopes:   []string{util.ScopeAccountsRead},
+			},
+			buildStub: func(store *mock_db.MockStore) {
+				user := registeredUser
+				user.TwoFactorEnabled = true
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserByEmail(gomock.Any(), registeredUser.Email).Times(1).Return(user, nil)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(accountThrottleKey(registeredUser.Email))).Times(0)
+				store.EXPECT().CreateLoginChallenge(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginChallengeParams) (db.LoginChallenge, error) {
+						require.Equal(t, registeredUser.ID, arg.UserID)
+						require.Equal(t, util.ScopeAccountsRead, arg.Scopes)
+						require.Len(t, arg.TokenHash, 64)
+						require.Greater(t, arg.ExpiresAt, arg.CreatedAt)
+						return db.LoginChallenge{ID: 1}, nil
+					})
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data loginChallengeResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.True(t, response.Data.TwoFactorRequired)
+				require.NotEmpty(t, response.Data.ChallengeToken)
+			},
+		},
+		{
+			name: "AuditLogError",
+			body: loginUserRequest{
+				Email:    registeredUser.Email,
+				Password: plainPassword,
+			},
+			buildStub: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserByEmail(gomock.Any(), registeredUser.Email).Times(1).Return(registeredUser, nil)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(accountThrottleKey(registeredUser.Email))).Times(1)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().AppendAuditLog(gomock.Any(), gomock.Any()).Times(1).Return(db.AuditLog{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ScopeNotAllowedForRole",
+			body: loginUserRequest{
+				Email:    registeredUser.Email,
+				Password: plainPassword,
+				Scopes:   []string{util.ScopeAccountsRead, util.ScopeAdminWrite},
+			},
+			buildStub: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUserByEmail(gomock.Any(), registeredUser.Email).Times(1).Return(registeredUser, nil)
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Eq(accountThrottleKey(registeredUser.Email))).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
 		{
 			name: "InvalidBody",
 			body: loginUserRequest{
@@ -184,10 +300,14 @@ func TestLoginUserAPI(t *testing.T) {
 				Password: fmt.Sprint(plainPassword, "."),
 			},
 			buildStub: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
 				store.EXPECT().GetUserByEmail(gomock.Any(), registeredUser.Email).Times(1).Return(registeredUser, nil)
+				expectLoginFailure(store, registeredUser.Email)
+				expectAudit(t, store, 0, db.AuditUserLoginFailed, db.AuditTargetUser, fmt.Sprint(registeredUser.ID))
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidLogin.Error())
 			},
 		},
 		{
@@ -197,11 +317,30 @@ func TestLoginUserAPI(t *testing.T) {
 				Password: plainPassword,
 			},
 			buildStub: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
 				store.EXPECT().GetUserByEmail(gomock.Any(), registeredUser.Email).
 					Times(1).Return(registeredUser, sql.ErrNoRows)
+				expectLoginFailure(store, registeredUser.Email)
+				expectAudit(t, store, 0, db.AuditUserLoginFailed, db.AuditTargetEmail, registeredUser.Email)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidLogin.Error())
+			},
+		},
+		{
+			name: "Locked",
+			body: loginUserRequest{
+				Email:    registeredUser.Email,
+				Password: plainPassword,
+			},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().GetLoginLock(gomock.Any(), gomock.Any()).Times(1).Return(time.Now().Add(time.Minute).Unix(), nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
 			},
 		},
 		{
@@ -211,6 +350,7 @@ func TestLoginUserAPI(t *testing.T) {
 				Password: plainPassword,
 			},
 			buildStub: func(store *mock_db.MockStore) {
+				expectLoginAllowed(store)
 				store.EXPECT().GetUserByEmail(gomock.Any(), registeredUser.Email).
 					Times(1).Return(registeredUser, sql.ErrConnDone)
 			},
@@ -236,9 +376,10 @@ func TestLoginUserAPI(t *testing.T) {
 
 			recorder := httptest.NewRecorder()
 			server := NewTestServer(t, store)
-			require.NoError(t, err)
+			server.config.SESSION_POLICY = testCase.sessionPolicy
 
 			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
 		})
 	}
 }
@@ -249,7 +390,271 @@ func generateRandomUser() db.User {
 		Name:              util.GenerateString(8),
 		Email:             util.RandomEmail(),
 		Password:          util.GenerateString(8),
-		PasswordChangedAt: time.Now().Unix(),
+		PasswordChangedAt: time.Now().UnixMilli(),
 		CreatedAt:         time.Now().Unix(),
+		Role:              util.RoleCustomer,
+	}
+}
+
+func TestGetProfileAPI(t *testing.T) {
+	user := generateRandomUser()
+	user.EmailVerified = true
+	account := randomAccountWithCurrency("EUR")
+	account.UserID = user.ID
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, maker token.Maker)
+		buildStub     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListAccountsForUser(gomock.Any(), gomock.Eq(db.ListAccountsForUserParams{
+					UserID: user.ID,
+					Limit:  maxProfileAccounts,
+				})).Times(1).Return([]db.Account{account}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data userDetailsResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, user.Email, response.Data.Email)
+				require.True(t, response.Data.EmailVerified)
+				require.Len(t, response.Data.Accounts, 1)
+				require.Equal(t, account.Number, response.Data.Accounts[0].Number)
+				require.Equal(t, account.Balance, response.Data.Accounts[0].Balance.Amount)
+			},
+		},
+		{
+			name: "NoAccountsScope",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addRoleAuthorizationHeader(t, request, maker, user.ID, util.RoleCustomer, []string{util.ScopeTransfersWrite})
+			},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListAccountsForUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), `"accounts"`)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			setupAuth: func(t *testing.T, request *http.Request, maker token.Maker) {
+				addAuthorizationHeader(t, request, maker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+			},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().ListAccountsForUser(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockController := gomock.NewController(t)
+			defer mockController.Finish()
+
+			store := mock_db.NewMockStore(mockController)
+			testCase.buildStub(store)
+			server := NewTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+			require.NoError(t, err)
+			testCase.setupAuth(t, request, server.tokenMaker)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateProfileAPI(t *testing.T) {
+	user := generateRandomUser()
+	plainPassword := user.Password
+	hash, err := util.HashPassword(plainPassword)
+	require.NoError(t, err)
+	user.Password = hash
+	user.EmailVerified = true
+	newName := util.GenerateString(8)
+	newEmail := util.RandomEmail()
+
+	testCases := []struct {
+		name          string
+		body          map[string]string
+		buildStub     func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder)
+	}{
+		{
+			name: "Name",
+			body: map[string]string{"name": newName},
+			buildStub: func(store *mock_db.MockStore) {
+				updated := user
+				updated.Name = newName
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateProfileTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateProfileTxParams) (db.User, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Equal(t, newName, arg.Name)
+						require.Equal(t, user.Email, arg.Email)
+						require.Equal(t, user.ID, arg.Actor.ActorID)
+						return updated, nil
+					})
+				store.EXPECT().CreateEmailVerification(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, mailer.messages)
+			},
+		},
+		{
+			name: "Email",
+			body: map[string]string{"email": newEmail, "current_password": plainPassword},
+			buildStub: func(store *mock_db.MockStore) {
+				updated := user
+				updated.Email = newEmail
+				updated.EmailVerified = false
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateProfileTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateProfileTxParams) (db.User, error) {
+						require.Equal(t, user.Name, arg.Name)
+						require.Equal(t, newEmail, arg.Email)
+						return updated, nil
+					})
+				store.EXPECT().CreateEmailVerification(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateEmailVerificationParams) (db.EmailVerification, error) {
+						require.Equal(t, newEmail, arg.Email)
+						return db.EmailVerification{ID: 1}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response struct {
+					Data userDetailsResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, newEmail, response.Data.Email)
+				require.False(t, response.Data.EmailVerified)
+
+				require.Len(t, mailer.messages, 2)
+				require.Equal(t, newEmail, mailer.messages[0].To)
+				//The old address is told where the account went
+				require.Equal(t, user.Email, mailer.messages[1].To)
+				require.Contains(t, mailer.messages[1].Body, newEmail)
+			},
+		},
+		{
+			name: "EmailWithoutPassword",
+			body: map[string]string{"email": newEmail},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateProfileTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Empty(t, mailer.messages)
+			},
+		},
+		{
+			name: "EmailWrongPassword",
+			body: map[string]string{"email": newEmail, "current_password": plainPassword + "."},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateProfileTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Empty(t, mailer.messages)
+			},
+		},
+		{
+			name: "EmailTaken",
+			body: map[string]string{"email": newEmail, "current_password": plainPassword},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateProfileTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, &pq.Error{Code: "23505"})
+				store.EXPECT().CreateEmailVerification(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "EmptyBody",
+			body: map[string]string{},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().UpdateProfileTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: map[string]string{"email": "user.Email"},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().UpdateProfileTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			body: map[string]string{"name": newName},
+			buildStub: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateProfileTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *mailRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockController := gomock.NewController(t)
+			defer mockController.Finish()
+
+			store := mock_db.NewMockStore(mockController)
+			testCase.buildStub(store)
+			server := NewTestServer(t, store)
+			mailer := &mailRecorder{}
+			server.notifier = mail.NewNotifier(mailer)
+
+			body, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPatch, "/users/me", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			addAuthorizationHeader(t, request, server.tokenMaker, user.ID, authorizationHeaderKey, authorizationType, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder, mailer)
+		})
 	}
 }
@@ -11,3 +11,20 @@ var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 	}
 	return false
 }
+
+//Checks the account number's check digits so a typo is rejected before it is looked up
+var validAccountNumber validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if number, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsValidAccountNumber(number)
+	}
+	return false
+}
+
+//Money bound from a request has to be in a supported currency. Whether the amount may be
+//negative depends on the handler
+var validMoney validator.StructLevelFunc = func(structLevel validator.StructLevel) {
+	money, ok := structLevel.Current().Interface().(util.Money)
+	if !ok || !util.IsSupportedCurrency(money.Currency) {
+		structLevel.ReportError(money.Currency, "Currency", "currency", "currency", "")
+	}
+}
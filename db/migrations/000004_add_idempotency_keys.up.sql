@@ -0,0 +1,12 @@
+CREATE TABLE "idempotency_keys" (
+  "key" varchar NOT NULL,
+  "user_id" bigint NOT NULL,
+  "request_path" varchar NOT NULL,
+  "request_hash" varchar NOT NULL,
+  "response_code" integer NOT NULL DEFAULT 0,
+  "response_body" bytea NOT NULL DEFAULT '',
+  "created_at" bigint NOT NULL,
+  PRIMARY KEY ("user_id", "key")
+);
+
+ALTER TABLE "idempotency_keys" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
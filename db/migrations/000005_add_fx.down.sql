@@ -0,0 +1,5 @@
+ALTER TABLE "transactions" DROP "rate";
+
+ALTER TABLE "transactions" DROP "to_amount";
+
+drop table if exists "fx_quotes";
\ No newline at end of file
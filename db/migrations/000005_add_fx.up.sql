@@ -0,0 +1,23 @@
+CREATE TABLE "fx_quotes" (
+  "id" uuid PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "from_currency" varchar(10) NOT NULL,
+  "to_currency" varchar(10) NOT NULL,
+  "rate" numeric NOT NULL,
+  "amount" bigint NOT NULL,
+  "to_amount" bigint NOT NULL,
+  "expires_at" bigint NOT NULL,
+  "used_at" bigint NOT NULL DEFAULT 0,
+  "created_at" bigint NOT NULL
+);
+
+ALTER TABLE "fx_quotes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+-- amount is debited in the source currency, to_amount is credited in the destination currency
+ALTER TABLE "transactions" ADD "to_amount" bigint;
+
+UPDATE "transactions" SET "to_amount" = "amount";
+
+ALTER TABLE "transactions" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transactions" ADD "rate" numeric NOT NULL DEFAULT 1;
\ No newline at end of file
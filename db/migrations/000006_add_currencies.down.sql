@@ -0,0 +1,9 @@
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_currency_fkey";
+
+UPDATE "accounts" SET "currency" = 'YEN' WHERE "currency" = 'JPY';
+
+UPDATE "fx_quotes" SET "from_currency" = 'YEN' WHERE "from_currency" = 'JPY';
+
+UPDATE "fx_quotes" SET "to_currency" = 'YEN' WHERE "to_currency" = 'JPY';
+
+DROP TABLE IF EXISTS "currencies";
\ No newline at end of file
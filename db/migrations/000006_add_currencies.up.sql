@@ -0,0 +1,27 @@
+CREATE TABLE "currencies" (
+  "code" varchar(3) PRIMARY KEY,
+  "numeric_code" varchar(3) UNIQUE NOT NULL,
+  "exponent" integer NOT NULL,
+  "symbol" varchar(10) NOT NULL,
+  "name" varchar(50) NOT NULL,
+  "enabled" boolean NOT NULL DEFAULT false,
+  "updated_at" bigint NOT NULL DEFAULT 0
+);
+
+INSERT INTO "currencies" ("code", "numeric_code", "exponent", "symbol", "name", "enabled") VALUES
+  ('USD', '840', 2, '$', 'US Dollar', true),
+  ('EUR', '978', 2, '€', 'Euro', true),
+  ('INR', '356', 2, '₹', 'Indian Rupee', true),
+  ('CAD', '124', 2, 'CA$', 'Canadian Dollar', true),
+  ('JPY', '392', 0, '¥', 'Yen', true),
+  ('GBP', '826', 2, '£', 'Pound Sterling', false),
+  ('CHF', '756', 2, 'CHF', 'Swiss Franc', false);
+
+-- YEN was never an ISO 4217 code
+UPDATE "accounts" SET "currency" = 'JPY' WHERE "currency" = 'YEN';
+
+UPDATE "fx_quotes" SET "from_currency" = 'JPY' WHERE "from_currency" = 'YEN';
+
+UPDATE "fx_quotes" SET "to_currency" = 'JPY' WHERE "to_currency" = 'YEN';
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
\ No newline at end of file
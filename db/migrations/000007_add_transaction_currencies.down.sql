@@ -0,0 +1,3 @@
+ALTER TABLE "transactions" DROP "to_currency";
+
+ALTER TABLE "transactions" DROP "from_currency";
\ No newline at end of file
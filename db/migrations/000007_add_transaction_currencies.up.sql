@@ -0,0 +1,11 @@
+ALTER TABLE "transactions" ADD "from_currency" varchar(10);
+
+ALTER TABLE "transactions" ADD "to_currency" varchar(10);
+
+UPDATE "transactions" t SET
+  "from_currency" = (SELECT "currency" FROM "accounts" WHERE "id" = t."from_account_id"),
+  "to_currency" = (SELECT "currency" FROM "accounts" WHERE "id" = t."to_account_id");
+
+ALTER TABLE "transactions" ALTER COLUMN "from_currency" SET NOT NULL;
+
+ALTER TABLE "transactions" ALTER COLUMN "to_currency" SET NOT NULL;
\ No newline at end of file
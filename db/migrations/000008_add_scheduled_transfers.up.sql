@@ -0,0 +1,41 @@
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar(10) NOT NULL,
+  "recurrence" varchar(100) NOT NULL DEFAULT '',
+  "scheduled_at" bigint NOT NULL,
+  "next_attempt_at" bigint NOT NULL,
+  "end_at" bigint NOT NULL DEFAULT 0,
+  "attempts" integer NOT NULL DEFAULT 0,
+  "status" varchar(20) NOT NULL DEFAULT 'active',
+  "locked_until" bigint NOT NULL DEFAULT 0,
+  "created_at" bigint NOT NULL
+);
+
+CREATE TABLE "scheduled_transfer_runs" (
+  "id" bigserial PRIMARY KEY,
+  "scheduled_transfer_id" bigint NOT NULL,
+  "transaction_id" bigint NOT NULL DEFAULT 0,
+  "status" varchar(20) NOT NULL,
+  "attempt" integer NOT NULL,
+  "error" varchar NOT NULL DEFAULT '',
+  "scheduled_at" bigint NOT NULL,
+  "created_at" bigint NOT NULL
+);
+
+CREATE INDEX ON "scheduled_transfers" ("user_id");
+
+CREATE INDEX ON "scheduled_transfers" ("status", "next_attempt_at");
+
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id");
\ No newline at end of file
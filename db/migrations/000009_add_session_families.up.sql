@@ -0,0 +1,11 @@
+-- every refresh consumes its session and starts a new one in the same family,
+-- the family is the chain of sessions that started with a single login
+ALTER TABLE "sessions" ADD "family_id" uuid;
+
+UPDATE "sessions" SET "family_id" = "id";
+
+ALTER TABLE "sessions" ALTER COLUMN "family_id" SET NOT NULL;
+
+ALTER TABLE "sessions" ADD "consumed_at" bigint NOT NULL DEFAULT 0;
+
+CREATE INDEX ON "sessions" ("family_id");
\ No newline at end of file
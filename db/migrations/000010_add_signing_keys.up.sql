@@ -0,0 +1,12 @@
+-- keys tokens are signed with. secret holds the shared secret or the PEM encoded private key,
+-- a key verifies tokens as soon as it is added but only signs them once not_before has passed
+CREATE TABLE "signing_keys" (
+  "id" varchar PRIMARY KEY,
+  "token_type" varchar NOT NULL,
+  "secret" varchar NOT NULL,
+  "not_before" bigint NOT NULL,
+  "retired_at" bigint NOT NULL DEFAULT 0,
+  "created_at" bigint NOT NULL
+);
+
+CREATE INDEX ON "signing_keys" ("token_type", "retired_at");
\ No newline at end of file
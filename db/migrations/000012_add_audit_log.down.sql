@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "audit_log";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "status";
\ No newline at end of file
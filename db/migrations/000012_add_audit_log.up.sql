@@ -0,0 +1,19 @@
+ALTER TABLE "accounts" ADD COLUMN "status" varchar NOT NULL DEFAULT 'active';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_status_check" CHECK ("status" in ('active', 'frozen'));
+
+CREATE TABLE "audit_log" (
+  "id" bigserial PRIMARY KEY,
+  "actor_id" bigint NOT NULL,
+  "action" varchar NOT NULL,
+  "target_type" varchar NOT NULL,
+  "target_id" varchar NOT NULL,
+  "reason" varchar NOT NULL DEFAULT '',
+  "before" json NOT NULL,
+  "after" json NOT NULL,
+  "created_at" bigint NOT NULL
+);
+
+CREATE INDEX ON "audit_log" ("target_type", "target_id");
+
+CREATE INDEX ON "audit_log" ("actor_id");
\ No newline at end of file
@@ -0,0 +1,13 @@
+DROP TRIGGER IF EXISTS "audit_log_no_truncate" ON "audit_log";
+
+DROP TRIGGER IF EXISTS "audit_log_no_update" ON "audit_log";
+
+DROP FUNCTION IF EXISTS audit_log_append_only();
+
+ALTER TABLE "audit_log" DROP COLUMN IF EXISTS "hash";
+
+ALTER TABLE "audit_log" DROP COLUMN IF EXISTS "prev_hash";
+
+ALTER TABLE "audit_log" DROP COLUMN IF EXISTS "client_ip";
+
+ALTER TABLE "audit_log" DROP COLUMN IF EXISTS "request_id";
\ No newline at end of file
@@ -0,0 +1,20 @@
+ALTER TABLE "audit_log" ADD COLUMN "request_id" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "audit_log" ADD COLUMN "client_ip" varchar NOT NULL DEFAULT '';
+
+-- Rows written before the chain existed keep an empty hash and are skipped by verification
+ALTER TABLE "audit_log" ADD COLUMN "prev_hash" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "audit_log" ADD COLUMN "hash" varchar NOT NULL DEFAULT '';
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_log_no_update" BEFORE UPDATE OR DELETE ON "audit_log"
+FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER "audit_log_no_truncate" BEFORE TRUNCATE ON "audit_log"
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- Closed accounts stay unusable until an admin unfreezes them
+UPDATE "accounts" SET "status" = 'frozen' WHERE "status" = 'closed';
+
+ALTER TABLE "accounts" DROP CONSTRAINT "accounts_status_check";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_status_check" CHECK ("status" in ('active', 'frozen'));
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "accounts" DROP CONSTRAINT "accounts_status_check";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_status_check" CHECK ("status" in ('active', 'frozen', 'closed'));
\ No newline at end of file
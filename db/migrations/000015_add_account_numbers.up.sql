@@ -0,0 +1,10 @@
+ALTER TABLE "accounts" ADD COLUMN "number" varchar;
+
+-- Existing accounts get a random number with IBAN style check digits, S=28 and B=11
+UPDATE "accounts" SET "number" = 'SB' || lpad((98 - ((generated.digits || '281100')::numeric % 97))::text, 2, '0') || generated.digits
+FROM (SELECT "id", lpad(floor(random() * 1000000000000)::bigint::text, 12, '0') AS digits FROM "accounts") AS generated
+WHERE "accounts"."id" = generated."id";
+
+ALTER TABLE "accounts" ALTER COLUMN "number" SET NOT NULL;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_number_key" UNIQUE ("number");
\ No newline at end of file
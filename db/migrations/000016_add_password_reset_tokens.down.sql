@@ -0,0 +1 @@
+drop table if exists "password_reset_tokens";
\ No newline at end of file
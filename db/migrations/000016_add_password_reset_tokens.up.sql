@@ -0,0 +1,14 @@
+-- reset tokens are stored hashed and are spent by setting used_at, requesting a new one
+-- or changing the password spends every token the user still has
+CREATE TABLE "password_reset_tokens" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "token_hash" varchar UNIQUE NOT NULL,
+  "expires_at" bigint NOT NULL,
+  "used_at" bigint NOT NULL DEFAULT 0,
+  "created_at" bigint NOT NULL
+);
+
+CREATE INDEX ON "password_reset_tokens" ("user_id");
+
+ALTER TABLE "password_reset_tokens" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
\ No newline at end of file
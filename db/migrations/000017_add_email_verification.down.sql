@@ -0,0 +1,3 @@
+drop table if exists "email_verifications";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "email_verified";
\ No newline at end of file
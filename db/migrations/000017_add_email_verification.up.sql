@@ -0,0 +1,18 @@
+-- addresses are unverified until the user redeems a token mailed to them, users created before
+-- this migration can ask for a new one. email is the address the token was sent to so a token
+-- stops working when the user changes their address
+ALTER TABLE "users" ADD COLUMN "email_verified" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "email_verifications" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "email" varchar NOT NULL,
+  "token_hash" varchar UNIQUE NOT NULL,
+  "expires_at" bigint NOT NULL,
+  "used_at" bigint NOT NULL DEFAULT 0,
+  "created_at" bigint NOT NULL
+);
+
+CREATE INDEX ON "email_verifications" ("user_id");
+
+ALTER TABLE "email_verifications" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
\ No newline at end of file
@@ -0,0 +1,7 @@
+drop table if exists "login_challenges";
+
+drop table if exists "recovery_codes";
+
+drop table if exists "totp_credentials";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "two_factor_enabled";
\ No newline at end of file
@@ -0,0 +1,43 @@
+-- a totp credential is pending until the user confirms it with a code from their app, only then
+-- is two_factor_enabled set. last_used_step stops a code from being used twice
+ALTER TABLE "users" ADD COLUMN "two_factor_enabled" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "totp_credentials" (
+  "user_id" bigint PRIMARY KEY,
+  "secret" varchar NOT NULL,
+  "confirmed_at" bigint NOT NULL DEFAULT 0,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" bigint NOT NULL
+);
+
+-- recovery codes are stored hashed and each can be used once
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" bigint NOT NULL DEFAULT 0,
+  "created_at" bigint NOT NULL
+);
+
+-- issued by a password login when the user has two factor authentication enabled, the login
+-- finishes when the challenge is answered with a code. scopes are the ones the login asked for
+CREATE TABLE "login_challenges" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "token_hash" varchar UNIQUE NOT NULL,
+  "scopes" varchar NOT NULL DEFAULT '',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "expires_at" bigint NOT NULL,
+  "used_at" bigint NOT NULL DEFAULT 0,
+  "created_at" bigint NOT NULL
+);
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("user_id", "code_hash");
+
+CREATE INDEX ON "login_challenges" ("user_id");
+
+ALTER TABLE "totp_credentials" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "login_challenges" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists "login_throttles";
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- failed logins counted per key, the email a login was attempted for or the client ip. a key
+-- is locked until locked_until once it has failed too often, and forgotten after a quiet period
+CREATE TABLE "login_throttles" (
+  "key" varchar PRIMARY KEY,
+  "failures" integer NOT NULL DEFAULT 0,
+  "locked_until" bigint NOT NULL DEFAULT 0,
+  "last_failure_at" bigint NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- keys are only kept for IDEMPOTENCY_KEY_TTL, the index is for the cleanup that deletes them
+CREATE INDEX ON "idempotency_keys" ("created_at");
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE "users" SET "password_changed_at" = "password_changed_at" / 1000;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- password_changed_at is compared with token issue times, whole seconds revoked tokens issued in the same second as the change
+UPDATE "users" SET "password_changed_at" = "password_changed_at" * 1000;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "signing_keys" DROP CONSTRAINT "signing_keys_pkey";
+ALTER TABLE "signing_keys" ADD PRIMARY KEY ("id");
\ No newline at end of file
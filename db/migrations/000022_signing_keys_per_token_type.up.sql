@@ -0,0 +1,3 @@
+-- key ids only need to be unique per token type, every type starts with its own legacy key
+ALTER TABLE "signing_keys" DROP CONSTRAINT "signing_keys_pkey";
+ALTER TABLE "signing_keys" ADD PRIMARY KEY ("token_type", "id");
\ No newline at end of file
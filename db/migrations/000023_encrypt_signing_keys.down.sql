@@ -0,0 +1 @@
+ALTER TABLE "signing_keys" DROP COLUMN IF EXISTS "public_key";
\ No newline at end of file
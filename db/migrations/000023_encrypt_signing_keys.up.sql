@@ -0,0 +1,4 @@
+-- secret is stored encrypted with TOKEN_KEY_ENCRYPTION_KEY, rows added before stay readable until
+-- "keys encrypt" converts them. public_key holds the PEM encoded public half of private keys so
+-- servers that only verify tokens can load rotated keys without being able to sign
+ALTER TABLE "signing_keys" ADD "public_key" varchar NOT NULL DEFAULT '';
\ No newline at end of file
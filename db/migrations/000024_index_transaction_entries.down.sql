@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "transactions_from_entry_id_idx";
+DROP INDEX IF EXISTS "transactions_to_entry_id_idx";
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- reconciliation looks up the transaction of every entry to find orphan entries
+CREATE INDEX ON "transactions" ("from_entry_id");
+
+CREATE INDEX ON "transactions" ("to_entry_id");
\ No newline at end of file
@@ -1,13 +1,16 @@
 -- name: CreateAccount :one
 INSERT into accounts (
-  "user_id", "name", "balance", "currency", "created_at"
+  "user_id", "name", "balance", "currency", "number", "created_at"
 )
 values
-($1, $2, $3, $4, $5) RETURNING *;
+($1, $2, $3, $4, $5, $6) RETURNING *;
 
 -- name: GetAccount :one
 SELECT * from accounts where id = $1 limit 1;
 
+-- name: GetAccountByNumber :one
+SELECT * from accounts where number = $1 limit 1;
+
 -- name: GetAccountForUpdate :one
 SELECT * from accounts where id = $1 limit 1 for NO KEY UPDATE;
 
@@ -21,4 +24,21 @@ SELECT * from accounts where user_id = $1 order by id limit $2 offset $3;
 UPDATE accounts set balance = balance + sqlc.arg(amount) where id = sqlc.arg(id) RETURNING *;
 
 -- name: DeleteAccount :exec
-DELETE from accounts where id = $1;
\ No newline at end of file
+DELETE from accounts where id = $1;
+
+-- name: SetBalance :one
+UPDATE accounts set balance = sqlc.arg(balance) where id = sqlc.arg(id) RETURNING *;
+
+-- name: ListBalanceDiscrepancies :many
+-- Orphan entries are left out, see ListOrphanEntries
+SELECT a.id as account_id, a.balance, COALESCE(SUM(e.amount), 0)::bigint as entries_total
+from accounts a
+left join entries e on e.account_id = a.id
+  and (exists (select 1 from transactions t where t.from_entry_id = e.id or t.to_entry_id = e.id)
+    or exists (select 1 from audit_log l where l.action = 'account.adjustment' and (l."after"->'entry'->>'id')::bigint = e.id))
+group by a.id
+having a.balance <> COALESCE(SUM(e.amount), 0)
+order by a.id;
+
+-- name: SetAccountStatus :one
+UPDATE accounts set status = $1 where id = $2 RETURNING *;
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- name: CreateAuditLog :one
+INSERT into audit_log (
+  "actor_id", "action", "target_type", "target_id", "reason", "before", "after",
+  "request_id", "client_ip", "prev_hash", "hash", "created_at"
+)
+values
+($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING *;
+
+-- name: ListAuditLog :many
+SELECT * from audit_log
+where (sqlc.arg(target_type)::varchar = '' or target_type = sqlc.arg(target_type))
+  and (sqlc.arg(target_id)::varchar = '' or target_id = sqlc.arg(target_id))
+order by id desc
+limit sqlc.arg(count) offset sqlc.arg(skip);
+
+-- name: LockAuditLog :exec
+-- Serialises writers so every row links to the one before it
+SELECT pg_advisory_xact_lock(hashtext('audit_log'));
+
+-- name: GetLastAuditLog :one
+SELECT * from audit_log order by id desc limit 1;
+
+-- name: ListAuditLogAfter :many
+SELECT * from audit_log where id > $1 order by id limit $2;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- name: ListCurrencies :many
+SELECT * from currencies order by code;
+
+-- name: GetCurrency :one
+SELECT * from currencies where code = $1 limit 1;
+
+-- name: SetCurrencyEnabled :one
+UPDATE currencies set enabled = $2, updated_at = $3 where code = $1 RETURNING *;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- name: CreateEmailVerification :one
+INSERT into email_verifications (
+  "user_id", "email", "token_hash", "expires_at", "created_at"
+)
+values
+($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: UseEmailVerification :one
+-- Spends a verification token. Returns no rows when it is unknown, already used or expired
+UPDATE email_verifications set used_at = sqlc.arg(used_at)
+where token_hash = sqlc.arg(token_hash) and used_at = 0 and expires_at > sqlc.arg(used_at) RETURNING *;
+
+-- name: RevokeEmailVerifications :exec
+UPDATE email_verifications set used_at = $1 where user_id = $2 and used_at = 0;
\ No newline at end of file
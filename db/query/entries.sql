@@ -9,4 +9,30 @@ values
 SELECT * from entries where id = $1 limit 1;
 
 -- name: ListEntries :many
-SELECT * from entries order by id limit $1 offset $2;
\ No newline at end of file
+SELECT * from entries
+where account_id = sqlc.arg(account_id)
+  and created_at >= sqlc.arg(from_time) and created_at < sqlc.arg(to_time)
+order by id;
+
+-- name: SumEntries :one
+SELECT COALESCE(SUM(amount), 0)::bigint as total from entries
+where account_id = sqlc.arg(account_id)
+  and created_at >= sqlc.arg(from_time) and created_at < sqlc.arg(to_time);
+
+-- name: ListRecentEntries :many
+SELECT * from entries where account_id = $1 order by id desc limit $2 offset $3;
+
+-- name: ListOrphanEntries :many
+-- Entries that belong to no transaction and weren't posted by an admin adjustment. Older
+-- transfers wrote a second pair of entries that never moved the balance
+SELECT e.* from entries e
+where not (exists (select 1 from transactions t where t.from_entry_id = e.id or t.to_entry_id = e.id)
+    or exists (select 1 from audit_log l where l.action = 'account.adjustment' and (l."after"->'entry'->>'id')::bigint = e.id))
+order by e.id;
+
+-- name: SumLedgerEntries :one
+-- Total of the account's entries without the orphan ones
+SELECT COALESCE(SUM(e.amount), 0)::bigint as total from entries e
+where e.account_id = sqlc.arg(account_id)
+  and (exists (select 1 from transactions t where t.from_entry_id = e.id or t.to_entry_id = e.id)
+    or exists (select 1 from audit_log l where l.action = 'account.adjustment' and (l."after"->'entry'->>'id')::bigint = e.id));
\ No newline at end of file
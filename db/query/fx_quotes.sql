@@ -0,0 +1,13 @@
+-- name: CreateFxQuote :one
+INSERT into fx_quotes (
+  "id", "user_id", "from_currency", "to_currency", "rate", "amount", "to_amount", "expires_at", "created_at"
+)
+values
+($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING *;
+
+-- name: GetFxQuote :one
+SELECT * from fx_quotes where id = $1 limit 1;
+
+-- name: UseFxQuote :one
+UPDATE fx_quotes set used_at = sqlc.arg(used_at)
+where id = sqlc.arg(id) and used_at = 0 and expires_at > sqlc.arg(used_at) RETURNING *;
\ No newline at end of file
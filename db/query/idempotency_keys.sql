@@ -0,0 +1,23 @@
+-- name: CreateIdempotencyKey :one
+-- Claims a key for a request. Returns no rows when the key is held by an earlier request,
+-- keys created before expired_before are claimed again
+INSERT into idempotency_keys (
+  "key", "user_id", "request_path", "request_hash", "created_at"
+)
+values
+(sqlc.arg(key), sqlc.arg(user_id), sqlc.arg(request_path), sqlc.arg(request_hash), sqlc.arg(created_at))
+ON CONFLICT ("user_id", "key") DO UPDATE set request_path = EXCLUDED.request_path, request_hash = EXCLUDED.request_hash,
+  response_code = 0, response_body = '', created_at = EXCLUDED.created_at
+where idempotency_keys.created_at < sqlc.arg(expired_before) RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * from idempotency_keys where user_id = $1 and key = $2 limit 1;
+
+-- name: UpdateIdempotencyKeyResponse :exec
+UPDATE idempotency_keys set response_code = $1, response_body = $2 where user_id = $3 and key = $4;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE from idempotency_keys where user_id = $1 and key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE from idempotency_keys where created_at < $1;
\ No newline at end of file
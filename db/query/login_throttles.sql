@@ -0,0 +1,22 @@
+-- name: GetLoginLock :one
+-- Time until which either key is locked, zero when neither has been throttled
+SELECT coalesce(max(locked_until), 0)::bigint from login_throttles
+where key = sqlc.arg(account_key) or key = sqlc.arg(ip_key);
+
+-- name: RecordLoginFailure :one
+-- Counts a failed login. Failures from before forget_before no longer count
+INSERT into login_throttles (
+  "key", "failures", "last_failure_at"
+)
+values
+(sqlc.arg(key), 1, sqlc.arg(now))
+ON CONFLICT ("key") DO UPDATE set
+  failures = CASE WHEN login_throttles.last_failure_at < sqlc.arg(forget_before) THEN 1 ELSE login_throttles.failures + 1 END,
+  last_failure_at = EXCLUDED.last_failure_at
+RETURNING *;
+
+-- name: LockLogin :exec
+UPDATE login_throttles set locked_until = greatest(locked_until, $1) where key = $2;
+
+-- name: ResetLoginThrottle :exec
+DELETE from login_throttles where key = $1;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- name: CreatePasswordResetToken :one
+INSERT into password_reset_tokens (
+  "user_id", "token_hash", "expires_at", "created_at"
+)
+values
+($1, $2, $3, $4) RETURNING *;
+
+-- name: UsePasswordResetToken :one
+-- Spends a reset token. Returns no rows when it is unknown, already used or expired
+UPDATE password_reset_tokens set used_at = sqlc.arg(used_at)
+where token_hash = sqlc.arg(token_hash) and used_at = 0 and expires_at > sqlc.arg(used_at) RETURNING *;
+
+-- name: RevokePasswordResetTokens :exec
+UPDATE password_reset_tokens set used_at = $1 where user_id = $2 and used_at = 0;
\ No newline at end of file
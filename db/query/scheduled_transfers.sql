@@ -0,0 +1,46 @@
+-- name: CreateScheduledTransfer :one
+INSERT into scheduled_transfers (
+  "user_id", "from_account_id", "to_account_id", "amount", "currency", "recurrence", "scheduled_at", "next_attempt_at", "end_at", "created_at"
+)
+values
+($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * from scheduled_transfers where id = $1 limit 1;
+
+-- name: GetScheduledTransferForUpdate :one
+SELECT * from scheduled_transfers where id = $1 limit 1 for NO KEY UPDATE;
+
+-- name: ListScheduledTransfers :many
+SELECT * from scheduled_transfers where user_id = $1 order by id limit $2 offset $3;
+
+-- name: CancelScheduledTransfer :one
+UPDATE scheduled_transfers set status = 'cancelled', locked_until = 0
+where id = $1 and user_id = $2 and status = 'active' RETURNING *;
+
+-- name: ClaimDueScheduledTransfers :many
+-- Claims a batch of due transfers with a lease. Rows locked by another replica are skipped
+-- and a claimed row can't be claimed again until its lease runs out
+UPDATE scheduled_transfers set locked_until = sqlc.arg(locked_until)
+where id in (
+  SELECT id from scheduled_transfers
+  where status = 'active' and next_attempt_at <= sqlc.arg(now) and locked_until < sqlc.arg(now)
+  order by next_attempt_at
+  limit sqlc.arg(count)
+  for update skip locked
+) RETURNING *;
+
+-- name: UpdateScheduledTransferState :one
+UPDATE scheduled_transfers set
+  status = $2, scheduled_at = $3, next_attempt_at = $4, attempts = $5, locked_until = 0
+where id = $1 RETURNING *;
+
+-- name: CreateScheduledTransferRun :one
+INSERT into scheduled_transfer_runs (
+  "scheduled_transfer_id", "transaction_id", "status", "attempt", "error", "scheduled_at", "created_at"
+)
+values
+($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+SELECT * from scheduled_transfer_runs where scheduled_transfer_id = $1 order by id;
\ No newline at end of file
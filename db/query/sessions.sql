@@ -1,12 +1,38 @@
 -- name: CreateSession :one
 INSERT into sessions (
-  "id", "user_id", "refresh_token", "user_agent", "client_ip", "expires_at", "created_at"
+  "id", "user_id", "family_id", "refresh_token", "user_agent", "client_ip", "expires_at", "created_at"
 )
 values
-($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
 
 -- name: GetSession :one
 SELECT * from sessions where id = $1 LIMIT 1;
 
 -- name: UpdateSession :exec
-UPDATE sessions set is_blocked = $1 where user_id = $2;
\ No newline at end of file
+UPDATE sessions set is_blocked = $1 where user_id = $2;
+
+-- name: ConsumeSession :one
+-- Marks a refresh token as used. Returns no rows when it was already used or blocked
+UPDATE sessions set consumed_at = $1
+where id = $2 and consumed_at = 0 and is_blocked = false RETURNING *;
+
+-- name: BlockSessionFamily :execrows
+UPDATE sessions set is_blocked = true where user_id = $1 and family_id = $2;
+
+-- name: ListActiveSessions :many
+-- Lists the sessions that can still be refreshed, one per login
+SELECT * from sessions
+where user_id = $1 and is_blocked = false and consumed_at = 0 and expires_at > $2
+order by created_at desc;
+
+-- name: GetTokenRevocation :one
+-- Returns what decides whether an access token is still valid: when the user last
+-- changed their password and whether the session the token belongs to was blocked
+SELECT u.password_changed_at, EXISTS (
+  SELECT 1 from sessions s where s.family_id = $2 and s.user_id = u.id and s.is_blocked
+) AS session_blocked
+from users u where u.id = $1;
+
+-- name: BlockOtherSessions :execrows
+-- Logs the user out everywhere except the session the request came from
+UPDATE sessions set is_blocked = true where user_id = $1 and family_id <> $2;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: CreateSigningKey :one
+INSERT into signing_keys (
+  "id", "token_type", "secret", "public_key", "not_before", "created_at"
+)
+values
+($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: ListSigningKeys :many
+SELECT * from signing_keys where token_type = $1 order by not_before;
+
+-- name: ListActiveSigningKeys :many
+SELECT * from signing_keys where token_type = $1 and retired_at = 0 order by not_before;
+
+-- name: RetireSigningKey :one
+UPDATE signing_keys set retired_at = $1 where token_type = $2 and id = $3 and retired_at = 0 RETURNING *;
+
+-- name: UpdateSigningKeySecret :one
+UPDATE signing_keys set secret = $1, public_key = $2 where token_type = $3 and id = $4 RETURNING *;
\ No newline at end of file
@@ -1,12 +1,45 @@
 -- name: CreateTransaction :one
 INSERT into transactions (
- "from_account_id", "to_account_id", "from_entry_id", "to_entry_id", "amount", "created_at"
+ "from_account_id", "to_account_id", "from_entry_id", "to_entry_id", "amount", "to_amount", "rate", "from_currency", "to_currency", "created_at"
 )
 values
-($1, $2, $3, $4, $5, $6) RETURNING *;
+($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING *;
 
 -- name: GetTransaction :one
 SELECT * from transactions where id = $1 limit 1;
 
 -- name: ListTransactions :many
-SELECT * from transactions order by id limit $1 offset $2;
\ No newline at end of file
+SELECT * from transactions order by id limit $1 offset $2;
+
+-- name: GetTransactionForUser :one
+SELECT t.* from transactions t
+where t.id = sqlc.arg(id) and exists (
+  SELECT 1 from accounts a
+  where a.user_id = sqlc.arg(user_id) and a.id in (t.from_account_id, t.to_account_id)
+) limit 1;
+
+-- name: ListAccountTransactions :many
+SELECT * from transactions
+where (
+    (from_account_id = sqlc.arg(account_id) and sqlc.arg(direction)::varchar <> 'in') or
+    (to_account_id = sqlc.arg(account_id) and sqlc.arg(direction)::varchar <> 'out')
+  )
+  and created_at between sqlc.arg(from_time) and sqlc.arg(to_time)
+  and amount between sqlc.arg(min_amount) and sqlc.arg(max_amount)
+  and id < sqlc.arg(cursor)
+order by id desc
+limit sqlc.arg(count);
+
+-- name: ListTransactionsByEntries :many
+SELECT * from transactions
+where from_entry_id = ANY(sqlc.arg(entry_ids)::bigint[]) or to_entry_id = ANY(sqlc.arg(entry_ids)::bigint[]);
+
+-- name: ListUnbalancedTransactions :many
+SELECT t.* from transactions t
+join entries fe on fe.id = t.from_entry_id
+join entries te on te.id = t.to_entry_id
+where fe.account_id <> t.from_account_id
+  or te.account_id <> t.to_account_id
+  or fe.amount <> -t.amount
+  or te.amount <> t.to_amount
+order by t.id;
\ No newline at end of file
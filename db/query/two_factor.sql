@@ -0,0 +1,57 @@
+-- name: UpsertTotpCredential :one
+-- Starts an enrolment or replaces one that was never confirmed. Returns no rows
+-- when the user already has a confirmed credential
+INSERT into totp_credentials (
+  "user_id", "secret", "created_at"
+)
+values
+($1, $2, $3)
+ON CONFLICT ("user_id") DO UPDATE set secret = EXCLUDED.secret, created_at = EXCLUDED.created_at
+where totp_credentials.confirmed_at = 0 RETURNING *;
+
+-- name: GetTotpCredential :one
+SELECT * from totp_credentials where user_id = $1 limit 1;
+
+-- name: ConfirmTotpCredential :one
+UPDATE totp_credentials set confirmed_at = $1, last_used_step = $2
+where user_id = $3 and confirmed_at = 0 RETURNING *;
+
+-- name: UseTotpStep :one
+-- Records the step a code was accepted for. Returns no rows when a code for
+-- the same or a later step was already used
+UPDATE totp_credentials set last_used_step = $1
+where user_id = $2 and confirmed_at > 0 and last_used_step < $1 RETURNING *;
+
+-- name: DeleteTotpCredential :exec
+DELETE from totp_credentials where user_id = $1;
+
+-- name: CreateRecoveryCode :one
+INSERT into recovery_codes (
+  "user_id", "code_hash", "created_at"
+)
+values
+($1, $2, $3) RETURNING *;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes set used_at = $1
+where user_id = $2 and code_hash = $3 and used_at = 0 RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE from recovery_codes where user_id = $1;
+
+-- name: CreateLoginChallenge :one
+INSERT into login_challenges (
+  "user_id", "token_hash", "scopes", "expires_at", "created_at"
+)
+values
+($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: AttemptLoginChallenge :one
+-- Counts an attempt at answering a challenge. Returns no rows when it is unknown,
+-- already answered, expired or out of attempts
+UPDATE login_challenges set attempts = attempts + 1
+where token_hash = sqlc.arg(token_hash) and used_at = 0 and expires_at > sqlc.arg(now)
+and attempts < sqlc.arg(max_attempts)::int RETURNING *;
+
+-- name: UseLoginChallenge :one
+UPDATE login_challenges set used_at = $1 where id = $2 and used_at = 0 RETURNING *;
\ No newline at end of file
@@ -16,4 +16,23 @@ SELECT * from Users order by id limit $1 offset $2;
 
 -- name: UpdatePassword :one
 UPDATE users set password = sqlc.arg(password), password_changed_at = sqlc.arg(passwordChangedAt)
-where id = sqlc.arg(id) RETURNING *;
\ No newline at end of file
+where id = sqlc.arg(id) RETURNING *;
+
+-- name: UpdateUserRole :one
+UPDATE users set role = $1 where id = $2 RETURNING *;
+
+-- name: SearchUsersByEmail :many
+SELECT * from users where email ILIKE '%' || sqlc.arg(email)::varchar || '%'
+order by id limit sqlc.arg(count) offset sqlc.arg(skip);
+
+-- name: VerifyUserEmail :one
+-- Returns no rows when the user changed their email since the token was sent
+UPDATE users set email_verified = true where id = $1 and email = $2 RETURNING *;
+
+-- name: SetUserTwoFactor :one
+UPDATE users set two_factor_enabled = $1 where id = $2 RETURNING *;
+
+-- name: UpdateUserProfile :one
+-- A changed email is unverified until the new address is verified
+UPDATE users set name = $1, email = $2, email_verified = email_verified and email = $2
+where id = $3 RETURNING *;
\ No newline at end of file
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+//Returned by TransferTx when either account was frozen by an admin
+var ErrAccountFrozen = errors.New("Account is frozen")
+
+//Returned by TransferTx when either account was closed by its owner
+var ErrAccountClosed = errors.New("Account is closed")
+
+//Returned by CloseAccountTx when money is left in the account and no sweep account is given
+var ErrAccountNotEmpty = errors.New("Account balance must be zero to close it")
+
+//Returned when an account can't move from its current status to the requested one
+var ErrAccountStatusChange = errors.New("Account status can't be changed")
+
+//Returned by CloseAccountTx when the sweep account is the account itself or uses another currency
+var ErrInvalidSweepAccount = errors.New("Sweep account must be another account in the same currency")
+
+//Why an account can't send or receive money, nil when it is active
+func AccountStatusError(status string) error {
+	switch status {
+	case AccountStatusFrozen:
+		return ErrAccountFrozen
+	case AccountStatusClosed:
+		return ErrAccountClosed
+	}
+	return nil
+}
+
+//The audit action for each allowed status change, keyed by the old and the new status.
+//Admins freeze and unfreeze, owners close and reopen
+var accountStatusActions = map[[2]string]string{
+	{AccountStatusActive, AccountStatusFrozen}: AuditAccountFreeze,
+	{AccountStatusFrozen, AccountStatusActive}: AuditAccountUnfreeze,
+	{AccountStatusActive, AccountStatusClosed}: AuditAccountClose,
+	{AccountStatusClosed, AccountStatusActive}: AuditAccountReopen,
+}
+
+//Input for SetAccountStatusTx, Actor is the admin or owner making the change. From is the status
+//the account must have, so an admin unfreezing an account can't reopen one its owner closed
+type SetAccountStatusTxParams struct {
+	AccountID int64      `json:"account_id"`
+	From      string     `json:"from"`
+	Status    string     `json:"status"`
+	Actor     AuditActor `json:"actor"`
+	Reason    string     `json:"reason"`
+}
+
+//Moves an account to a new status and records it in the audit log in the same transaction.
+//Only accounts with a zero balance can be closed
+func (store *SQLStore) SetAccountStatusTx(ctx context.Context, arg SetAccountStatusTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		account, err = changeAccountStatus(ctx, q, arg)
+		return err
+	})
+
+	return account, err
+}
+
+func changeAccountStatus(ctx context.Context, q *Queries, arg SetAccountStatusTxParams) (Account, error) {
+	before, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+	if err != nil {
+		return Account{}, err
+	}
+	if before.Status != arg.From {
+		//Frozen and closed accounts are reported the same way transfers report them
+		if err := AccountStatusError(before.Status); err != nil {
+			return Account{}, err
+		}
+		return Account{}, fmt.Errorf("%w, it is already %s", ErrAccountStatusChange, before.Status)
+	}
+	action, ok := accountStatusActions[[2]string{before.Status, arg.Status}]
+	if !ok {
+		return Account{}, fmt.Errorf("%w from %s to %s", ErrAccountStatusChange, before.Status, arg.Status)
+	}
+	if arg.Status == AccountStatusClosed && before.Balance != 0 {
+		return Account{}, ErrAccountNotEmpty
+	}
+
+	account, err := q.SetAccountStatus(ctx, SetAccountStatusParams{
+		Status: arg.Status,
+		ID:     arg.AccountID,
+	})
+	if err != nil {
+		return Account{}, err
+	}
+
+	audit, err := NewAuditLogParams(arg.Actor, action, AuditTargetAccount, strconv.FormatInt(arg.AccountID, 10), arg.Reason, before, account)
+	if err != nil {
+		return Account{}, err
+	}
+	_, err = appendAuditLog(ctx, q, audit)
+	return account, err
+}
+
+//Input for CloseAccountTx. When SweepAccountID is set the remaining balance is moved there first
+type CloseAccountTxParams struct {
+	AccountID      int64      `json:"account_id"`
+	SweepAccountID int64      `json:"sweep_account_id"`
+	Actor          AuditActor `json:"actor"`
+}
+
+type CloseAccountTxResult struct {
+	Account Account `json:"account"`
+	//Only set when money was swept out of the account
+	Sweep *TransferTxResult `json:"sweep,omitempty"`
+}
+
+//Closes an account, sweeping whatever is left in it to another account in the same transaction
+//so a transfer can't land between the sweep and the close
+func (store *SQLStore) CloseAccountTx(ctx context.Context, arg CloseAccountTxParams) (CloseAccountTxResult, error) {
+	var result CloseAccountTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if arg.SweepAccountID != 0 {
+			if arg.SweepAccountID == arg.AccountID {
+				return ErrInvalidSweepAccount
+			}
+			//Locked in id order like every transfer, the sweep below takes the same locks again
+			account, sweepAccount, err := lockAccounts(ctx, q, arg.AccountID, arg.SweepAccountID)
+			if err != nil {
+				return err
+			}
+			if account.Currency != sweepAccount.Currency {
+				return ErrInvalidSweepAccount
+			}
+			if account.Balance > 0 {
+				sweep, err := transfer(ctx, q, TransferTxParams{
+					FromAccountID: arg.AccountID,
+					ToAccountID:   arg.SweepAccountID,
+					Amount:        account.Balance,
+					CreatedAt:     time.Now().Unix(),
+				})
+				if err != nil {
+					return err
+				}
+				if err = auditTransfer(ctx, q, arg.Actor, "Account closed", sweep); err != nil {
+					return err
+				}
+				result.Sweep = &sweep
+			}
+		}
+
+		var err error
+		result.Account, err = changeAccountStatus(ctx, q, SetAccountStatusTxParams{
+			AccountID: arg.AccountID,
+			From:      AccountStatusActive,
+			Status:    AccountStatusClosed,
+			Actor:     arg.Actor,
+		})
+		return err
+	})
+
+	return result, err
+}
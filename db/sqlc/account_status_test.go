@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createTestAccount(t, 0)
+	other := createTestAccount(t, -1)
+
+	closed, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID: account.ID,
+		Actor:     AuditActor{ActorID: account.UserID},
+	})
+	require.NoError(t, err)
+	require.Nil(t, closed.Sweep)
+	require.Equal(t, AccountStatusClosed, closed.Account.Status)
+
+	//Closed accounts can neither send nor receive
+	_, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: other.ID, ToAccountID: account.ID, Amount: 1})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	//Only the owner reopens a closed account, an admin unfreeze is refused
+	_, err = store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		From:      AccountStatusFrozen,
+		Status:    AccountStatusActive,
+		Actor:     AuditActor{ActorID: other.UserID},
+		Reason:    "Customer verified",
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	reopened, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		From:      AccountStatusClosed,
+		Status:    AccountStatusActive,
+		Actor:     AuditActor{ActorID: account.UserID},
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, reopened.Status)
+
+	_, err = store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		From:      AccountStatusClosed,
+		Status:    AccountStatusActive,
+		Actor:     AuditActor{ActorID: account.UserID},
+	})
+	require.ErrorIs(t, err, ErrAccountStatusChange)
+}
+
+func TestCloseAccountTxWithBalance(t *testing.T) {
+	store := NewStore(testDB)
+	account := createTestAccount(t, 100)
+
+	_, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID})
+	require.ErrorIs(t, err, ErrAccountNotEmpty)
+
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID, SweepAccountID: account.ID})
+	require.ErrorIs(t, err, ErrInvalidSweepAccount)
+
+	user := createTestUser(t)
+	sweepAccount, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Name:      user.Name,
+		UserID:    user.ID,
+		Currency:  account.Currency,
+		Number:    util.GenerateAccountNumber(),
+		CreatedAt: time.Now().Unix(),
+	})
+	require.NoError(t, err)
+
+	result, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:      account.ID,
+		SweepAccountID: sweepAccount.ID,
+		Actor:          AuditActor{ActorID: account.UserID},
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, result.Account.Status)
+	require.Zero(t, result.Account.Balance)
+	require.NotNil(t, result.Sweep)
+	require.Equal(t, int64(100), result.Sweep.Transaction.Amount)
+	require.Equal(t, int64(100), result.Sweep.ToAccount.Balance)
+}
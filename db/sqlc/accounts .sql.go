@@ -11,10 +11,10 @@ import (
 
 const createAccount = `-- name: CreateAccount :one
 INSERT into accounts (
-  "user_id", "name", "balance", "currency", "created_at"
+  "user_id", "name", "balance", "currency", "number", "created_at"
 )
 values
-($1, $2, $3, $4, $5) RETURNING id, name, balance, currency, created_at, user_id
+($1, $2, $3, $4, $5, $6) RETURNING id, name, balance, currency, created_at, user_id, status, number
 `
 
 type CreateAccountParams struct {
@@ -22,6 +22,7 @@ type CreateAccountParams struct {
 	Name      string `json:"name"`
 	Balance   int64  `json:"balance"`
 	Currency  string `json:"currency"`
+	Number    string `json:"number"`
 	CreatedAt int64  `json:"created_at"`
 }
 
@@ -31,6 +32,7 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		arg.Name,
 		arg.Balance,
 		arg.Currency,
+		arg.Number,
 		arg.CreatedAt,
 	)
 	var i Account
@@ -41,6 +43,8 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Currency,
 		&i.CreatedAt,
 		&i.UserID,
+		&i.Status,
+		&i.Number,
 	)
 	return i, err
 }
@@ -55,7 +59,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, name, balance, currency, created_at, user_id from accounts where id = $1 limit 1
+SELECT id, name, balance, currency, created_at, user_id, status, number from accounts where id = $1 limit 1
 `
 
 func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
@@ -68,12 +72,34 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Currency,
 		&i.CreatedAt,
 		&i.UserID,
+		&i.Status,
+		&i.Number,
+	)
+	return i, err
+}
+
+const getAccountByNumber = `-- name: GetAccountByNumber :one
+SELECT id, name, balance, currency, created_at, user_id, status, number from accounts where number = $1 limit 1
+`
+
+func (q *Queries) GetAccountByNumber(ctx context.Context, number string) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getAccountByNumber, number)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.Number,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, name, balance, currency, created_at, user_id from accounts where id = $1 limit 1 for NO KEY UPDATE
+SELECT id, name, balance, currency, created_at, user_id, status, number from accounts where id = $1 limit 1 for NO KEY UPDATE
 `
 
 func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
@@ -86,12 +112,14 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Currency,
 		&i.CreatedAt,
 		&i.UserID,
+		&i.Status,
+		&i.Number,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, name, balance, currency, created_at, user_id from accounts order by id limit $1 offset $2
+SELECT id, name, balance, currency, created_at, user_id, status, number from accounts order by id limit $1 offset $2
 `
 
 type ListAccountsParams struct {
@@ -115,6 +143,8 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Currency,
 			&i.CreatedAt,
 			&i.UserID,
+			&i.Status,
+			&i.Number,
 		); err != nil {
 			return nil, err
 		}
@@ -130,7 +160,7 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 }
 
 const listAccountsForUser = `-- name: ListAccountsForUser :many
-SELECT id, name, balance, currency, created_at, user_id from accounts where user_id = $1 order by id limit $2 offset $3
+SELECT id, name, balance, currency, created_at, user_id, status, number from accounts where user_id = $1 order by id limit $2 offset $3
 `
 
 type ListAccountsForUserParams struct {
@@ -155,6 +185,8 @@ func (q *Queries) ListAccountsForUser(ctx context.Context, arg ListAccountsForUs
 			&i.Currency,
 			&i.CreatedAt,
 			&i.UserID,
+			&i.Status,
+			&i.Number,
 		); err != nil {
 			return nil, err
 		}
@@ -169,8 +201,103 @@ func (q *Queries) ListAccountsForUser(ctx context.Context, arg ListAccountsForUs
 	return items, nil
 }
 
+const listBalanceDiscrepancies = `-- name: ListBalanceDiscrepancies :many
+SELECT a.id as account_id, a.balance, COALESCE(SUM(e.amount), 0)::bigint as entries_total
+from accounts a
+left join entries e on e.account_id = a.id
+  and (exists (select 1 from transactions t where t.from_entry_id = e.id or t.to_entry_id = e.id)
+    or exists (select 1 from audit_log l where l.action = 'account.adjustment' and (l."after"->'entry'->>'id')::bigint = e.id))
+group by a.id
+having a.balance <> COALESCE(SUM(e.amount), 0)
+order by a.id
+`
+
+type ListBalanceDiscrepanciesRow struct {
+	AccountID    int64 `json:"account_id"`
+	Balance      int64 `json:"balance"`
+	EntriesTotal int64 `json:"entries_total"`
+}
+
+// Orphan entries are left out, see ListOrphanEntries
+func (q *Queries) ListBalanceDiscrepancies(ctx context.Context) ([]ListBalanceDiscrepanciesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBalanceDiscrepancies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListBalanceDiscrepanciesRow{}
+	for rows.Next() {
+		var i ListBalanceDiscrepanciesRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.Balance,
+			&i.EntriesTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setAccountStatus = `-- name: SetAccountStatus :one
+UPDATE accounts set status = $1 where id = $2 RETURNING id, name, balance, currency, created_at, user_id, status, number
+`
+
+type SetAccountStatusParams struct {
+	Status string `json:"status"`
+	ID     int64  `json:"id"`
+}
+
+func (q *Queries) SetAccountStatus(ctx context.Context, arg SetAccountStatusParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, setAccountStatus, arg.Status, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.Number,
+	)
+	return i, err
+}
+
+const setBalance = `-- name: SetBalance :one
+UPDATE accounts set balance = $1 where id = $2 RETURNING id, name, balance, currency, created_at, user_id, status, number
+`
+
+type SetBalanceParams struct {
+	Balance int64 `json:"balance"`
+	ID      int64 `json:"id"`
+}
+
+func (q *Queries) SetBalance(ctx context.Context, arg SetBalanceParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, setBalance, arg.Balance, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.Number,
+	)
+	return i, err
+}
+
 const updateBalance = `-- name: UpdateBalance :one
-UPDATE accounts set balance = balance + $1 where id = $2 RETURNING id, name, balance, currency, created_at, user_id
+UPDATE accounts set balance = balance + $1 where id = $2 RETURNING id, name, balance, currency, created_at, user_id, status, number
 `
 
 type UpdateBalanceParams struct {
@@ -188,6 +315,8 @@ func (q *Queries) UpdateBalance(ctx context.Context, arg UpdateBalanceParams) (A
 		&i.Currency,
 		&i.CreatedAt,
 		&i.UserID,
+		&i.Status,
+		&i.Number,
 	)
 	return i, err
 }
@@ -20,6 +20,7 @@ func createTestAccount(t *testing.T, balance int64) Account {
 		UserID:    user.ID,
 		Balance:   balance,
 		Currency:  util.GenerateCurrency(),
+		Number:    util.GenerateAccountNumber(),
 		CreatedAt: time.Now().Unix(),
 	}
 	account, err := testQueries.CreateAccount(context.Background(), arg)
@@ -30,6 +31,7 @@ func createTestAccount(t *testing.T, balance int64) Account {
 	require.Equal(t, arg.Name, account.Name)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, arg.Number, account.Number)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -49,6 +51,17 @@ func TestGetAccount(t *testing.T) {
 	require.Equal(t, account.ID, account2.ID)
 }
 
+func TestGetAccountByNumber(t *testing.T) {
+	account := createTestAccount(t, -1)
+	account2, err := testQueries.GetAccountByNumber(context.Background(), account.Number)
+
+	require.NoError(t, err)
+	require.Equal(t, account.ID, account2.ID)
+
+	_, err = testQueries.GetAccountByNumber(context.Background(), util.GenerateAccountNumber())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
 func TestListAccounts(t *testing.T) {
 	for i := 0; i < 5; i++ {
 		createTestAccount(t, -1)
@@ -109,3 +122,68 @@ func TestListAccountsForUser(t *testing.T) {
 	require.NotEmpty(t, list)
 	require.Equal(t, account.UserID, list[0].UserID)
 }
+
+func TestListBalanceDiscrepancies(t *testing.T) {
+	//Created with a balance but no entries, so it always drifts
+	account := createTestAccount(t, -1)
+
+	list, err := testQueries.ListBalanceDiscrepancies(context.Background())
+	require.NoError(t, err)
+
+	found := false
+	for _, discrepancy := range list {
+		require.NotEqual(t, discrepancy.Balance, discrepancy.EntriesTotal)
+		if discrepancy.AccountID == account.ID {
+			found = true
+			require.Equal(t, account.Balance, discrepancy.Balance)
+			require.Zero(t, discrepancy.EntriesTotal)
+		}
+	}
+	require.True(t, found)
+}
+
+func TestRepairBalanceTx(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createTestUser(t)
+	account := createTestAccount(t, -1)
+	other := createTestAccount(t, 100)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	adjustment, err := store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Amount:    5,
+		Currency:  account.Currency,
+		Actor:     AuditActor{ActorID: admin.ID},
+		Reason:    "Refunded card fee",
+	})
+	require.NoError(t, err)
+
+	//Written by transfers before the ledger was fixed, it never moved the balance
+	orphan, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    util.GenerateAmount(),
+		CreatedAt: time.Now().Unix(),
+	})
+	require.NoError(t, err)
+
+	orphans, err := testQueries.ListOrphanEntries(context.Background())
+	require.NoError(t, err)
+	ids := make(map[int64]bool, len(orphans))
+	for _, entry := range orphans {
+		ids[entry.ID] = true
+	}
+	require.True(t, ids[orphan.ID])
+	require.False(t, ids[transfer.ToEntry.ID])
+	require.False(t, ids[adjustment.Entry.ID])
+
+	//The balance comes from the transfer and the adjustment, the orphan isn't applied
+	repaired, err := store.RepairBalanceTx(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, repaired.ID)
+	require.Equal(t, int64(15), repaired.Balance)
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+//Returned when an adjustment is in another currency than the account
+var ErrAdjustmentCurrency = errors.New("Adjustment currency doesn't match the account currency")
+
+//Input for AdjustBalanceTx. A negative amount takes money out of the account, the currency
+//has to be the account's so the minor units mean what the admin expects
+type AdjustBalanceTxParams struct {
+	AccountID int64      `json:"account_id"`
+	Amount    int64      `json:"amount"`
+	Currency  string     `json:"currency"`
+	Actor     AuditActor `json:"actor"`
+	Reason    string     `json:"reason"`
+}
+
+type AdjustBalanceTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+//Posts a manual entry against an account, for example to correct a failed external payment.
+//The balance moves with the entry so the ledger still reconciles, and the reason is audited
+func (store *SQLStore) AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error) {
+	var result AdjustBalanceTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		if before.Status == AccountStatusClosed {
+			return ErrAccountClosed
+		}
+		if before.Currency != arg.Currency {
+			return ErrAdjustmentCurrency
+		}
+		if before.Balance+arg.Amount < 0 {
+			return ErrInsufficientFunds
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+			CreatedAt: time.Now().Unix(),
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.UpdateBalance(ctx, UpdateBalanceParams{
+			Amount: arg.Amount,
+			ID:     arg.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+
+		audit, err := NewAuditLogParams(arg.Actor, AuditAccountAdjustment, AuditTargetAccount, strconv.FormatInt(arg.AccountID, 10), arg.Reason, before, result)
+		if err != nil {
+			return err
+		}
+		_, err = appendAuditLog(ctx, q, audit)
+		return err
+	})
+
+	return result, err
+}
+
+//Input for UpdateUserRoleTx, Actor is the admin making the change
+type UpdateUserRoleTxParams struct {
+	UserID int64      `json:"user_id"`
+	Role   string     `json:"role"`
+	Actor  AuditActor `json:"actor"`
+	Reason string     `json:"reason"`
+}
+
+//Changes a user's role and logs every session out so no token carries the old role, recording
+//the change in the audit log in the same transaction
+func (store *SQLStore) UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetUser(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+		user, err = q.UpdateUserRole(ctx, UpdateUserRoleParams{
+			Role: arg.Role,
+			ID:   arg.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.UpdateSession(ctx, UpdateSessionParams{
+			IsBlocked: true,
+			UserID:    arg.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		audit, err := NewAuditLogParams(arg.Actor, AuditUserRoleChange, AuditTargetUser, strconv.FormatInt(arg.UserID, 10), arg.Reason,
+			map[string]string{"role": before.Role}, map[string]string{"role": user.Role})
+		if err != nil {
+			return err
+		}
+		_, err = appendAuditLog(ctx, q, audit)
+		return err
+	})
+
+	return user, err
+}
+
+//Input for BlockUserSessionsTx, Actor is the admin logging the user out
+type BlockUserSessionsTxParams struct {
+	UserID int64      `json:"user_id"`
+	Actor  AuditActor `json:"actor"`
+	Reason string     `json:"reason"`
+}
+
+//Logs every session of the user out and records it in the audit log in the same transaction
+func (store *SQLStore) BlockUserSessionsTx(ctx context.Context, arg BlockUserSessionsTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		err := q.UpdateSession(ctx, UpdateSessionParams{
+			IsBlocked: true,
+			UserID:    arg.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		audit, err := NewAuditLogParams(arg.Actor, AuditUserSessionsBlock, AuditTargetUser, strconv.FormatInt(arg.UserID, 10), arg.Reason, nil, nil)
+		if err != nil {
+			return err
+		}
+		_, err = appendAuditLog(ctx, q, audit)
+		return err
+	})
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAccountStatusTx(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createTestUser(t)
+	account1 := createTestAccount(t, -1)
+	account2 := createTestAccount(t, -1)
+
+	frozen, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account1.ID,
+		From:      AccountStatusActive,
+		Status:    AccountStatusFrozen,
+		Actor:     AuditActor{ActorID: admin.ID},
+		Reason:    "Reported as compromised",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, frozen.Status)
+
+	//Frozen accounts can neither send nor receive
+	_, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 1})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+	_, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 1})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	logs, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		TargetType: AuditTargetAccount,
+		TargetID:   fmt.Sprint(account1.ID),
+		Count:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, admin.ID, logs[0].ActorID)
+	require.Equal(t, AuditAccountFreeze, logs[0].Action)
+	require.Equal(t, "Reported as compromised", logs[0].Reason)
+
+	var before, after Account
+	require.NoError(t, json.Unmarshal(logs[0].Before, &before))
+	require.NoError(t, json.Unmarshal(logs[0].After, &after))
+	require.Equal(t, AccountStatusActive, before.Status)
+	require.Equal(t, AccountStatusFrozen, after.Status)
+
+	active, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account1.ID,
+		From:      AccountStatusFrozen,
+		Status:    AccountStatusActive,
+		Actor:     AuditActor{ActorID: admin.ID},
+		Reason:    "Customer verified",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, active.Status)
+}
+
+func TestAdjustBalanceTx(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createTestUser(t)
+	account := createTestAccount(t, 100)
+
+	result, err := store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Amount:    -40,
+		Currency:  account.Currency,
+		Actor:     AuditActor{ActorID: admin.ID},
+		Reason:    "Reversed card payment",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(60), result.Account.Balance)
+	require.Equal(t, int64(-40), result.Entry.Amount)
+	require.Equal(t, account.ID, result.Entry.AccountID)
+
+	_, err = store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Amount:    -61,
+		Currency:  account.Currency,
+		Actor:     AuditActor{ActorID: admin.ID},
+		Reason:    "Reversed card payment",
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	//Minor units of another currency would move a different amount than meant
+	other := "USD"
+	if account.Currency == other {
+		other = "EUR"
+	}
+	_, err = store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Amount:    10,
+		Currency:  other,
+		Actor:     AuditActor{ActorID: admin.ID},
+		Reason:    "Reversed card payment",
+	})
+	require.ErrorIs(t, err, ErrAdjustmentCurrency)
+
+	logs, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		TargetType: AuditTargetAccount,
+		TargetID:   fmt.Sprint(account.ID),
+		Count:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, AuditAccountAdjustment, logs[0].Action)
+}
+
+func TestUpdateUserRoleTx(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createTestUser(t)
+	user := createTestUser(t)
+	session := createTestUserSession(t, user.ID)
+
+	updated, err := store.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{
+		UserID: user.ID,
+		Role:   util.RoleSupport,
+		Actor:  AuditActor{ActorID: admin.ID},
+		Reason: "Joined support",
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.RoleSupport, updated.Role)
+
+	//Tokens carrying the old role can't be refreshed
+	blocked, err := store.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	logs, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		TargetType: AuditTargetUser,
+		TargetID:   fmt.Sprint(user.ID),
+		Count:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, AuditUserRoleChange, logs[0].Action)
+	require.JSONEq(t, `{"role":"customer"}`, string(logs[0].Before))
+	require.JSONEq(t, `{"role":"support"}`, string(logs[0].After))
+
+	//Nothing is changed or audited when the role is rejected
+	_, err = store.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{
+		UserID: user.ID,
+		Role:   "owner",
+		Actor:  AuditActor{ActorID: admin.ID},
+	})
+	require.Error(t, err)
+	logs, err = store.ListAuditLog(context.Background(), ListAuditLogParams{
+		TargetType: AuditTargetUser,
+		TargetID:   fmt.Sprint(user.ID),
+		Count:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+}
+
+func TestBlockUserSessionsTx(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createTestUser(t)
+	user := createTestUser(t)
+	session := createTestUserSession(t, user.ID)
+
+	err := store.BlockUserSessionsTx(context.Background(), BlockUserSessionsTxParams{
+		UserID: user.ID,
+		Actor:  AuditActor{ActorID: admin.ID},
+		Reason: "Credentials leaked",
+	})
+	require.NoError(t, err)
+
+	blocked, err := store.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	logs, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		TargetType: AuditTargetUser,
+		TargetID:   fmt.Sprint(user.ID),
+		Count:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, AuditUserSessionsBlock, logs[0].Action)
+	require.Equal(t, "Credentials leaked", logs[0].Reason)
+}
+
+func TestSearchUsersByEmail(t *testing.T) {
+	user := createTestUser(t)
+
+	users, err := testQueries.SearchUsersByEmail(context.Background(), SearchUsersByEmailParams{
+		Email: user.Email[:len(user.Email)-2],
+		Count: 10,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, users)
+
+	found := false
+	for _, match := range users {
+		found = found || match.ID == user.ID
+	}
+	require.True(t, found)
+}
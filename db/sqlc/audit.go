@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+//Actions written to the audit log
+const (
+	AuditAccountCreate        = "account.create"
+	AuditAccountFreeze        = "account.freeze"
+	AuditAccountUnfreeze      = "account.unfreeze"
+	AuditAccountClose         = "account.close"
+	AuditAccountReopen        = "account.reopen"
+	AuditAccountAdjustment    = "account.adjustment"
+	AuditTransferCreate       = "transfer.create"
+	AuditUserLogin            = "user.login"
+	AuditUserLoginFailed      = "user.login_failed"
+	AuditUserLoginLockout     = "user.login_lockout"
+	AuditUserEmailVerify      = "user.email_verify"
+	AuditUserPasswordChange   = "user.password_change"
+	AuditUserPasswordReset    = "user.password_reset"
+	AuditUserProfileUpdate    = "user.profile_update"
+	AuditUserRoleChange       = "user.role_change"
+	AuditUserSessionsBlock    = "user.sessions_block"
+	AuditUserTwoFactorEnable  = "user.2fa_enable"
+	AuditUserTwoFactorDisable = "user.2fa_disable"
+	AuditSessionRefresh       = "session.refresh"
+)
+
+//Kinds of records an audit log row points at
+const (
+	AuditTargetAccount  = "account"
+	AuditTargetEmail    = "email"
+	AuditTargetIP       = "ip"
+	AuditTargetSession  = "session"
+	AuditTargetTransfer = "transfer"
+	AuditTargetUser     = "user"
+)
+
+//Who made a change and where the request came from. ActorID is zero when the caller
+//isn't known, for example a failed login for an unregistered email
+type AuditActor struct {
+	ActorID   int64  `json:"actor_id"`
+	RequestID string `json:"request_id"`
+	ClientIp  string `json:"client_ip"`
+}
+
+//Builds an audit log row with before and after stored as JSON snapshots of the target.
+//Either snapshot can be nil when the record didn't exist before or after the action
+func NewAuditLogParams(actor AuditActor, action, targetType, targetID, reason string, before, after interface{}) (CreateAuditLogParams, error) {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return CreateAuditLogParams{}, err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return CreateAuditLogParams{}, err
+	}
+	return CreateAuditLogParams{
+		ActorID:    actor.ActorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Before:     beforeJSON,
+		After:      afterJSON,
+		RequestID:  actor.RequestID,
+		ClientIp:   actor.ClientIp,
+		CreatedAt:  time.Now().Unix(),
+	}, nil
+}
+
+//Hash of a row's content and the hash of the row before it, so changing or removing
+//any row breaks every hash after it. The id isn't part of it since it's assigned on insert
+func auditLogHash(arg CreateAuditLogParams) (string, error) {
+	data, err := json.Marshal([]interface{}{
+		arg.PrevHash,
+		arg.ActorID,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.Reason,
+		arg.Before,
+		arg.After,
+		arg.RequestID,
+		arg.ClientIp,
+		arg.CreatedAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+//Links the row to the end of the chain and inserts it. Callers must run it in a transaction,
+//the lock is held until it commits so concurrent writers can't link to the same row
+func appendAuditLog(ctx context.Context, q *Queries, arg CreateAuditLogParams) (AuditLog, error) {
+	if err := q.LockAuditLog(ctx); err != nil {
+		return AuditLog{}, err
+	}
+	last, err := q.GetLastAuditLog(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		return AuditLog{}, err
+	}
+	arg.PrevHash = last.Hash
+	arg.Hash, err = auditLogHash(arg)
+	if err != nil {
+		return AuditLog{}, err
+	}
+	return q.CreateAuditLog(ctx, arg)
+}
+
+//Writes a row to the end of the hash chained audit log
+func (store *SQLStore) AppendAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error) {
+	var log AuditLog
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		log, err = appendAuditLog(ctx, q, arg)
+		return err
+	})
+	return log, err
+}
+
+const auditVerifyBatch = 500
+
+//Outcome of walking the audit log chain
+type AuditVerification struct {
+	//Rows checked against the chain
+	Checked int64 `json:"checked"`
+	//Rows written before the log was chained, they come first and have no hash
+	Unchained int64 `json:"unchained"`
+	//First row whose hash or link to the previous row doesn't match, zero when the chain is intact
+	BrokenAt int64 `json:"broken_at"`
+}
+
+func (v AuditVerification) Valid() bool {
+	return v.BrokenAt == 0
+}
+
+//Recomputes every hash in id order and stops at the first row that doesn't match
+func VerifyAuditLog(ctx context.Context, q Querier) (AuditVerification, error) {
+	var result AuditVerification
+	prevHash := ""
+	lastID := int64(0)
+	for {
+		logs, err := q.ListAuditLogAfter(ctx, ListAuditLogAfterParams{
+			ID:    lastID,
+			Limit: auditVerifyBatch,
+		})
+		if err != nil {
+			return result, err
+		}
+		for _, log := range logs {
+			lastID = log.ID
+			if result.Checked == 0 && log.Hash == "" {
+				result.Unchained++
+				continue
+			}
+			result.Checked++
+			//A snapshot that no longer parses counts as tampering rather than an error
+			hash, err := auditLogHash(CreateAuditLogParams{
+				ActorID:    log.ActorID,
+				Action:     log.Action,
+				TargetType: log.TargetType,
+				TargetID:   log.TargetID,
+				Reason:     log.Reason,
+				Before:     log.Before,
+				After:      log.After,
+				RequestID:  log.RequestID,
+				ClientIp:   log.ClientIp,
+				PrevHash:   log.PrevHash,
+				CreatedAt:  log.CreatedAt,
+			})
+			if err != nil || log.PrevHash != prevHash || log.Hash != hash {
+				result.BrokenAt = log.ID
+				return result, nil
+			}
+			prevHash = log.Hash
+		}
+		if len(logs) < auditVerifyBatch {
+			return result, nil
+		}
+	}
+}
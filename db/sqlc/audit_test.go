@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createTestAuditLog(t *testing.T, store Store, before, after interface{}) AuditLog {
+	user := createTestUser(t)
+	arg, err := NewAuditLogParams(AuditActor{ActorID: user.ID, RequestID: "request", ClientIp: "127.0.0.1"},
+		AuditUserLogin, AuditTargetUser, fmt.Sprint(user.ID), "", before, after)
+	require.NoError(t, err)
+
+	log, err := store.AppendAuditLog(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, log.ID)
+	require.Equal(t, arg.RequestID, log.RequestID)
+	require.Equal(t, arg.ClientIp, log.ClientIp)
+	require.NotEmpty(t, log.Hash)
+	return log
+}
+
+func TestAppendAuditLog(t *testing.T) {
+	store := NewStore(testDB)
+	log1 := createTestAuditLog(t, store, nil, map[string]string{"session_id": "1"})
+	log2 := createTestAuditLog(t, store, nil, nil)
+
+	require.Equal(t, log1.Hash, log2.PrevHash)
+
+	result, err := VerifyAuditLog(context.Background(), store)
+	require.NoError(t, err)
+	require.True(t, result.Valid())
+	require.NotZero(t, result.Checked)
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	log := createTestAuditLog(t, NewStore(testDB), nil, nil)
+
+	_, err := testDB.Exec(`UPDATE audit_log SET reason = 'changed' WHERE id = $1`, log.ID)
+	require.Error(t, err)
+	_, err = testDB.Exec(`DELETE FROM audit_log WHERE id = $1`, log.ID)
+	require.Error(t, err)
+}
+
+//Serves audit log rows from memory so tampering can be simulated
+type memoryAuditLog struct {
+	Querier
+	logs []AuditLog
+}
+
+func (m *memoryAuditLog) ListAuditLogAfter(ctx context.Context, arg ListAuditLogAfterParams) ([]AuditLog, error) {
+	result := []AuditLog{}
+	for _, log := range m.logs {
+		if log.ID > arg.ID && len(result) < int(arg.Limit) {
+			result = append(result, log)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryAuditLog) append(t *testing.T, arg CreateAuditLogParams) {
+	if len(m.logs) > 0 {
+		arg.PrevHash = m.logs[len(m.logs)-1].Hash
+	}
+	hash, err := auditLogHash(arg)
+	require.NoError(t, err)
+	m.logs = append(m.logs, AuditLog{
+		ID:         int64(len(m.logs) + 1),
+		ActorID:    arg.ActorID,
+		Action:     arg.Action,
+		TargetType: arg.TargetType,
+		TargetID:   arg.TargetID,
+		Reason:     arg.Reason,
+		Before:     arg.Before,
+		After:      arg.After,
+		CreatedAt:  arg.CreatedAt,
+		RequestID:  arg.RequestID,
+		ClientIp:   arg.ClientIp,
+		PrevHash:   arg.PrevHash,
+		Hash:       hash,
+	})
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	newChain := func(t *testing.T) *memoryAuditLog {
+		chain := &memoryAuditLog{}
+		for i := 0; i < auditVerifyBatch+10; i++ {
+			arg, err := NewAuditLogParams(AuditActor{ActorID: int64(i)}, AuditAccountAdjustment, AuditTargetAccount,
+				fmt.Sprint(i), "Reversed card payment", map[string]int64{"balance": 100}, map[string]int64{"balance": int64(i)})
+			require.NoError(t, err)
+			chain.append(t, arg)
+		}
+		return chain
+	}
+
+	testCases := []struct {
+		name     string
+		tamper   func(logs []AuditLog) []AuditLog
+		brokenAt int64
+	}{
+		{
+			name:     "Intact",
+			tamper:   func(logs []AuditLog) []AuditLog { return logs },
+			brokenAt: 0,
+		},
+		{
+			name: "ChangedSnapshot",
+			tamper: func(logs []AuditLog) []AuditLog {
+				logs[10].After = []byte(`{"balance":1000000}`)
+				return logs
+			},
+			brokenAt: 11,
+		},
+		{
+			name: "ChangedActor",
+			tamper: func(logs []AuditLog) []AuditLog {
+				logs[auditVerifyBatch+5].ActorID = 1
+				return logs
+			},
+			brokenAt: auditVerifyBatch + 6,
+		},
+		{
+			name: "DeletedRow",
+			tamper: func(logs []AuditLog) []AuditLog {
+				return append(logs[:20], logs[21:]...)
+			},
+			brokenAt: 22,
+		},
+		{
+			name: "RehashedRow",
+			tamper: func(logs []AuditLog) []AuditLog {
+				//Recomputing the changed row's hash still breaks the link from the next one
+				logs[30].Reason = "changed"
+				logs[30].Hash, _ = auditLogHash(CreateAuditLogParams{
+					ActorID:    logs[30].ActorID,
+					Action:     logs[30].Action,
+					TargetType: logs[30].TargetType,
+					TargetID:   logs[30].TargetID,
+					Reason:     logs[30].Reason,
+					Before:     logs[30].Before,
+					After:      logs[30].After,
+					RequestID:  logs[30].RequestID,
+					ClientIp:   logs[30].ClientIp,
+					PrevHash:   logs[30].PrevHash,
+					CreatedAt:  logs[30].CreatedAt,
+				})
+				return logs
+			},
+			brokenAt: 32,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			chain := newChain(t)
+			chain.logs = tc.tamper(chain.logs)
+
+			result, err := VerifyAuditLog(context.Background(), chain)
+			require.NoError(t, err)
+			require.Equal(t, tc.brokenAt, result.BrokenAt)
+			require.Equal(t, tc.brokenAt == 0, result.Valid())
+		})
+	}
+
+	t.Run("UnchainedRows", func(t *testing.T) {
+		//Rows from before the chain existed have no hash and are only counted
+		chain := &memoryAuditLog{logs: []AuditLog{{ID: 1}, {ID: 2}}}
+		arg, err := NewAuditLogParams(AuditActor{}, AuditUserLoginFailed, AuditTargetEmail, "someone@example.com", "", nil, nil)
+		require.NoError(t, err)
+		chain.append(t, arg)
+
+		result, err := VerifyAuditLog(context.Background(), chain)
+		require.NoError(t, err)
+		require.True(t, result.Valid())
+		require.Equal(t, int64(2), result.Unchained)
+		require.Equal(t, int64(1), result.Checked)
+	})
+}
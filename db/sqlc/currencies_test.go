@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCurrencies(t *testing.T) {
+	currencies, err := testQueries.ListCurrencies(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, currencies)
+
+	for i, currency := range currencies {
+		require.Len(t, currency.Code, 3)
+		require.Len(t, currency.NumericCode, 3)
+		if i > 0 {
+			require.Less(t, currencies[i-1].Code, currency.Code)
+		}
+	}
+}
+
+func TestGetCurrency(t *testing.T) {
+	currency, err := testQueries.GetCurrency(context.Background(), "JPY")
+	require.NoError(t, err)
+	require.Equal(t, "392", currency.NumericCode)
+	require.Equal(t, int32(0), currency.Exponent)
+
+	_, err = testQueries.GetCurrency(context.Background(), "YEN")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSetCurrencyEnabled(t *testing.T) {
+	currency, err := testQueries.GetCurrency(context.Background(), "CHF")
+	require.NoError(t, err)
+
+	updated, err := testQueries.SetCurrencyEnabled(context.Background(), SetCurrencyEnabledParams{
+		Code:      currency.Code,
+		Enabled:   !currency.Enabled,
+		UpdatedAt: time.Now().Unix(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, !currency.Enabled, updated.Enabled)
+	require.NotZero(t, updated.UpdatedAt)
+
+	_, err = testQueries.SetCurrencyEnabled(context.Background(), SetCurrencyEnabledParams{
+		Code:      currency.Code,
+		Enabled:   currency.Enabled,
+		UpdatedAt: time.Now().Unix(),
+	})
+	require.NoError(t, err)
+}
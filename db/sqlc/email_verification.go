@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+//Returned by VerifyEmailTx when the token is unknown, used, expired or was sent to an address
+//the user has since changed
+var ErrInvalidVerificationToken = errors.New("Email verification token is invalid or has expired")
+
+//Input for VerifyEmailTx. TokenHash is the hash of the token the user was mailed
+type VerifyEmailTxParams struct {
+	TokenHash string     `json:"token_hash"`
+	Actor     AuditActor `json:"actor"`
+}
+
+//Spends a verification token and marks the address it was sent to as verified
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		now := time.Now().Unix()
+		verification, err := q.UseEmailVerification(ctx, UseEmailVerificationParams{
+			UsedAt:    now,
+			TokenHash: arg.TokenHash,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidVerificationToken
+			}
+			return err
+		}
+		user, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			ID:    verification.UserID,
+			Email: verification.Email,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidVerificationToken
+			}
+			return err
+		}
+		err = q.RevokeEmailVerifications(ctx, RevokeEmailVerificationsParams{
+			UsedAt: now,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		actor := arg.Actor
+		actor.ActorID = user.ID
+		audit, err := NewAuditLogParams(actor, AuditUserEmailVerify, AuditTargetUser, strconv.FormatInt(user.ID, 10), "", nil, map[string]string{"email": user.Email})
+		if err != nil {
+			return err
+		}
+		_, err = appendAuditLog(ctx, q, audit)
+		return err
+	})
+
+	return user, err
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestEmailVerification(t *testing.T, user User, expiresAt time.Time) string {
+	token := util.GenerateString(32)
+	verification, err := testQueries.CreateEmailVerification(context.Background(), CreateEmailVerificationParams{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: util.HashSecretToken(token),
+		ExpiresAt: expiresAt.Unix(),
+		CreatedAt: time.Now().Unix(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Email, verification.Email)
+	require.Zero(t, verification.UsedAt)
+	return token
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createTestUser(t)
+	token := createTestEmailVerification(t, user, time.Now().Add(time.Hour))
+	otherToken := createTestEmailVerification(t, user, time.Now().Add(time.Hour))
+
+	arg := VerifyEmailTxParams{
+		TokenHash: util.HashSecretToken(token),
+		Actor:     AuditActor{RequestID: "verify-email"},
+	}
+	verified, err := store.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, verified.ID)
+	require.True(t, verified.EmailVerified)
+
+	//Tokens are single use and verifying spends the others too
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidVerificationToken)
+	arg.TokenHash = util.HashSecretToken(otherToken)
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidVerificationToken)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := createTestUser(t)
+	token := createTestEmailVerification(t, user, time.Now().Add(-time.Minute))
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		TokenHash: util.HashSecretToken(token),
+	})
+	require.ErrorIs(t, err, ErrInvalidVerificationToken)
+
+	unverified, err := store.GetUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.False(t, unverified.EmailVerified)
+}
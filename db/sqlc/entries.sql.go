@@ -52,16 +52,57 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at from entries order by id limit $1 offset $2
+SELECT id, account_id, amount, created_at from entries
+where account_id = $1
+  and created_at >= $2 and created_at < $3
+order by id
 `
 
 type ListEntriesParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	AccountID int64 `json:"account_id"`
+	FromTime  int64 `json:"from_time"`
+	ToTime    int64 `json:"to_time"`
 }
 
 func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
-	rows, err := q.db.QueryContext(ctx, listEntries, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listEntries, arg.AccountID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrphanEntries = `-- name: ListOrphanEntries :many
+SELECT e.id, e.account_id, e.amount, e.created_at from entries e
+where not (exists (select 1 from transactions t where t.from_entry_id = e.id or t.to_entry_id = e.id)
+    or exists (select 1 from audit_log l where l.action = 'account.adjustment' and (l."after"->'entry'->>'id')::bigint = e.id))
+order by e.id
+`
+
+// Entries that belong to no transaction and weren't posted by an admin adjustment. Older
+// transfers wrote a second pair of entries that never moved the balance
+func (q *Queries) ListOrphanEntries(ctx context.Context) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listOrphanEntries)
 	if err != nil {
 		return nil, err
 	}
@@ -87,3 +128,75 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
+
+const listRecentEntries = `-- name: ListRecentEntries :many
+SELECT id, account_id, amount, created_at from entries where account_id = $1 order by id desc limit $2 offset $3
+`
+
+type ListRecentEntriesParams struct {
+	AccountID int64 `json:"account_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
+}
+
+func (q *Queries) ListRecentEntries(ctx context.Context, arg ListRecentEntriesParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listRecentEntries, arg.AccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sumEntries = `-- name: SumEntries :one
+SELECT COALESCE(SUM(amount), 0)::bigint as total from entries
+where account_id = $1
+  and created_at >= $2 and created_at < $3
+`
+
+type SumEntriesParams struct {
+	AccountID int64 `json:"account_id"`
+	FromTime  int64 `json:"from_time"`
+	ToTime    int64 `json:"to_time"`
+}
+
+func (q *Queries) SumEntries(ctx context.Context, arg SumEntriesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumEntries, arg.AccountID, arg.FromTime, arg.ToTime)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const sumLedgerEntries = `-- name: SumLedgerEntries :one
+SELECT COALESCE(SUM(e.amount), 0)::bigint as total from entries e
+where e.account_id = $1
+  and (exists (select 1 from transactions t where t.from_entry_id = e.id or t.to_entry_id = e.id)
+    or exists (select 1 from audit_log l where l.action = 'account.adjustment' and (l."after"->'entry'->>'id')::bigint = e.id))
+`
+
+// Total of the account's entries without the orphan ones
+func (q *Queries) SumLedgerEntries(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumLedgerEntries, accountID)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
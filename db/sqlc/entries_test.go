@@ -39,20 +39,56 @@ func TestGetEntry(t *testing.T) {
 }
 
 func TestListEntries(t *testing.T) {
-	for i := 0; i < 5; i++ {
-		createTestEntry(t, util.GenerateAmount())
+	entry, account := createTestEntry(t, util.GenerateAmount())
+	for i := 0; i < 4; i++ {
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    util.GenerateAmount(),
+			CreatedAt: entry.CreatedAt,
+		})
+		require.NoError(t, err)
 	}
+	createTestEntry(t, util.GenerateAmount())
 
 	args := ListEntriesParams{
-		Limit:  100,
-		Offset: 0,
+		AccountID: account.ID,
+		FromTime:  entry.CreatedAt,
+		ToTime:    entry.CreatedAt + 1,
 	}
 
 	list, err := testQueries.ListEntries(context.Background(), args)
 
 	require.NoError(t, err)
+	require.Len(t, list, 5)
 
-	for _, entry := range list[len(list)-5:] {
+	for _, entry := range list {
 		require.NotEmpty(t, entry)
+		require.Equal(t, account.ID, entry.AccountID)
 	}
+
+	args.FromTime = entry.CreatedAt + 1
+	args.ToTime = entry.CreatedAt + 2
+	list, err = testQueries.ListEntries(context.Background(), args)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func TestSumEntries(t *testing.T) {
+	entry, account := createTestEntry(t, util.GenerateAmount())
+
+	total, err := testQueries.SumEntries(context.Background(), SumEntriesParams{
+		AccountID: account.ID,
+		FromTime:  0,
+		ToTime:    entry.CreatedAt + 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, entry.Amount, total)
+
+	total, err = testQueries.SumEntries(context.Background(), SumEntriesParams{
+		AccountID: account.ID,
+		FromTime:  0,
+		ToTime:    entry.CreatedAt,
+	})
+	require.NoError(t, err)
+	require.Zero(t, total)
 }
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestFxQuote(t *testing.T, expiresAt int64) FxQuote {
+	user := createTestUser(t)
+	arg := CreateFxQuoteParams{
+		ID:           uuid.New(),
+		UserID:       user.ID,
+		FromCurrency: "USD",
+		ToCurrency:   "EUR",
+		Rate:         "0.92",
+		Amount:       100,
+		ToAmount:     92,
+		ExpiresAt:    expiresAt,
+		CreatedAt:    time.Now().Unix(),
+	}
+	quote, err := testQueries.CreateFxQuote(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, quote.ID)
+	require.Equal(t, arg.UserID, quote.UserID)
+	require.Equal(t, arg.ToAmount, quote.ToAmount)
+	require.Equal(t, arg.ExpiresAt, quote.ExpiresAt)
+	require.Zero(t, quote.UsedAt)
+	return quote
+}
+
+func TestCreateFxQuote(t *testing.T) {
+	createTestFxQuote(t, time.Now().Add(time.Minute).Unix())
+}
+
+func TestGetFxQuote(t *testing.T) {
+	quote := createTestFxQuote(t, time.Now().Add(time.Minute).Unix())
+	checkQuote, err := testQueries.GetFxQuote(context.Background(), quote.ID)
+	require.NoError(t, err)
+	require.Equal(t, quote, checkQuote)
+}
+
+func TestUseFxQuote(t *testing.T) {
+	quote := createTestFxQuote(t, time.Now().Add(time.Minute).Unix())
+	now := time.Now().Unix()
+
+	used, err := testQueries.UseFxQuote(context.Background(), UseFxQuoteParams{
+		UsedAt: now,
+		ID:     quote.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, now, used.UsedAt)
+
+	//A quote can only be used once
+	_, err = testQueries.UseFxQuote(context.Background(), UseFxQuoteParams{
+		UsedAt: now,
+		ID:     quote.ID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseExpiredFxQuote(t *testing.T) {
+	quote := createTestFxQuote(t, time.Now().Add(-time.Minute).Unix())
+	_, err := testQueries.UseFxQuote(context.Background(), UseFxQuoteParams{
+		UsedAt: time.Now().Unix(),
+		ID:     quote.ID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestIdempotencyKey(t *testing.T) IdempotencyKey {
+	user := createTestUser(t)
+	arg := CreateIdempotencyKeyParams{
+		Key:         util.GenerateString(16),
+		UserID:      user.ID,
+		RequestPath: "/transfers",
+		RequestHash: util.GenerateString(64),
+		CreatedAt:   time.Now().Unix(),
+	}
+	key, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Key, key.Key)
+	require.Equal(t, arg.UserID, key.UserID)
+	require.Equal(t, arg.RequestHash, key.RequestHash)
+	require.Zero(t, key.ResponseCode)
+	require.Empty(t, key.ResponseBody)
+	return key
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	key := createTestIdempotencyKey(t)
+
+	//A second insert with the same key must not overwrite the first one
+	_, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Key:         key.Key,
+		UserID:      key.UserID,
+		RequestPath: key.RequestPath,
+		RequestHash: util.GenerateString(64),
+		CreatedAt:   time.Now().Unix(),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestUpdateIdempotencyKeyResponse(t *testing.T) {
+	key := createTestIdempotencyKey(t)
+	body := []byte(`{"code":200}`)
+
+	err := testQueries.UpdateIdempotencyKeyResponse(context.Background(), UpdateIdempotencyKeyResponseParams{
+		ResponseCode: 201,
+		ResponseBody: body,
+		UserID:       key.UserID,
+		Key:          key.Key,
+	})
+	require.NoError(t, err)
+
+	saved, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		UserID: key.UserID,
+		Key:    key.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(201), saved.ResponseCode)
+	require.Equal(t, body, saved.ResponseBody)
+}
+
+func TestDeleteIdempotencyKey(t *testing.T) {
+	key := createTestIdempotencyKey(t)
+	err := testQueries.DeleteIdempotencyKey(context.Background(), DeleteIdempotencyKeyParams{
+		UserID: key.UserID,
+		Key:    key.Key,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		UserID: key.UserID,
+		Key:    key.Key,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
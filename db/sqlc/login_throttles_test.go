@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	accountKey := "email:" + util.GenerateString(10)
+	ipKey := "ip:" + util.GenerateString(10)
+	now := time.Now()
+	failure := RecordLoginFailureParams{
+		Key:          accountKey,
+		Now:          now.Unix(),
+		ForgetBefore: now.Add(-time.Hour).Unix(),
+	}
+
+	for i := 1; i <= 3; i++ {
+		throttle, err := testQueries.RecordLoginFailure(context.Background(), failure)
+		require.NoError(t, err)
+		require.Equal(t, int32(i), throttle.Failures)
+	}
+
+	lockedUntil, err := testQueries.GetLoginLock(context.Background(), GetLoginLockParams{AccountKey: accountKey, IpKey: ipKey})
+	require.NoError(t, err)
+	require.Zero(t, lockedUntil)
+
+	//A shorter lock never cuts an existing one short
+	until := now.Add(time.Minute).Unix()
+	require.NoError(t, testQueries.LockLogin(context.Background(), LockLoginParams{LockedUntil: until, Key: accountKey}))
+	require.NoError(t, testQueries.LockLogin(context.Background(), LockLoginParams{LockedUntil: now.Unix(), Key: accountKey}))
+	lockedUntil, err = testQueries.GetLoginLock(context.Background(), GetLoginLockParams{AccountKey: accountKey, IpKey: ipKey})
+	require.NoError(t, err)
+	require.Equal(t, until, lockedUntil)
+
+	//Failures older than the window are forgotten
+	failure.ForgetBefore = now.Unix() + 1
+	throttle, err := testQueries.RecordLoginFailure(context.Background(), failure)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), throttle.Failures)
+
+	require.NoError(t, testQueries.ResetLoginThrottle(context.Background(), accountKey))
+	lockedUntil, err = testQueries.GetLoginLock(context.Background(), GetLoginLockParams{AccountKey: accountKey, IpKey: ipKey})
+	require.NoError(t, err)
+	require.Zero(t, lockedUntil)
+}
@@ -5,6 +5,8 @@
 package db
 
 import (
+	"encoding/json"
+
 	"github.com/google/uuid"
 )
 
@@ -15,6 +17,44 @@ type Account struct {
 	Currency  string `json:"currency"`
 	CreatedAt int64  `json:"created_at"`
 	UserID    int64  `json:"user_id"`
+	Status    string `json:"status"`
+	Number    string `json:"number"`
+}
+
+type AuditLog struct {
+	ID         int64           `json:"id"`
+	ActorID    int64           `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Reason     string          `json:"reason"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  int64           `json:"created_at"`
+	RequestID  string          `json:"request_id"`
+	ClientIp   string          `json:"client_ip"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+type Currency struct {
+	Code        string `json:"code"`
+	NumericCode string `json:"numeric_code"`
+	Exponent    int32  `json:"exponent"`
+	Symbol      string `json:"symbol"`
+	Name        string `json:"name"`
+	Enabled     bool   `json:"enabled"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
+
+type EmailVerification struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	Email     string `json:"email"`
+	TokenHash string `json:"token_hash"`
+	ExpiresAt int64  `json:"expires_at"`
+	UsedAt    int64  `json:"used_at"`
+	CreatedAt int64  `json:"created_at"`
 }
 
 type Entry struct {
@@ -24,6 +64,92 @@ type Entry struct {
 	CreatedAt int64 `json:"created_at"`
 }
 
+type FxQuote struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       int64     `json:"user_id"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         string    `json:"rate"`
+	Amount       int64     `json:"amount"`
+	ToAmount     int64     `json:"to_amount"`
+	ExpiresAt    int64     `json:"expires_at"`
+	UsedAt       int64     `json:"used_at"`
+	CreatedAt    int64     `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	Key          string `json:"key"`
+	UserID       int64  `json:"user_id"`
+	RequestPath  string `json:"request_path"`
+	RequestHash  string `json:"request_hash"`
+	ResponseCode int32  `json:"response_code"`
+	ResponseBody []byte `json:"response_body"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+type LoginChallenge struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	TokenHash string `json:"token_hash"`
+	Scopes    string `json:"scopes"`
+	Attempts  int32  `json:"attempts"`
+	ExpiresAt int64  `json:"expires_at"`
+	UsedAt    int64  `json:"used_at"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Key           string `json:"key"`
+	Failures      int32  `json:"failures"`
+	LockedUntil   int64  `json:"locked_until"`
+	LastFailureAt int64  `json:"last_failure_at"`
+}
+
+type PasswordResetToken struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	TokenHash string `json:"token_hash"`
+	ExpiresAt int64  `json:"expires_at"`
+	UsedAt    int64  `json:"used_at"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	CodeHash  string `json:"code_hash"`
+	UsedAt    int64  `json:"used_at"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	UserID        int64  `json:"user_id"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Recurrence    string `json:"recurrence"`
+	ScheduledAt   int64  `json:"scheduled_at"`
+	NextAttemptAt int64  `json:"next_attempt_at"`
+	EndAt         int64  `json:"end_at"`
+	Attempts      int32  `json:"attempts"`
+	Status        string `json:"status"`
+	LockedUntil   int64  `json:"locked_until"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64  `json:"id"`
+	ScheduledTransferID int64  `json:"scheduled_transfer_id"`
+	TransactionID       int64  `json:"transaction_id"`
+	Status              string `json:"status"`
+	Attempt             int32  `json:"attempt"`
+	Error               string `json:"error"`
+	ScheduledAt         int64  `json:"scheduled_at"`
+	CreatedAt           int64  `json:"created_at"`
+}
+
 type Session struct {
 	ID           uuid.UUID `json:"id"`
 	UserID       int64     `json:"user_id"`
@@ -33,16 +159,40 @@ type Session struct {
 	IsBlocked    bool      `json:"is_blocked"`
 	ExpiresAt    int64     `json:"expires_at"`
 	CreatedAt    int64     `json:"created_at"`
+	FamilyID     uuid.UUID `json:"family_id"`
+	ConsumedAt   int64     `json:"consumed_at"`
+}
+
+type SigningKey struct {
+	ID        string `json:"id"`
+	TokenType string `json:"token_type"`
+	Secret    string `json:"secret"`
+	NotBefore int64  `json:"not_before"`
+	RetiredAt int64  `json:"retired_at"`
+	CreatedAt int64  `json:"created_at"`
+	PublicKey string `json:"public_key"`
+}
+
+type TotpCredential struct {
+	UserID       int64  `json:"user_id"`
+	Secret       string `json:"secret"`
+	ConfirmedAt  int64  `json:"confirmed_at"`
+	LastUsedStep int64  `json:"last_used_step"`
+	CreatedAt    int64  `json:"created_at"`
 }
 
 type Transaction struct {
-	ID            int64 `json:"id"`
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	FromEntryID   int64 `json:"from_entry_id"`
-	ToEntryID     int64 `json:"to_entry_id"`
-	Amount        int64 `json:"amount"`
-	CreatedAt     int64 `json:"created_at"`
+	ID            int64  `json:"id"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	FromEntryID   int64  `json:"from_entry_id"`
+	ToEntryID     int64  `json:"to_entry_id"`
+	Amount        int64  `json:"amount"`
+	CreatedAt     int64  `json:"created_at"`
+	ToAmount      int64  `json:"to_amount"`
+	Rate          string `json:"rate"`
+	FromCurrency  string `json:"from_currency"`
+	ToCurrency    string `json:"to_currency"`
 }
 
 type User struct {
@@ -52,4 +202,7 @@ type User struct {
 	Email             string `json:"email"`
 	PasswordChangedAt int64  `json:"password_changed_at"`
 	CreatedAt         int64  `json:"created_at"`
+	Role              string `json:"role"`
+	EmailVerified     bool   `json:"email_verified"`
+	TwoFactorEnabled  bool   `json:"two_factor_enabled"`
 }
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//Returned by ResetPasswordTx when the token is unknown, was already used or has expired
+var ErrInvalidResetToken = errors.New("Password reset token is invalid or has expired")
+
+//Input for ChangePasswordTx. Password is already hashed, SessionID is the session the user
+//changed it from, which stays logged in
+type ChangePasswordTxParams struct {
+	UserID    int64      `json:"user_id"`
+	Password  string     `json:"password"`
+	SessionID uuid.UUID  `json:"session_id"`
+	Actor     AuditActor `json:"actor"`
+}
+
+//Sets a new password and logs every other session out
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = setPassword(ctx, q, arg.UserID, arg.Password)
+		if err != nil {
+			return err
+		}
+		_, err = q.BlockOtherSessions(ctx, BlockOtherSessionsParams{
+			UserID:   arg.UserID,
+			FamilyID: arg.SessionID,
+		})
+		if err != nil {
+			return err
+		}
+		return appendUserAudit(ctx, q, arg.Actor, AuditUserPasswordChange, user.ID)
+	})
+
+	return user, err
+}
+
+//Input for ResetPasswordTx. TokenHash is the hash of the token the user was mailed
+type ResetPasswordTxParams struct {
+	TokenHash string     `json:"token_hash"`
+	Password  string     `json:"password"`
+	Actor     AuditActor `json:"actor"`
+}
+
+//Spends a reset token and sets the new password. Whoever asked for the reset may not be the
+//one holding the old sessions, so every session is logged out
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		token, err := q.UsePasswordResetToken(ctx, UsePasswordResetTokenParams{
+			UsedAt:    time.Now().Unix(),
+			TokenHash: arg.TokenHash,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		user, err = setPassword(ctx, q, token.UserID, arg.Password)
+		if err != nil {
+			return err
+		}
+		err = q.UpdateSession(ctx, UpdateSessionParams{
+			IsBlocked: true,
+			UserID:    user.ID,
+		})
+		if err != nil {
+			return err
+		}
+		actor := arg.Actor
+		actor.ActorID = user.ID
+		return appendUserAudit(ctx, q, actor, AuditUserPasswordReset, user.ID)
+	})
+
+	return user, err
+}
+
+//Sets the password and spends the reset tokens still outstanding, a link mailed
+//before the change must not be able to undo it
+func setPassword(ctx context.Context, q *Queries, userID int64, password string) (User, error) {
+	now := time.Now()
+	//Milliseconds, a token issued later in the same second must stay valid
+	user, err := q.UpdatePassword(ctx, UpdatePasswordParams{
+		Password:          password,
+		Passwordchangedat: now.UnixMilli(),
+		ID:                userID,
+	})
+	if err != nil {
+		return User{}, err
+	}
+	err = q.RevokePasswordResetTokens(ctx, RevokePasswordResetTokensParams{
+		UsedAt: now.Unix(),
+		UserID: userID,
+	})
+	return user, err
+}
+
+//Records a change to the user's credentials. Secrets never go into the audit log, the row only records that it happened
+func appendUserAudit(ctx context.Context, q *Queries, actor AuditActor, action string, userID int64) error {
+	audit, err := NewAuditLogParams(actor, action, AuditTargetUser, strconv.FormatInt(userID, 10), "", nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = appendAuditLog(ctx, q, audit)
+	return err
+}
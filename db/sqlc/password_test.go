@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestUserSession(t *testing.T, userID int64) Session {
+	id := uuid.New()
+	session, err := testQueries.CreateSession(context.Background(), CreateSessionParams{
+		ID:           id,
+		UserID:       userID,
+		FamilyID:     id,
+		RefreshToken: util.GenerateString(32),
+		UserAgent:    util.GenerateString(8),
+		ClientIp:     "127.0.0.1",
+		ExpiresAt:    time.Now().Add(time.Hour).Unix(),
+		CreatedAt:    time.Now().Unix(),
+	})
+	require.NoError(t, err)
+	return session
+}
+
+func createTestResetToken(t *testing.T, userID int64, expiresAt time.Time) (string, PasswordResetToken) {
+	token := util.GenerateString(32)
+	resetToken, err := testQueries.CreatePasswordResetToken(context.Background(), CreatePasswordResetTokenParams{
+		UserID:    userID,
+		TokenHash: util.HashSecretToken(token),
+		ExpiresAt: expiresAt.Unix(),
+		CreatedAt: time.Now().Unix(),
+	})
+	require.NoError(t, err)
+	require.Zero(t, resetToken.UsedAt)
+	return token, resetToken
+}
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createTestUser(t)
+	current := createTestUserSession(t, user.ID)
+	other := createTestUserSession(t, user.ID)
+	token, _ := createTestResetToken(t, user.ID, time.Now().Add(time.Hour))
+
+	updated, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		UserID:    user.ID,
+		Password:  "new-hash",
+		SessionID: current.FamilyID,
+		Actor:     AuditActor{ActorID: user.ID, RequestID: "change-password"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new-hash", updated.Password)
+	require.GreaterOrEqual(t, updated.PasswordChangedAt, user.PasswordChangedAt)
+
+	kept, err := store.GetSession(context.Background(), current.ID)
+	require.NoError(t, err)
+	require.False(t, kept.IsBlocked)
+	blocked, err := store.GetSession(context.Background(), other.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	//A reset token mailed before the change can't be used after it
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash: util.HashSecretToken(token),
+		Password:  "reset-hash",
+	})
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createTestUser(t)
+	session := createTestUserSession(t, user.ID)
+	token, _ := createTestResetToken(t, user.ID, time.Now().Add(time.Hour))
+	otherToken, _ := createTestResetToken(t, user.ID, time.Now().Add(time.Hour))
+
+	arg := ResetPasswordTxParams{
+		TokenHash: util.HashSecretToken(token),
+		Password:  "reset-hash",
+		Actor:     AuditActor{RequestID: "reset-password"},
+	}
+	updated, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, updated.ID)
+	require.Equal(t, "reset-hash", updated.Password)
+
+	blocked, err := store.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	//Tokens are single use and the reset spends the others too
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+	arg.TokenHash = util.HashSecretToken(otherToken)
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := createTestUser(t)
+	token, _ := createTestResetToken(t, user.ID, time.Now().Add(-time.Minute))
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash: util.HashSecretToken(token),
+		Password:  "reset-hash",
+	})
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+
+	unchanged, err := store.GetUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, user.Password, unchanged.Password)
+}
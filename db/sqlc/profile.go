@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+//Input for UpdateProfileTx, both fields are set to the given values
+type UpdateProfileTxParams struct {
+	UserID int64      `json:"user_id"`
+	Name   string     `json:"name"`
+	Email  string     `json:"email"`
+	Actor  AuditActor `json:"actor"`
+}
+
+//The parts of a user a profile change is audited with, the password hash stays out of the log
+type profileSnapshot struct {
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+func newProfileSnapshot(user User) profileSnapshot {
+	return profileSnapshot{
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+	}
+}
+
+//Updates the user's name and email. A new email is unverified and verification tokens
+//sent to the old one are spent. Returns the unique violation when the email is taken
+func (store *SQLStore) UpdateProfileTx(ctx context.Context, arg UpdateProfileTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetUser(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+		user, err = q.UpdateUserProfile(ctx, UpdateUserProfileParams{
+			Name:  arg.Name,
+			Email: arg.Email,
+			ID:    arg.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		if user.Email != before.Email {
+			err = q.RevokeEmailVerifications(ctx, RevokeEmailVerificationsParams{
+				UsedAt: time.Now().Unix(),
+				UserID: user.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		audit, err := NewAuditLogParams(arg.Actor, AuditUserProfileUpdate, AuditTargetUser, strconv.FormatInt(user.ID, 10), "", newProfileSnapshot(before), newProfileSnapshot(user))
+		if err != nil {
+			return err
+		}
+		_, err = appendAuditLog(ctx, q, audit)
+		return err
+	})
+
+	return user, err
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateProfileTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createTestUser(t)
+	token := createTestEmailVerification(t, user, time.Now().Add(time.Hour))
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{TokenHash: util.HashSecretToken(token)})
+	require.NoError(t, err)
+
+	//Only the name changes, so the email stays verified
+	arg := UpdateProfileTxParams{
+		UserID: user.ID,
+		Name:   util.GenerateString(8),
+		Email:  user.Email,
+		Actor:  AuditActor{ActorID: user.ID, RequestID: "update-profile"},
+	}
+	updated, err := store.UpdateProfileTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Name, updated.Name)
+	require.True(t, updated.EmailVerified)
+
+	//A new email has to be verified again and older tokens stop working
+	pending := createTestEmailVerification(t, updated, time.Now().Add(time.Hour))
+	arg.Email = util.RandomEmail()
+	updated, err = store.UpdateProfileTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Email, updated.Email)
+	require.False(t, updated.EmailVerified)
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{TokenHash: util.HashSecretToken(pending)})
+	require.ErrorIs(t, err, ErrInvalidVerificationToken)
+
+	//Emails stay unique
+	other := createTestUser(t)
+	arg.Email = other.Email
+	_, err = store.UpdateProfileTx(context.Background(), arg)
+	pqErr, ok := err.(*pq.Error)
+	require.True(t, ok)
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+}
@@ -11,27 +11,100 @@ import (
 )
 
 type Querier interface {
+	AttemptLoginChallenge(ctx context.Context, arg AttemptLoginChallengeParams) (LoginChallenge, error)
+	BlockOtherSessions(ctx context.Context, arg BlockOtherSessionsParams) (int64, error)
+	BlockSessionFamily(ctx context.Context, arg BlockSessionFamilyParams) (int64, error)
+	CancelScheduledTransfer(ctx context.Context, arg CancelScheduledTransferParams) (ScheduledTransfer, error)
+	ClaimDueScheduledTransfers(ctx context.Context, arg ClaimDueScheduledTransfersParams) ([]ScheduledTransfer, error)
+	ConfirmTotpCredential(ctx context.Context, arg ConfirmTotpCredentialParams) (TotpCredential, error)
+	ConsumeSession(ctx context.Context, arg ConsumeSessionParams) (Session, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	CreateEmailVerification(ctx context.Context, arg CreateEmailVerificationParams) (EmailVerification, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFxQuote(ctx context.Context, arg CreateFxQuoteParams) (FxQuote, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) (LoginChallenge, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateSigningKey(ctx context.Context, arg CreateSigningKeyParams) (SigningKey, error)
 	CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, createdAt int64) (int64, error)
+	DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error
+	DeleteRecoveryCodes(ctx context.Context, userID int64) error
+	DeleteTotpCredential(ctx context.Context, userID int64) error
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountByNumber(ctx context.Context, number string) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetCurrency(ctx context.Context, code string) (Currency, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetFxQuote(ctx context.Context, id uuid.UUID) (FxQuote, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetLastAuditLog(ctx context.Context) (AuditLog, error)
+	GetLoginLock(ctx context.Context, arg GetLoginLockParams) (int64, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetScheduledTransferForUpdate(ctx context.Context, id int64) (ScheduledTransfer, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetTokenRevocation(ctx context.Context, arg GetTokenRevocationParams) (GetTokenRevocationRow, error)
+	GetTotpCredential(ctx context.Context, userID int64) (TotpCredential, error)
 	GetTransaction(ctx context.Context, id int64) (Transaction, error)
+	GetTransactionForUser(ctx context.Context, arg GetTransactionForUserParams) (Transaction, error)
 	GetUser(ctx context.Context, id int64) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
+	ListAccountTransactions(ctx context.Context, arg ListAccountTransactionsParams) ([]Transaction, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
 	ListAccountsForUser(ctx context.Context, arg ListAccountsForUserParams) ([]Account, error)
+	ListActiveSessions(ctx context.Context, arg ListActiveSessionsParams) ([]Session, error)
+	ListActiveSigningKeys(ctx context.Context, tokenType string) ([]SigningKey, error)
+	ListAuditLog(ctx context.Context, arg ListAuditLogParams) ([]AuditLog, error)
+	ListAuditLogAfter(ctx context.Context, arg ListAuditLogAfterParams) ([]AuditLog, error)
+	ListBalanceDiscrepancies(ctx context.Context) ([]ListBalanceDiscrepanciesRow, error)
+	ListCurrencies(ctx context.Context) ([]Currency, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListOrphanEntries(ctx context.Context) ([]Entry, error)
+	ListRecentEntries(ctx context.Context, arg ListRecentEntriesParams) ([]Entry, error)
+	ListScheduledTransferRuns(ctx context.Context, scheduledTransferID int64) ([]ScheduledTransferRun, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
+	ListSigningKeys(ctx context.Context, tokenType string) ([]SigningKey, error)
 	ListTransactions(ctx context.Context, arg ListTransactionsParams) ([]Transaction, error)
+	ListTransactionsByEntries(ctx context.Context, entryIds []int64) ([]Transaction, error)
+	ListUnbalancedTransactions(ctx context.Context) ([]Transaction, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	LockAuditLog(ctx context.Context) error
+	LockLogin(ctx context.Context, arg LockLoginParams) error
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	ResetLoginThrottle(ctx context.Context, key string) error
+	RetireSigningKey(ctx context.Context, arg RetireSigningKeyParams) (SigningKey, error)
+	RevokeEmailVerifications(ctx context.Context, arg RevokeEmailVerificationsParams) error
+	RevokePasswordResetTokens(ctx context.Context, arg RevokePasswordResetTokensParams) error
+	SearchUsersByEmail(ctx context.Context, arg SearchUsersByEmailParams) ([]User, error)
+	SetAccountStatus(ctx context.Context, arg SetAccountStatusParams) (Account, error)
+	SetBalance(ctx context.Context, arg SetBalanceParams) (Account, error)
+	SetCurrencyEnabled(ctx context.Context, arg SetCurrencyEnabledParams) (Currency, error)
+	SetUserTwoFactor(ctx context.Context, arg SetUserTwoFactorParams) (User, error)
+	SumEntries(ctx context.Context, arg SumEntriesParams) (int64, error)
+	SumLedgerEntries(ctx context.Context, accountID int64) (int64, error)
 	UpdateBalance(ctx context.Context, arg UpdateBalanceParams) (Account, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) error
 	UpdatePassword(ctx context.Context, arg UpdatePasswordParams) (User, error)
+	UpdateScheduledTransferState(ctx context.Context, arg UpdateScheduledTransferStateParams) (ScheduledTransfer, error)
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) error
+	UpdateSigningKeySecret(ctx context.Context, arg UpdateSigningKeySecretParams) (SigningKey, error)
+	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpsertTotpCredential(ctx context.Context, arg UpsertTotpCredentialParams) (TotpCredential, error)
+	UseEmailVerification(ctx context.Context, arg UseEmailVerificationParams) (EmailVerification, error)
+	UseFxQuote(ctx context.Context, arg UseFxQuoteParams) (FxQuote, error)
+	UseLoginChallenge(ctx context.Context, arg UseLoginChallengeParams) (LoginChallenge, error)
+	UsePasswordResetToken(ctx context.Context, arg UsePasswordResetTokenParams) (PasswordResetToken, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseTotpStep(ctx context.Context, arg UseTotpStepParams) (TotpCredential, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+)
+
+//Recomputes the balance of an account from its entries while holding the row lock,
+//so a transfer committed between the check and the repair can't be lost. Orphan entries
+//never moved the balance, so they are left out instead of being applied a second time
+func (store *SQLStore) RepairBalanceTx(ctx context.Context, accountID int64) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		_, err := q.GetAccountForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		total, err := q.SumLedgerEntries(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		account, err = q.SetBalance(ctx, SetBalanceParams{
+			ID:      accountID,
+			Balance: total,
+		})
+		return err
+	})
+
+	return account, err
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	ScheduledTransferActive    = "active"
+	ScheduledTransferCompleted = "completed"
+	ScheduledTransferCancelled = "cancelled"
+	ScheduledTransferFailed    = "failed"
+)
+
+const (
+	ScheduledRunSucceeded = "succeeded"
+	ScheduledRunRetrying  = "retrying"
+	ScheduledRunFailed    = "failed"
+)
+
+//Returned by RunScheduledTransferTx when the transfer was cancelled or claimed by another scheduler
+//after this one took its lease
+var ErrScheduleLeaseLost = errors.New("Scheduled transfer is no longer held by this scheduler")
+
+//Input for running a claimed scheduled transfer. The caller works out the schedule:
+//NextScheduledAt is the following occurrence (0 when there is none) and RetryAt is when
+//to try again if the account can't cover the amount (0 when retries are exhausted)
+type RunScheduledTransferTxParams struct {
+	ID              int64 `json:"id"`
+	LockedUntil     int64 `json:"locked_until"`
+	RunAt           int64 `json:"run_at"`
+	NextScheduledAt int64 `json:"next_scheduled_at"`
+	RetryAt         int64 `json:"retry_at"`
+}
+
+//Result of running a scheduled transfer, Transfer is empty unless the run succeeded
+type RunScheduledTransferTxResult struct {
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+	Transfer          TransferTxResult     `json:"transfer"`
+}
+
+//Executes one occurrence of a scheduled transfer. The transfer, its audit row, the run record and
+//the next occurrence are written in a single transaction so an occurrence can't be paid twice
+func (store *SQLStore) RunScheduledTransferTx(ctx context.Context, arg RunScheduledTransferTxParams) (RunScheduledTransferTxResult, error) {
+	var result RunScheduledTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		scheduled, err := q.GetScheduledTransferForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if scheduled.Status != ScheduledTransferActive || scheduled.LockedUntil != arg.LockedUntil {
+			return ErrScheduleLeaseLost
+		}
+
+		run := CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			Attempt:             scheduled.Attempts + 1,
+			ScheduledAt:         scheduled.ScheduledAt,
+			CreatedAt:           arg.RunAt,
+		}
+		state := UpdateScheduledTransferStateParams{
+			ID:            scheduled.ID,
+			Status:        ScheduledTransferActive,
+			ScheduledAt:   arg.NextScheduledAt,
+			NextAttemptAt: arg.NextScheduledAt,
+		}
+
+		result.Transfer, err = transfer(ctx, q, TransferTxParams{
+			FromAccountID: scheduled.FromAccountID,
+			ToAccountID:   scheduled.ToAccountID,
+			Amount:        scheduled.Amount,
+			CreatedAt:     arg.RunAt,
+		})
+		//A frozen account may be unfrozen before the next attempt, so it is retried like a low balance
+		retryable := errors.Is(err, ErrInsufficientFunds) || errors.Is(err, ErrAccountFrozen)
+		switch {
+		case err == nil:
+			//Recorded against the owner, the scheduler runs the transfer on their behalf
+			err = auditTransfer(ctx, q, AuditActor{ActorID: scheduled.UserID}, "Scheduled transfer", result.Transfer)
+			if err != nil {
+				return err
+			}
+			run.Status = ScheduledRunSucceeded
+			run.TransactionID = result.Transfer.Transaction.ID
+			if arg.NextScheduledAt == 0 {
+				state.Status = ScheduledTransferCompleted
+			}
+		case retryable && arg.RetryAt > 0:
+			run.Status = ScheduledRunRetrying
+			run.Error = err.Error()
+			state.ScheduledAt = scheduled.ScheduledAt
+			state.NextAttemptAt = arg.RetryAt
+			state.Attempts = run.Attempt
+		case retryable:
+			//Out of retries, this occurrence is skipped
+			run.Status = ScheduledRunFailed
+			run.Error = err.Error()
+			if arg.NextScheduledAt == 0 {
+				state.Status = ScheduledTransferFailed
+			}
+		case errors.Is(err, ErrAccountClosed):
+			//Closed accounts stay closed, so later occurrences would fail the same way
+			run.Status = ScheduledRunFailed
+			run.Error = err.Error()
+			state.Status = ScheduledTransferFailed
+		default:
+			return err
+		}
+		if state.Status != ScheduledTransferActive {
+			state.ScheduledAt = scheduled.ScheduledAt
+			state.NextAttemptAt = 0
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, run)
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer, err = q.UpdateScheduledTransferState(ctx, state)
+		return err
+	})
+
+	return result, err
+}
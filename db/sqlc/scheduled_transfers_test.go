@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createTestScheduledTransfer(t *testing.T, amount int64, recurrence string) (ScheduledTransfer, Account, Account) {
+	fromAccount := createTestAccount(t, -1)
+	toAccount := createTestAccount(t, -1)
+	now := time.Now().Unix()
+
+	arg := CreateScheduledTransferParams{
+		UserID:        fromAccount.UserID,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        amount,
+		Currency:      fromAccount.Currency,
+		Recurrence:    recurrence,
+		ScheduledAt:   now - 1,
+		NextAttemptAt: now - 1,
+		CreatedAt:     now,
+	}
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, scheduled.ID)
+	require.Equal(t, arg.Amount, scheduled.Amount)
+	require.Equal(t, arg.Recurrence, scheduled.Recurrence)
+	require.Equal(t, ScheduledTransferActive, scheduled.Status)
+	require.Zero(t, scheduled.Attempts)
+	require.Zero(t, scheduled.LockedUntil)
+	return scheduled, fromAccount, toAccount
+}
+
+//Claims the given scheduled transfer the way the scheduler does
+func claimTestScheduledTransfer(t *testing.T, scheduled ScheduledTransfer) int64 {
+	now := time.Now().Unix()
+	lockedUntil := now + 60
+	claimed, err := testQueries.ClaimDueScheduledTransfers(context.Background(), ClaimDueScheduledTransfersParams{
+		LockedUntil: lockedUntil,
+		Now:         now,
+		Count:       1000,
+	})
+	require.NoError(t, err)
+
+	found := false
+	for _, item := range claimed {
+		found = found || item.ID == scheduled.ID
+	}
+	require.True(t, found)
+	return lockedUntil
+}
+
+func TestListScheduledTransfers(t *testing.T) {
+	scheduled, _, _ := createTestScheduledTransfer(t, 10, "")
+
+	list, err := testQueries.ListScheduledTransfers(context.Background(), ListScheduledTransfersParams{
+		UserID: scheduled.UserID,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, scheduled, list[0])
+}
+
+func TestClaimDueScheduledTransfers(t *testing.T) {
+	scheduled, _, _ := createTestScheduledTransfer(t, 10, "")
+	claimTestScheduledTransfer(t, scheduled)
+
+	//Leased transfers are not handed out again
+	claimed, err := testQueries.ClaimDueScheduledTransfers(context.Background(), ClaimDueScheduledTransfersParams{
+		LockedUntil: time.Now().Unix() + 60,
+		Now:         time.Now().Unix(),
+		Count:       1000,
+	})
+	require.NoError(t, err)
+	for _, item := range claimed {
+		require.NotEqual(t, scheduled.ID, item.ID)
+	}
+}
+
+func TestCancelScheduledTransfer(t *testing.T) {
+	scheduled, _, _ := createTestScheduledTransfer(t, 10, "")
+
+	cancelled, err := testQueries.CancelScheduledTransfer(context.Background(), CancelScheduledTransferParams{
+		ID:     scheduled.ID,
+		UserID: scheduled.UserID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferCancelled, cancelled.Status)
+
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), CancelScheduledTransferParams{
+		ID:     scheduled.ID,
+		UserID: scheduled.UserID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRunScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+	scheduled, fromAccount, toAccount := createTestScheduledTransfer(t, 10, "FREQ=DAILY")
+	lockedUntil := claimTestScheduledTransfer(t, scheduled)
+	next := scheduled.ScheduledAt + 24*60*60
+
+	result, err := store.RunScheduledTransferTx(context.Background(), RunScheduledTransferTxParams{
+		ID:              scheduled.ID,
+		LockedUntil:     lockedUntil,
+		RunAt:           time.Now().Unix(),
+		NextScheduledAt: next,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledRunSucceeded, result.Run.Status)
+	require.Equal(t, result.Transfer.Transaction.ID, result.Run.TransactionID)
+	require.Equal(t, int32(1), result.Run.Attempt)
+	require.Equal(t, fromAccount.Balance-10, result.Transfer.FromAccount.Balance)
+	require.Equal(t, toAccount.Balance+10, result.Transfer.ToAccount.Balance)
+
+	require.Equal(t, ScheduledTransferActive, result.ScheduledTransfer.Status)
+	require.Equal(t, next, result.ScheduledTransfer.ScheduledAt)
+	require.Equal(t, next, result.ScheduledTransfer.NextAttemptAt)
+	require.Zero(t, result.ScheduledTransfer.LockedUntil)
+
+	//The owner is the actor, the scheduler moved the money for them
+	logs, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		TargetType: AuditTargetTransfer,
+		TargetID:   strconv.FormatInt(result.Transfer.Transaction.ID, 10),
+		Count:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, scheduled.UserID, logs[0].ActorID)
+	require.Equal(t, AuditTransferCreate, logs[0].Action)
+	require.Equal(t, "Scheduled transfer", logs[0].Reason)
+
+	//The lease was released, running it again with the old lease is refused
+	_, err = store.RunScheduledTransferTx(context.Background(), RunScheduledTransferTxParams{
+		ID:          scheduled.ID,
+		LockedUntil: lockedUntil,
+		RunAt:       time.Now().Unix(),
+	})
+	require.ErrorIs(t, err, ErrScheduleLeaseLost)
+}
+
+func TestRunScheduledTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+	scheduled, fromAccount, _ := createTestScheduledTransfer(t, 1000000, "")
+	lockedUntil := claimTestScheduledTransfer(t, scheduled)
+	retryAt := time.Now().Unix() + 3600
+
+	result, err := store.RunScheduledTransferTx(context.Background(), RunScheduledTransferTxParams{
+		ID:          scheduled.ID,
+		LockedUntil: lockedUntil,
+		RunAt:       time.Now().Unix(),
+		RetryAt:     retryAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledRunRetrying, result.Run.Status)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Run.Error)
+	require.Zero(t, result.Run.TransactionID)
+	require.Equal(t, ScheduledTransferActive, result.ScheduledTransfer.Status)
+	require.Equal(t, scheduled.ScheduledAt, result.ScheduledTransfer.ScheduledAt)
+	require.Equal(t, retryAt, result.ScheduledTransfer.NextAttemptAt)
+	require.Equal(t, int32(1), result.ScheduledTransfer.Attempts)
+
+	//Out of retries, a one-off transfer fails for good
+	lockedUntil = claimAfterRetry(t, result.ScheduledTransfer)
+	result, err = store.RunScheduledTransferTx(context.Background(), RunScheduledTransferTxParams{
+		ID:          scheduled.ID,
+		LockedUntil: lockedUntil,
+		RunAt:       time.Now().Unix(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledRunFailed, result.Run.Status)
+	require.Equal(t, int32(2), result.Run.Attempt)
+	require.Equal(t, ScheduledTransferFailed, result.ScheduledTransfer.Status)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance, account.Balance)
+}
+
+//Makes the retry due right away and claims it again
+func claimAfterRetry(t *testing.T, scheduled ScheduledTransfer) int64 {
+	_, err := testQueries.UpdateScheduledTransferState(context.Background(), UpdateScheduledTransferStateParams{
+		ID:            scheduled.ID,
+		Status:        scheduled.Status,
+		ScheduledAt:   scheduled.ScheduledAt,
+		NextAttemptAt: time.Now().Unix() - 1,
+		Attempts:      scheduled.Attempts,
+	})
+	require.NoError(t, err)
+	return claimTestScheduledTransfer(t, scheduled)
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+//Returned by RotateSessionTx when the refresh token was already exchanged or the session was blocked
+var ErrSessionReused = errors.New("Refresh token has already been used")
+
+//Input for rotating a refresh token. Session is the replacement, its user and family
+//are taken from the consumed session
+type RotateSessionTxParams struct {
+	ID         uuid.UUID           `json:"id"`
+	ConsumedAt int64               `json:"consumed_at"`
+	Session    CreateSessionParams `json:"session"`
+}
+
+//Exchanges a refresh token for a new one. The old session is consumed and the new one is
+//created in the same transaction so a refresh token can only be exchanged once
+func (store *SQLStore) RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (Session, error) {
+	var session Session
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		consumed, err := q.ConsumeSession(ctx, ConsumeSessionParams{
+			ConsumedAt: arg.ConsumedAt,
+			ID:         arg.ID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrSessionReused
+			}
+			return err
+		}
+
+		next := arg.Session
+		next.UserID = consumed.UserID
+		next.FamilyID = consumed.FamilyID
+		session, err = q.CreateSession(ctx, next)
+		return err
+	})
+
+	return session, err
+}
@@ -11,17 +11,82 @@ import (
 	"github.com/google/uuid"
 )
 
+const blockOtherSessions = `-- name: BlockOtherSessions :execrows
+UPDATE sessions set is_blocked = true where user_id = $1 and family_id <> $2
+`
+
+type BlockOtherSessionsParams struct {
+	UserID   int64     `json:"user_id"`
+	FamilyID uuid.UUID `json:"family_id"`
+}
+
+// Logs the user out everywhere except the session the request came from
+func (q *Queries) BlockOtherSessions(ctx context.Context, arg BlockOtherSessionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, blockOtherSessions, arg.UserID, arg.FamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const blockSessionFamily = `-- name: BlockSessionFamily :execrows
+UPDATE sessions set is_blocked = true where user_id = $1 and family_id = $2
+`
+
+type BlockSessionFamilyParams struct {
+	UserID   int64     `json:"user_id"`
+	FamilyID uuid.UUID `json:"family_id"`
+}
+
+func (q *Queries) BlockSessionFamily(ctx context.Context, arg BlockSessionFamilyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, blockSessionFamily, arg.UserID, arg.FamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const consumeSession = `-- name: ConsumeSession :one
+UPDATE sessions set consumed_at = $1
+where id = $2 and consumed_at = 0 and is_blocked = false RETURNING id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, family_id, consumed_at
+`
+
+type ConsumeSessionParams struct {
+	ConsumedAt int64     `json:"consumed_at"`
+	ID         uuid.UUID `json:"id"`
+}
+
+// Marks a refresh token as used. Returns no rows when it was already used or blocked
+func (q *Queries) ConsumeSession(ctx context.Context, arg ConsumeSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, consumeSession, arg.ConsumedAt, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RefreshToken,
+		&i.UserAgent,
+		&i.ClientIp,
+		&i.IsBlocked,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ConsumedAt,
+	)
+	return i, err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT into sessions (
-  "id", "user_id", "refresh_token", "user_agent", "client_ip", "expires_at", "created_at"
+  "id", "user_id", "family_id", "refresh_token", "user_agent", "client_ip", "expires_at", "created_at"
 )
 values
-($1, $2, $3, $4, $5, $6, $7) RETURNING id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at
+($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, family_id, consumed_at
 `
 
 type CreateSessionParams struct {
 	ID           uuid.UUID `json:"id"`
 	UserID       int64     `json:"user_id"`
+	FamilyID     uuid.UUID `json:"family_id"`
 	RefreshToken string    `json:"refresh_token"`
 	UserAgent    string    `json:"user_agent"`
 	ClientIp     string    `json:"client_ip"`
@@ -33,6 +98,7 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 	row := q.db.QueryRowContext(ctx, createSession,
 		arg.ID,
 		arg.UserID,
+		arg.FamilyID,
 		arg.RefreshToken,
 		arg.UserAgent,
 		arg.ClientIp,
@@ -49,12 +115,14 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.IsBlocked,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ConsumedAt,
 	)
 	return i, err
 }
 
 const getSession = `-- name: GetSession :one
-SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at from sessions where id = $1 LIMIT 1
+SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, family_id, consumed_at from sessions where id = $1 LIMIT 1
 `
 
 func (q *Queries) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
@@ -69,10 +137,87 @@ func (q *Queries) GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 		&i.IsBlocked,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ConsumedAt,
+	)
+	return i, err
+}
+
+const getTokenRevocation = `-- name: GetTokenRevocation :one
+SELECT u.password_changed_at, EXISTS (
+  SELECT 1 from sessions s where s.family_id = $2 and s.user_id = u.id and s.is_blocked
+) AS session_blocked
+from users u where u.id = $1
+`
+
+type GetTokenRevocationParams struct {
+	ID       int64     `json:"id"`
+	FamilyID uuid.UUID `json:"family_id"`
+}
+
+type GetTokenRevocationRow struct {
+	PasswordChangedAt int64 `json:"password_changed_at"`
+	SessionBlocked    bool  `json:"session_blocked"`
+}
+
+// Returns what decides whether an access token is still valid: when the user last
+// changed their password and whether the session the token belongs to was blocked
+func (q *Queries) GetTokenRevocation(ctx context.Context, arg GetTokenRevocationParams) (GetTokenRevocationRow, error) {
+	row := q.db.QueryRowContext(ctx, getTokenRevocation, arg.ID, arg.FamilyID)
+	var i GetTokenRevocationRow
+	err := row.Scan(
+		&i.PasswordChangedAt,
+		&i.SessionBlocked,
 	)
 	return i, err
 }
 
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, family_id, consumed_at from sessions
+where user_id = $1 and is_blocked = false and consumed_at = 0 and expires_at > $2
+order by created_at desc
+`
+
+type ListActiveSessionsParams struct {
+	UserID    int64 `json:"user_id"`
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// Lists the sessions that can still be refreshed, one per login
+func (q *Queries) ListActiveSessions(ctx context.Context, arg ListActiveSessionsParams) ([]Session, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveSessions, arg.UserID, arg.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.RefreshToken,
+			&i.UserAgent,
+			&i.ClientIp,
+			&i.IsBlocked,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.FamilyID,
+			&i.ConsumedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateSession = `-- name: UpdateSession :exec
 UPDATE sessions set is_blocked = $1 where user_id = $2
 `
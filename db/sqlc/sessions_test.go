@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestSession(t *testing.T) Session {
+	user := createTestUser(t)
+	id := uuid.New()
+
+	arg := CreateSessionParams{
+		ID:           id,
+		UserID:       user.ID,
+		FamilyID:     id,
+		RefreshToken: util.GenerateString(32),
+		UserAgent:    util.GenerateString(8),
+		ClientIp:     "127.0.0.1",
+		ExpiresAt:    time.Now().Add(time.Hour).Unix(),
+		CreatedAt:    time.Now().Unix(),
+	}
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.FamilyID, session.FamilyID)
+	require.False(t, session.IsBlocked)
+	require.Zero(t, session.ConsumedAt)
+	return session
+}
+
+func rotateTestSession(store Store, session Session) (Session, error) {
+	return store.RotateSessionTx(context.Background(), RotateSessionTxParams{
+		ID:         session.ID,
+		ConsumedAt: time.Now().Unix(),
+		Session: CreateSessionParams{
+			ID:           uuid.New(),
+			RefreshToken: util.GenerateString(32),
+			UserAgent:    session.UserAgent,
+			ClientIp:     session.ClientIp,
+			ExpiresAt:    time.Now().Add(time.Hour).Unix(),
+			CreatedAt:    time.Now().Unix(),
+		},
+	})
+}
+
+func TestRotateSessionTx(t *testing.T) {
+	store := NewStore(testDB)
+	session := createTestSession(t)
+
+	next, err := rotateTestSession(store, session)
+	require.NoError(t, err)
+	require.NotEqual(t, session.ID, next.ID)
+	require.Equal(t, session.UserID, next.UserID)
+	require.Equal(t, session.FamilyID, next.FamilyID)
+
+	consumed, err := store.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.NotZero(t, consumed.ConsumedAt)
+
+	//The old refresh token can't be exchanged twice
+	_, err = rotateTestSession(store, session)
+	require.ErrorIs(t, err, ErrSessionReused)
+}
+
+func TestBlockSessionFamily(t *testing.T) {
+	store := NewStore(testDB)
+	session := createTestSession(t)
+	next, err := rotateTestSession(store, session)
+	require.NoError(t, err)
+	other := createTestSession(t)
+
+	rows, err := store.BlockSessionFamily(context.Background(), BlockSessionFamilyParams{
+		UserID:   session.UserID,
+		FamilyID: session.FamilyID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rows)
+
+	blocked, err := store.GetSession(context.Background(), next.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	//A blocked session can't be rotated either
+	_, err = rotateTestSession(store, blocked)
+	require.ErrorIs(t, err, ErrSessionReused)
+
+	unrelated, err := store.GetSession(context.Background(), other.ID)
+	require.NoError(t, err)
+	require.False(t, unrelated.IsBlocked)
+}
+
+func TestListActiveSessions(t *testing.T) {
+	store := NewStore(testDB)
+	session := createTestSession(t)
+	next, err := rotateTestSession(store, session)
+	require.NoError(t, err)
+
+	//Only the latest session of the family can still be refreshed
+	list, err := store.ListActiveSessions(context.Background(), ListActiveSessionsParams{
+		UserID:    session.UserID,
+		ExpiresAt: time.Now().Unix(),
+	})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, next.ID, list[0].ID)
+}
+
+func TestGetTokenRevocation(t *testing.T) {
+	session := createTestSession(t)
+	arg := GetTokenRevocationParams{
+		ID:       session.UserID,
+		FamilyID: session.FamilyID,
+	}
+
+	state, err := testQueries.GetTokenRevocation(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, state.SessionBlocked)
+	require.NotZero(t, state.PasswordChangedAt)
+
+	_, err = testQueries.BlockSessionFamily(context.Background(), BlockSessionFamilyParams{
+		UserID:   session.UserID,
+		FamilyID: session.FamilyID,
+	})
+	require.NoError(t, err)
+
+	state, err = testQueries.GetTokenRevocation(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, state.SessionBlocked)
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestSigningKey(t *testing.T, tokenType string) SigningKey {
+	arg := CreateSigningKeyParams{
+		ID:        util.GenerateString(16),
+		TokenType: tokenType,
+		Secret:    util.GenerateString(32),
+		NotBefore: time.Now().Unix(),
+		CreatedAt: time.Now().Unix(),
+	}
+	key, err := testQueries.CreateSigningKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, key.ID)
+	require.Equal(t, arg.Secret, key.Secret)
+	require.Equal(t, arg.NotBefore, key.NotBefore)
+	require.Zero(t, key.RetiredAt)
+	return key
+}
+
+func TestRetireSigningKey(t *testing.T) {
+	tokenType := util.GenerateString(10)
+	key1 := createTestSigningKey(t, tokenType)
+	key2 := createTestSigningKey(t, tokenType)
+
+	retired, err := testQueries.RetireSigningKey(context.Background(), RetireSigningKeyParams{
+		RetiredAt: time.Now().Unix(),
+		TokenType: tokenType,
+		ID:        key1.ID,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, retired.RetiredAt)
+
+	//A key is only retired once
+	_, err = testQueries.RetireSigningKey(context.Background(), RetireSigningKeyParams{
+		RetiredAt: time.Now().Unix(),
+		TokenType: tokenType,
+		ID:        key1.ID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	active, err := testQueries.ListActiveSigningKeys(context.Background(), tokenType)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	require.Equal(t, key2.ID, active[0].ID)
+
+	all, err := testQueries.ListSigningKeys(context.Background(), tokenType)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+}
+
+func TestSigningKeyIDPerTokenType(t *testing.T) {
+	id := util.GenerateString(16)
+	keys := make([]SigningKey, 2)
+	for i := range keys {
+		key, err := testQueries.CreateSigningKey(context.Background(), CreateSigningKeyParams{
+			ID:        id,
+			TokenType: util.GenerateString(10),
+			Secret:    util.GenerateString(32),
+			CreatedAt: time.Now().Unix(),
+		})
+		require.NoError(t, err)
+		keys[i] = key
+	}
+
+	//Retiring a key leaves the key with the same id of another token type alone
+	_, err := testQueries.RetireSigningKey(context.Background(), RetireSigningKeyParams{
+		RetiredAt: time.Now().Unix(),
+		TokenType: keys[0].TokenType,
+		ID:        id,
+	})
+	require.NoError(t, err)
+
+	active, err := testQueries.ListActiveSigningKeys(context.Background(), keys[1].TokenType)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	require.Equal(t, id, active[0].ID)
+}
+func TestUpdateSigningKeySecret(t *testing.T) {
+	key := createTestSigningKey(t, util.GenerateString(10))
+	arg := UpdateSigningKeySecretParams{
+		Secret:    util.GenerateString(40),
+		PublicKey: util.GenerateString(40),
+		TokenType: key.TokenType,
+		ID:        key.ID,
+	}
+
+	updated, err := testQueries.UpdateSigningKeySecret(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Secret, updated.Secret)
+	require.Equal(t, arg.PublicKey, updated.PublicKey)
+	require.Equal(t, key.NotBefore, updated.NotBefore)
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+)
+
+//Returned by StatementTx when the entries don't add up to the stored account balance
+var ErrLedgerMismatch = errors.New("Account balance doesn't match the sum of its entries")
+
+//Input for statement tx, the period includes FromTime and excludes ToTime
+type StatementTxParams struct {
+	AccountID int64 `json:"account_id"`
+	FromTime  int64 `json:"from_time"`
+	ToTime    int64 `json:"to_time"`
+}
+
+//Entry with the account balance right after it was posted and the transfer that created it
+type StatementLine struct {
+	Entry
+	Balance               int64 `json:"balance"`
+	TransactionID         int64 `json:"transaction_id,omitempty"`
+	CounterpartyAccountID int64 `json:"counterparty_account_id,omitempty"`
+}
+
+//Result of statement tx
+type StatementTxResult struct {
+	Account        Account         `json:"account"`
+	FromTime       int64           `json:"from_time"`
+	ToTime         int64           `json:"to_time"`
+	OpeningBalance int64           `json:"opening_balance"`
+	ClosingBalance int64           `json:"closing_balance"`
+	Lines          []StatementLine `json:"entries"`
+}
+
+//Builds the statement of an account for a period from a single snapshot of the entries table.
+//Every entry is returned with its running balance and the result is checked against accounts.balance
+func (store *SQLStore) StatementTx(ctx context.Context, arg StatementTxParams) (StatementTxResult, error) {
+	result := StatementTxResult{
+		FromTime: arg.FromTime,
+		ToTime:   arg.ToTime,
+	}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := store.execTxWithOptions(ctx, opts, func(q *Queries) error {
+		var err error
+
+		result.Account, err = q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		result.OpeningBalance, err = q.SumEntries(ctx, SumEntriesParams{
+			AccountID: arg.AccountID,
+			FromTime:  0,
+			ToTime:    arg.FromTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		entries, err := q.ListEntries(ctx, ListEntriesParams{
+			AccountID: arg.AccountID,
+			FromTime:  arg.FromTime,
+			ToTime:    arg.ToTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		transactions, err := q.ListTransactionsByEntries(ctx, entryIDs(entries))
+		if err != nil {
+			return err
+		}
+
+		later, err := q.SumEntries(ctx, SumEntriesParams{
+			AccountID: arg.AccountID,
+			FromTime:  arg.ToTime,
+			ToTime:    math.MaxInt64,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Lines, result.ClosingBalance = runningBalances(result.OpeningBalance, entries)
+		linkTransactions(result.Lines, transactions)
+
+		if result.ClosingBalance+later != result.Account.Balance {
+			return ErrLedgerMismatch
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+func runningBalances(opening int64, entries []Entry) ([]StatementLine, int64) {
+	lines := make([]StatementLine, len(entries))
+	balance := opening
+	for i, entry := range entries {
+		balance += entry.Amount
+		lines[i] = StatementLine{Entry: entry, Balance: balance}
+	}
+	return lines, balance
+}
+
+func entryIDs(entries []Entry) []int64 {
+	ids := make([]int64, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	return ids
+}
+
+//Fills in the transfer and the other side's account for lines created by TransferTx
+func linkTransactions(lines []StatementLine, transactions []Transaction) {
+	byEntry := make(map[int64]Transaction, len(transactions)*2)
+	for _, transaction := range transactions {
+		byEntry[transaction.FromEntryID] = transaction
+		byEntry[transaction.ToEntryID] = transaction
+	}
+	for i := range lines {
+		transaction, ok := byEntry[lines[i].ID]
+		if !ok {
+			continue
+		}
+		lines[i].TransactionID = transaction.ID
+		if transaction.FromEntryID == lines[i].ID {
+			lines[i].CounterpartyAccountID = transaction.ToAccountID
+		} else {
+			lines[i].CounterpartyAccountID = transaction.FromAccountID
+		}
+	}
+}
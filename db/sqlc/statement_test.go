@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	//Accounts start empty so the entries are the whole ledger
+	account1 := createTestAccount(t, 0)
+	account2 := createTestAccount(t, 0)
+	_, err := testQueries.UpdateBalance(context.Background(), UpdateBalanceParams{ID: account1.ID, Amount: 100})
+	require.NoError(t, err)
+	_, err = testQueries.CreateEntry(context.Background(), CreateEntryParams{AccountID: account1.ID, Amount: 100, CreatedAt: 1})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+	}
+
+	statement, err := store.StatementTx(context.Background(), StatementTxParams{
+		AccountID: account1.ID,
+		FromTime:  2,
+		ToTime:    time.Now().Unix() + 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), statement.OpeningBalance)
+	require.Equal(t, int64(70), statement.ClosingBalance)
+	require.Len(t, statement.Lines, 3)
+	require.Equal(t, account1.ID, statement.Account.ID)
+	for i, line := range statement.Lines {
+		require.Equal(t, int64(-10), line.Amount)
+		require.Equal(t, int64(100-10*(i+1)), line.Balance)
+		require.NotZero(t, line.TransactionID)
+		require.Equal(t, account2.ID, line.CounterpartyAccountID)
+	}
+
+	//Balance changed without an entry, the statement must not hide it
+	_, err = testQueries.UpdateBalance(context.Background(), UpdateBalanceParams{ID: account2.ID, Amount: 5})
+	require.NoError(t, err)
+	_, err = store.StatementTx(context.Background(), StatementTxParams{
+		AccountID: account2.ID,
+		FromTime:  0,
+		ToTime:    time.Now().Unix() + 1,
+	})
+	require.ErrorIs(t, err, ErrLedgerMismatch)
+}
@@ -3,14 +3,34 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Store provides functions to execute db queries and transactions
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	StatementTx(ctx context.Context, arg StatementTxParams) (StatementTxResult, error)
+	RepairBalanceTx(ctx context.Context, accountID int64) (Account, error)
+	RunScheduledTransferTx(ctx context.Context, arg RunScheduledTransferTxParams) (RunScheduledTransferTxResult, error)
+	RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (Session, error)
+	SetAccountStatusTx(ctx context.Context, arg SetAccountStatusTxParams) (Account, error)
+	AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error)
+	UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (User, error)
+	BlockUserSessionsTx(ctx context.Context, arg BlockUserSessionsTxParams) error
+	CloseAccountTx(ctx context.Context, arg CloseAccountTxParams) (CloseAccountTxResult, error)
+	AppendAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error)
+	ConfirmTotpTx(ctx context.Context, arg ConfirmTotpTxParams) (User, error)
+	DisableTwoFactorTx(ctx context.Context, arg DisableTwoFactorTxParams) (User, error)
+	UpdateProfileTx(ctx context.Context, arg UpdateProfileTxParams) (User, error)
 }
 
 // Implements store functions on real db
@@ -29,7 +49,12 @@ func NewStore(db *sql.DB) Store {
 
 //Executes db transaction
 func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+	return store.execTxWithOptions(ctx, nil, fn)
+}
+
+//Executes db transaction with custom isolation level or read only mode
+func (store *SQLStore) execTxWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, opts)
 
 	if err != nil {
 		return err
@@ -50,14 +75,24 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 
 var txKey = struct{}{}
 
-//Input for transfer tx
+//Returned by TransferTx when the source account can't cover the amount
+var ErrInsufficientFunds = errors.New("Account does not have enough balance")
+
+//Returned by TransferTx when the fx quote has expired or was already used
+var ErrQuoteUnavailable = errors.New("Exchange rate quote has expired or was already used")
+
+//Input for transfer tx. ToAmount and Rate are only needed for cross currency transfers,
+//they default to Amount and "1". QuoteID is consumed in the same transaction when set.
+//Actor is written to the audit log with the transfer
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	ToEntryID     int64 `json:"to_entry_id"`
-	FromEntryID   int64 `json:"from_entry_id"`
-	Amount        int64 `json:"amount"`
-	CreatedAt     int64 `json:"created_at"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        int64      `json:"amount"`
+	ToAmount      int64      `json:"to_amount"`
+	Rate          string     `json:"rate"`
+	QuoteID       uuid.UUID  `json:"quote_id"`
+	CreatedAt     int64      `json:"created_at"`
+	Actor         AuditActor `json:"actor"`
 }
 
 //Result of transfer tx
@@ -69,70 +104,148 @@ type TransferTxResult struct {
 	ToEntry     Entry       `json:"to_entry"`
 }
 
-//This function is transfers money between accounts and adds entries to entries table, all done in single transaction
+//This function is transfers money between accounts and adds entries to entries table, all done in single transaction.
+//Both accounts are locked before the balance is checked so concurrent transfers can't overdraw the source account
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
-
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-			CreatedAt: time.Now().Unix(),
-		})
-
+		result, err = transfer(ctx, q, arg)
 		if err != nil {
 			return err
 		}
+		return auditTransfer(ctx, q, arg.Actor, "", result)
+	})
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-			CreatedAt: time.Now().Unix(),
-		})
+	return result, err
+}
 
-		if err != nil {
-			return err
-		}
+//Appends the transfer to the audit log inside the transaction that made it
+func auditTransfer(ctx context.Context, q *Queries, actor AuditActor, reason string, result TransferTxResult) error {
+	audit, err := NewAuditLogParams(actor, AuditTransferCreate, AuditTargetTransfer,
+		strconv.FormatInt(result.Transaction.ID, 10), reason, nil, result)
+	if err != nil {
+		return err
+	}
+	_, err = appendAuditLog(ctx, q, audit)
+	return err
+}
 
-		result.Transaction, err = q.CreateTransaction(ctx, CreateTransactionParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			FromEntryID:   result.FromEntry.ID,
-			ToEntryID:     result.ToEntry.ID,
-			Amount:        arg.Amount,
-			CreatedAt:     time.Now().Unix(),
-		})
+//Moves the money inside an open transaction. Nothing is written when the balance check fails
+//so callers can keep using the transaction after ErrInsufficientFunds
+func transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	if arg.ToAmount == 0 {
+		arg.ToAmount = arg.Amount
+	}
+	if len(arg.Rate) == 0 {
+		arg.Rate = "1"
+	}
 
-		if err != nil {
-			return err
-		}
+	fromAccount, toAccount, err := lockAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
 
-		account1, err := q.UpdateBalance(ctx, UpdateBalanceParams{
-			ID:     arg.FromAccountID,
-			Amount: -arg.Amount,
-		})
+	if err := AccountStatusError(fromAccount.Status); err != nil {
+		return result, err
+	}
+	if err := AccountStatusError(toAccount.Status); err != nil {
+		return result, err
+	}
+	if fromAccount.Balance < arg.Amount {
+		return result, ErrInsufficientFunds
+	}
 
+	if arg.QuoteID != uuid.Nil {
+		_, err = q.UseFxQuote(ctx, UseFxQuoteParams{
+			UsedAt: time.Now().Unix(),
+			ID:     arg.QuoteID,
+		})
+		if err == sql.ErrNoRows {
+			return result, ErrQuoteUnavailable
+		}
 		if err != nil {
-			return err
+			return result, err
 		}
+	}
 
-		result.FromAccount = account1
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.FromAccountID,
+		Amount:    -arg.Amount,
+		CreatedAt: time.Now().Unix(),
+	})
 
-		account2, err := q.UpdateBalance(ctx, UpdateBalanceParams{
-			ID:     arg.ToAccountID,
-			Amount: arg.Amount,
-		})
+	if err != nil {
+		return result, err
+	}
 
-		if err != nil {
-			return err
-		}
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.ToAccountID,
+		Amount:    arg.ToAmount,
+		CreatedAt: time.Now().Unix(),
+	})
 
-		result.ToAccount = account2
+	if err != nil {
+		return result, err
+	}
 
-		return nil
+	result.Transaction, err = q.CreateTransaction(ctx, CreateTransactionParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		FromEntryID:   result.FromEntry.ID,
+		ToEntryID:     result.ToEntry.ID,
+		Amount:        arg.Amount,
+		ToAmount:      arg.ToAmount,
+		Rate:          arg.Rate,
+		FromCurrency:  fromAccount.Currency,
+		ToCurrency:    toAccount.Currency,
+		CreatedAt:     time.Now().Unix(),
 	})
 
-	return result, err
+	if err != nil {
+		return result, err
+	}
+
+	account1, err := q.UpdateBalance(ctx, UpdateBalanceParams{
+		ID:     arg.FromAccountID,
+		Amount: -arg.Amount,
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	result.FromAccount = account1
+
+	account2, err := q.UpdateBalance(ctx, UpdateBalanceParams{
+		ID:     arg.ToAccountID,
+		Amount: arg.ToAmount,
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	result.ToAccount = account2
+
+	return result, nil
+}
+
+//Locks both accounts for update. Rows are always locked in ascending id order so
+//two transfers running in opposite directions can't deadlock each other
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID, toAccountID int64) (fromAccount Account, toAccount Account, err error) {
+	if fromAccountID > toAccountID {
+		toAccount, fromAccount, err = lockAccounts(ctx, q, toAccountID, fromAccountID)
+		return
+	}
+
+	fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	if err != nil {
+		return
+	}
+
+	toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+	return
 }
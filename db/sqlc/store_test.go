@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -182,3 +183,152 @@ func addMoney(ctx context.Context, q Store, account1Id, account2Id, amount1, amo
 	}
 	return
 }
+
+func TestTransferTxOppositeDirections(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createTestAccount(t, -1)
+	account2 := createTestAccount(t, -1)
+
+	//Half of the transfers go each way, the accounts are locked in id order so none of them deadlock
+	n := 10
+	amount := int64(10)
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		fromAccountID := account1.ID
+		toAccountID := account2.ID
+		if i%2 == 1 {
+			fromAccountID, toAccountID = toAccountID, fromAccountID
+		}
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: fromAccountID,
+				ToAccountID:   toAccountID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	amount := int64(10)
+	account1 := createTestAccount(t, amount*2)
+	account2 := createTestAccount(t, -1)
+
+	//Only two of the concurrent transfers can be covered by the balance
+	n := 5
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	failed := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrInsufficientFunds)
+			failed++
+		}
+	}
+	require.Equal(t, n-2, failed)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance+amount*2, updatedAccount2.Balance)
+}
+
+func TestTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createTestAccount(t, -1)
+	account2 := createTestAccount(t, -1)
+	quote := createTestFxQuote(t, time.Now().Add(time.Minute).Unix())
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        quote.Amount,
+		ToAmount:      quote.ToAmount,
+		Rate:          quote.Rate,
+		QuoteID:       quote.ID,
+	}
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, quote.Amount, result.Transaction.Amount)
+	require.Equal(t, quote.ToAmount, result.Transaction.ToAmount)
+	require.Equal(t, account1.Currency, result.Transaction.FromCurrency)
+	require.Equal(t, account2.Currency, result.Transaction.ToCurrency)
+	require.Equal(t, -quote.Amount, result.FromEntry.Amount)
+	require.Equal(t, quote.ToAmount, result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-quote.Amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+quote.ToAmount, result.ToAccount.Balance)
+
+	//The quote was consumed by the first transfer
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrQuoteUnavailable)
+}
+
+func TestTransferTxAudit(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createTestAccount(t, 100)
+	account2 := createTestAccount(t, -1)
+	actor := AuditActor{ActorID: account1.UserID, RequestID: "request", ClientIp: "127.0.0.1"}
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Actor:         actor,
+	})
+	require.NoError(t, err)
+
+	logs, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		TargetType: AuditTargetTransfer,
+		TargetID:   fmt.Sprint(result.Transaction.ID),
+		Count:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, actor.ActorID, logs[0].ActorID)
+	require.Equal(t, actor.RequestID, logs[0].RequestID)
+	require.Equal(t, AuditTransferCreate, logs[0].Action)
+
+	//A failed transfer leaves no audit row behind
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1000,
+		Actor:         actor,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
@@ -7,23 +7,29 @@ package db
 
 import (
 	"context"
+
+	"github.com/lib/pq"
 )
 
 const createTransaction = `-- name: CreateTransaction :one
 INSERT into transactions (
- "from_account_id", "to_account_id", "from_entry_id", "to_entry_id", "amount", "created_at"
+ "from_account_id", "to_account_id", "from_entry_id", "to_entry_id", "amount", "to_amount", "rate", "from_currency", "to_currency", "created_at"
 )
 values
-($1, $2, $3, $4, $5, $6) RETURNING id, from_account_id, to_account_id, from_entry_id, to_entry_id, amount, created_at
+($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, from_account_id, to_account_id, from_entry_id, to_entry_id, amount, created_at, to_amount, rate, from_currency, to_currency
 `
 
 type CreateTransactionParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	FromEntryID   int64 `json:"from_entry_id"`
-	ToEntryID     int64 `json:"to_entry_id"`
-	Amount        int64 `json:"amount"`
-	CreatedAt     int64 `json:"created_at"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	FromEntryID   int64  `json:"from_entry_id"`
+	ToEntryID     int64  `json:"to_entry_id"`
+	Amount        int64  `json:"amount"`
+	ToAmount      int64  `json:"to_amount"`
+	Rate          string `json:"rate"`
+	FromCurrency  string `json:"from_currency"`
+	ToCurrency    string `json:"to_currency"`
+	CreatedAt     int64  `json:"created_at"`
 }
 
 func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error) {
@@ -33,6 +39,10 @@ func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionPa
 		arg.FromEntryID,
 		arg.ToEntryID,
 		arg.Amount,
+		arg.ToAmount,
+		arg.Rate,
+		arg.FromCurrency,
+		arg.ToCurrency,
 		arg.CreatedAt,
 	)
 	var i Transaction
@@ -44,12 +54,16 @@ func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionPa
 		&i.ToEntryID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.Rate,
+		&i.FromCurrency,
+		&i.ToCurrency,
 	)
 	return i, err
 }
 
 const getTransaction = `-- name: GetTransaction :one
-SELECT id, from_account_id, to_account_id, from_entry_id, to_entry_id, amount, created_at from transactions where id = $1 limit 1
+SELECT id, from_account_id, to_account_id, from_entry_id, to_entry_id, amount, created_at, to_amount, rate, from_currency, to_currency from transactions where id = $1 limit 1
 `
 
 func (q *Queries) GetTransaction(ctx context.Context, id int64) (Transaction, error) {
@@ -63,12 +77,116 @@ func (q *Queries) GetTransaction(ctx context.Context, id int64) (Transaction, er
 		&i.ToEntryID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.Rate,
+		&i.FromCurrency,
+		&i.ToCurrency,
 	)
 	return i, err
 }
 
+const getTransactionForUser = `-- name: GetTransactionForUser :one
+SELECT t.id, t.from_account_id, t.to_account_id, t.from_entry_id, t.to_entry_id, t.amount, t.created_at, t.to_amount, t.rate, t.from_currency, t.to_currency from transactions t
+where t.id = $1 and exists (
+  SELECT 1 from accounts a
+  where a.user_id = $2 and a.id in (t.from_account_id, t.to_account_id)
+) limit 1
+`
+
+type GetTransactionForUserParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) GetTransactionForUser(ctx context.Context, arg GetTransactionForUserParams) (Transaction, error) {
+	row := q.db.QueryRowContext(ctx, getTransactionForUser, arg.ID, arg.UserID)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.FromEntryID,
+		&i.ToEntryID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.Rate,
+		&i.FromCurrency,
+		&i.ToCurrency,
+	)
+	return i, err
+}
+
+const listAccountTransactions = `-- name: ListAccountTransactions :many
+SELECT id, from_account_id, to_account_id, from_entry_id, to_entry_id, amount, created_at, to_amount, rate, from_currency, to_currency from transactions
+where (
+    (from_account_id = $1 and $2::varchar <> 'in') or
+    (to_account_id = $1 and $2::varchar <> 'out')
+  )
+  and created_at between $3 and $4
+  and amount between $5 and $6
+  and id < $7
+order by id desc
+limit $8
+`
+
+type ListAccountTransactionsParams struct {
+	AccountID int64  `json:"account_id"`
+	Direction string `json:"direction"`
+	FromTime  int64  `json:"from_time"`
+	ToTime    int64  `json:"to_time"`
+	MinAmount int64  `json:"min_amount"`
+	MaxAmount int64  `json:"max_amount"`
+	Cursor    int64  `json:"cursor"`
+	Count     int32  `json:"count"`
+}
+
+func (q *Queries) ListAccountTransactions(ctx context.Context, arg ListAccountTransactionsParams) ([]Transaction, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransactions,
+		arg.AccountID,
+		arg.Direction,
+		arg.FromTime,
+		arg.ToTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Cursor,
+		arg.Count,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transaction{}
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.FromEntryID,
+			&i.ToEntryID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.Rate,
+			&i.FromCurrency,
+			&i.ToCurrency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransactions = `-- name: ListTransactions :many
-SELECT id, from_account_id, to_account_id, from_entry_id, to_entry_id, amount, created_at from transactions order by id limit $1 offset $2
+SELECT id, from_account_id, to_account_id, from_entry_id, to_entry_id, amount, created_at, to_amount, rate, from_currency, to_currency from transactions order by id limit $1 offset $2
 `
 
 type ListTransactionsParams struct {
@@ -93,6 +211,96 @@ func (q *Queries) ListTransactions(ctx context.Context, arg ListTransactionsPara
 			&i.ToEntryID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.Rate,
+			&i.FromCurrency,
+			&i.ToCurrency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsByEntries = `-- name: ListTransactionsByEntries :many
+SELECT id, from_account_id, to_account_id, from_entry_id, to_entry_id, amount, created_at, to_amount, rate, from_currency, to_currency from transactions
+where from_entry_id = ANY($1::bigint[]) or to_entry_id = ANY($1::bigint[])
+`
+
+func (q *Queries) ListTransactionsByEntries(ctx context.Context, entryIds []int64) ([]Transaction, error) {
+	rows, err := q.db.QueryContext(ctx, listTransactionsByEntries, pq.Array(entryIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transaction{}
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.FromEntryID,
+			&i.ToEntryID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.Rate,
+			&i.FromCurrency,
+			&i.ToCurrency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnbalancedTransactions = `-- name: ListUnbalancedTransactions :many
+SELECT t.id, t.from_account_id, t.to_account_id, t.from_entry_id, t.to_entry_id, t.amount, t.created_at, t.to_amount, t.rate, t.from_currency, t.to_currency from transactions t
+join entries fe on fe.id = t.from_entry_id
+join entries te on te.id = t.to_entry_id
+where fe.account_id <> t.from_account_id
+  or te.account_id <> t.to_account_id
+  or fe.amount <> -t.amount
+  or te.amount <> t.to_amount
+order by t.id
+`
+
+func (q *Queries) ListUnbalancedTransactions(ctx context.Context) ([]Transaction, error) {
+	rows, err := q.db.QueryContext(ctx, listUnbalancedTransactions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transaction{}
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.FromEntryID,
+			&i.ToEntryID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.Rate,
+			&i.FromCurrency,
+			&i.ToCurrency,
 		); err != nil {
 			return nil, err
 		}
@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"math"
 	"testing"
 	"time"
 
@@ -21,6 +23,10 @@ func createTestTransaction(t *testing.T) Transaction {
 		FromEntryID:   entry1.ID,
 		ToEntryID:     entry2.ID,
 		Amount:        amount,
+		ToAmount:      amount,
+		Rate:          "1",
+		FromCurrency:  account1.Currency,
+		ToCurrency:    account2.Currency,
 		CreatedAt:     time.Now().Unix(),
 	}
 	tx, err := testQueries.CreateTransaction(context.Background(), args)
@@ -31,6 +37,7 @@ func createTestTransaction(t *testing.T) Transaction {
 	require.Equal(t, tx.ToEntryID, entry2.ID)
 	require.Equal(t, tx.Amount, entry2.Amount)
 	require.Equal(t, -tx.Amount, entry1.Amount)
+	require.Equal(t, tx.ToAmount, entry2.Amount)
 	return tx
 }
 
@@ -66,3 +73,108 @@ func TestListTransactions(t *testing.T) {
 		require.NotEmpty(t, entry)
 	}
 }
+
+func TestGetTransactionForUser(t *testing.T) {
+	tx := createTestTransaction(t)
+	fromAccount, err := testQueries.GetAccount(context.Background(), tx.FromAccountID)
+	require.NoError(t, err)
+
+	checkTx, err := testQueries.GetTransactionForUser(context.Background(), GetTransactionForUserParams{
+		ID:     tx.ID,
+		UserID: fromAccount.UserID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, tx, checkTx)
+
+	//Users that don't own either account can't see the transaction
+	otherUser := createTestUser(t)
+	_, err = testQueries.GetTransactionForUser(context.Background(), GetTransactionForUserParams{
+		ID:     tx.ID,
+		UserID: otherUser.ID,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestListAccountTransactions(t *testing.T) {
+	account1 := createTestAccount(t, -1)
+	account2 := createTestAccount(t, -1)
+	store := NewStore(testDB)
+
+	for i := 0; i < 3; i++ {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        int64(10 * (i + 1)),
+		})
+		require.NoError(t, err)
+	}
+
+	args := ListAccountTransactionsParams{
+		AccountID: account1.ID,
+		Direction: "all",
+		FromTime:  0,
+		ToTime:    math.MaxInt64,
+		MinAmount: 0,
+		MaxAmount: math.MaxInt64,
+		Cursor:    math.MaxInt64,
+		Count:     2,
+	}
+	page, err := testQueries.ListAccountTransactions(context.Background(), args)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Greater(t, page[0].ID, page[1].ID)
+
+	args.Cursor = page[1].ID
+	page, err = testQueries.ListAccountTransactions(context.Background(), args)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, int64(10), page[0].Amount)
+
+	args.Cursor = math.MaxInt64
+	args.Direction = "in"
+	page, err = testQueries.ListAccountTransactions(context.Background(), args)
+	require.NoError(t, err)
+	require.Empty(t, page)
+
+	args.AccountID = account2.ID
+	args.MinAmount = 20
+	args.Count = 5
+	page, err = testQueries.ListAccountTransactions(context.Background(), args)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	for _, tx := range page {
+		require.Equal(t, account2.ID, tx.ToAccountID)
+		require.GreaterOrEqual(t, tx.Amount, int64(20))
+	}
+}
+
+func TestListUnbalancedTransactions(t *testing.T) {
+	valid := createTestTransaction(t)
+
+	//Entries belong to the right accounts but don't add up to the transfer
+	entry1, account1 := createTestEntry(t, -10)
+	entry2, account2 := createTestEntry(t, 20)
+	invalid, err := testQueries.CreateTransaction(context.Background(), CreateTransactionParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		FromEntryID:   entry1.ID,
+		ToEntryID:     entry2.ID,
+		Amount:        10,
+		ToAmount:      10,
+		Rate:          "1",
+		FromCurrency:  account1.Currency,
+		ToCurrency:    account2.Currency,
+		CreatedAt:     time.Now().Unix(),
+	})
+	require.NoError(t, err)
+
+	list, err := testQueries.ListUnbalancedTransactions(context.Background())
+	require.NoError(t, err)
+
+	ids := make(map[int64]bool)
+	for _, tx := range list {
+		ids[tx.ID] = true
+	}
+	require.True(t, ids[invalid.ID])
+	require.False(t, ids[valid.ID])
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+//Returned when enrolling or confirming a second factor for a user who already has one
+var ErrTwoFactorEnabled = errors.New("Two factor authentication is already enabled")
+
+//Input for ConfirmTotpTx. Step is the time step the confirmation code matched, so the same
+//code can't be used again to log in. RecoveryCodeHashes replace any codes the user had
+type ConfirmTotpTxParams struct {
+	UserID             int64      `json:"user_id"`
+	Step               int64      `json:"step"`
+	RecoveryCodeHashes []string   `json:"-"`
+	Actor              AuditActor `json:"actor"`
+}
+
+//Confirms a pending totp enrolment, stores the recovery codes and turns two factor authentication on
+func (store *SQLStore) ConfirmTotpTx(ctx context.Context, arg ConfirmTotpTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		now := time.Now().Unix()
+		_, err := q.ConfirmTotpCredential(ctx, ConfirmTotpCredentialParams{
+			ConfirmedAt:  now,
+			LastUsedStep: arg.Step,
+			UserID:       arg.UserID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrTwoFactorEnabled
+			}
+			return err
+		}
+		if err := q.DeleteRecoveryCodes(ctx, arg.UserID); err != nil {
+			return err
+		}
+		for _, hash := range arg.RecoveryCodeHashes {
+			_, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				UserID:    arg.UserID,
+				CodeHash:  hash,
+				CreatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		user, err = q.SetUserTwoFactor(ctx, SetUserTwoFactorParams{
+			TwoFactorEnabled: true,
+			ID:               arg.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		return appendUserAudit(ctx, q, arg.Actor, AuditUserTwoFactorEnable, user.ID)
+	})
+
+	return user, err
+}
+
+type DisableTwoFactorTxParams struct {
+	UserID int64      `json:"user_id"`
+	Actor  AuditActor `json:"actor"`
+}
+
+//Removes the totp credential and recovery codes and turns two factor authentication off
+func (store *SQLStore) DisableTwoFactorTx(ctx context.Context, arg DisableTwoFactorTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := q.DeleteTotpCredential(ctx, arg.UserID); err != nil {
+			return err
+		}
+		if err := q.DeleteRecoveryCodes(ctx, arg.UserID); err != nil {
+			return err
+		}
+		var err error
+		user, err = q.SetUserTwoFactor(ctx, SetUserTwoFactorParams{
+			TwoFactorEnabled: false,
+			ID:               arg.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		return appendUserAudit(ctx, q, arg.Actor, AuditUserTwoFactorDisable, user.ID)
+	})
+
+	return user, err
+}
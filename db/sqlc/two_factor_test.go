@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestTotpCredential(t *testing.T, user User) TotpCredential {
+	credential, err := testQueries.UpsertTotpCredential(context.Background(), UpsertTotpCredentialParams{
+		UserID:    user.ID,
+		Secret:    util.GenerateString(32),
+		CreatedAt: time.Now().Unix(),
+	})
+	require.NoError(t, err)
+	require.Zero(t, credential.ConfirmedAt)
+	return credential
+}
+
+func TestTwoFactorTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createTestUser(t)
+	createTestTotpCredential(t, user)
+	code := util.GenerateString(10)
+
+	enabled, err := store.ConfirmTotpTx(context.Background(), ConfirmTotpTxParams{
+		UserID:             user.ID,
+		Step:               100,
+		RecoveryCodeHashes: []string{util.HashSecretToken(code), util.HashSecretToken(util.GenerateString(10))},
+		Actor:              AuditActor{ActorID: user.ID, RequestID: "confirm-totp"},
+	})
+	require.NoError(t, err)
+	require.True(t, enabled.TwoFactorEnabled)
+
+	//A confirmed credential can't be confirmed or replaced by a new enrolment
+	_, err = store.ConfirmTotpTx(context.Background(), ConfirmTotpTxParams{UserID: user.ID, Step: 101})
+	require.ErrorIs(t, err, ErrTwoFactorEnabled)
+	_, err = testQueries.UpsertTotpCredential(context.Background(), UpsertTotpCredentialParams{
+		UserID:    user.ID,
+		Secret:    util.GenerateString(32),
+		CreatedAt: time.Now().Unix(),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	//Steps only move forward so a code can't be replayed
+	_, err = testQueries.UseTotpStep(context.Background(), UseTotpStepParams{LastUsedStep: 100, UserID: user.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = testQueries.UseTotpStep(context.Background(), UseTotpStepParams{LastUsedStep: 101, UserID: user.ID})
+	require.NoError(t, err)
+
+	//Recovery codes are single use
+	useCode := UseRecoveryCodeParams{
+		UsedAt:   time.Now().Unix(),
+		UserID:   user.ID,
+		CodeHash: util.HashSecretToken(code),
+	}
+	_, err = testQueries.UseRecoveryCode(context.Background(), useCode)
+	require.NoError(t, err)
+	_, err = testQueries.UseRecoveryCode(context.Background(), useCode)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	disabled, err := store.DisableTwoFactorTx(context.Background(), DisableTwoFactorTxParams{
+		UserID: user.ID,
+		Actor:  AuditActor{ActorID: user.ID, RequestID: "disable-2fa"},
+	})
+	require.NoError(t, err)
+	require.False(t, disabled.TwoFactorEnabled)
+	_, err = testQueries.GetTotpCredential(context.Background(), user.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestAttemptLoginChallenge(t *testing.T) {
+	user := createTestUser(t)
+	token := util.GenerateString(32)
+	challenge, err := testQueries.CreateLoginChallenge(context.Background(), CreateLoginChallengeParams{
+		UserID:    user.ID,
+		TokenHash: util.HashSecretToken(token),
+		Scopes:    util.ScopeAccountsRead,
+		ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		CreatedAt: time.Now().Unix(),
+	})
+	require.NoError(t, err)
+
+	arg := AttemptLoginChallengeParams{
+		TokenHash:   util.HashSecretToken(token),
+		Now:         time.Now().Unix(),
+		MaxAttempts: 2,
+	}
+	for i := 1; i <= 2; i++ {
+		attempt, err := testQueries.AttemptLoginChallenge(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, int32(i), attempt.Attempts)
+	}
+	//Out of attempts
+	_, err = testQueries.AttemptLoginChallenge(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	//Expired
+	arg.MaxAttempts = 5
+	arg.Now = challenge.ExpiresAt
+	_, err = testQueries.AttemptLoginChallenge(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	//Answered
+	arg.Now = time.Now().Unix()
+	_, err = testQueries.UseLoginChallenge(context.Background(), UseLoginChallengeParams{UsedAt: arg.Now, ID: challenge.ID})
+	require.NoError(t, err)
+	_, err = testQueries.AttemptLoginChallenge(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -14,7 +14,7 @@ INSERT into users (
   "name", "email", "password", "password_changed_at", "created_at"
 )
 values
-($1, $2, $3, $4, $5) RETURNING id, name, password, email, password_changed_at, created_at
+($1, $2, $3, $4, $5) RETURNING id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled
 `
 
 type CreateUserParams struct {
@@ -41,12 +41,15 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, name, password, email, password_changed_at, created_at from users where id = $1 limit 1
+SELECT id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled from users where id = $1 limit 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
@@ -59,12 +62,15 @@ func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, name, password, email, password_changed_at, created_at from users where email = $1 limit 1
+SELECT id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled from users where email = $1 limit 1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -77,12 +83,15 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
 	)
 	return i, err
 }
 
 const listUsers = `-- name: ListUsers :many
-SELECT id, name, password, email, password_changed_at, created_at from Users order by id limit $1 offset $2
+SELECT id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled from Users order by id limit $1 offset $2
 `
 
 type ListUsersParams struct {
@@ -106,6 +115,9 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.Email,
 			&i.PasswordChangedAt,
 			&i.CreatedAt,
+			&i.Role,
+			&i.EmailVerified,
+			&i.TwoFactorEnabled,
 		); err != nil {
 			return nil, err
 		}
@@ -120,9 +132,79 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 	return items, nil
 }
 
+const searchUsersByEmail = `-- name: SearchUsersByEmail :many
+SELECT id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled from users where email ILIKE '%' || $1::varchar || '%'
+order by id limit $2 offset $3
+`
+
+type SearchUsersByEmailParams struct {
+	Email string `json:"email"`
+	Count int32  `json:"count"`
+	Skip  int32  `json:"skip"`
+}
+
+func (q *Queries) SearchUsersByEmail(ctx context.Context, arg SearchUsersByEmailParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsersByEmail, arg.Email, arg.Count, arg.Skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Password,
+			&i.Email,
+			&i.PasswordChangedAt,
+			&i.CreatedAt,
+			&i.Role,
+			&i.EmailVerified,
+			&i.TwoFactorEnabled,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserTwoFactor = `-- name: SetUserTwoFactor :one
+UPDATE users set two_factor_enabled = $1 where id = $2 RETURNING id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled
+`
+
+type SetUserTwoFactorParams struct {
+	TwoFactorEnabled bool  `json:"two_factor_enabled"`
+	ID               int64 `json:"id"`
+}
+
+func (q *Queries) SetUserTwoFactor(ctx context.Context, arg SetUserTwoFactorParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserTwoFactor, arg.TwoFactorEnabled, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Password,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
+	)
+	return i, err
+}
+
 const updatePassword = `-- name: UpdatePassword :one
 UPDATE users set password = $1, password_changed_at = $2
-where id = $3 RETURNING id, name, password, email, password_changed_at, created_at
+where id = $3 RETURNING id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled
 `
 
 type UpdatePasswordParams struct {
@@ -141,6 +223,91 @@ func (q *Queries) UpdatePassword(ctx context.Context, arg UpdatePasswordParams)
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users set name = $1, email = $2, email_verified = email_verified and email = $2
+where id = $3 RETURNING id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled
+`
+
+type UpdateUserProfileParams struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	ID    int64  `json:"id"`
+}
+
+// A changed email is unverified until the new address is verified
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile, arg.Name, arg.Email, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Password,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users set role = $1 where id = $2 RETURNING id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled
+`
+
+type UpdateUserRoleParams struct {
+	Role string `json:"role"`
+	ID   int64  `json:"id"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Role, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Password,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users set email_verified = true where id = $1 and email = $2 RETURNING id, name, password, email, password_changed_at, created_at, role, email_verified, two_factor_enabled
+`
+
+type VerifyUserEmailParams struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+}
+
+// Returns no rows when the user changed their email since the token was sent
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Password,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
 	)
 	return i, err
 }
@@ -17,7 +17,7 @@ func createTestUser(t *testing.T) User {
 		Name:              util.GenerateString(8),
 		Email:             util.RandomEmail(),
 		Password:          hash,
-		PasswordChangedAt: time.Now().Unix(),
+		PasswordChangedAt: time.Now().UnixMilli(),
 		CreatedAt:         time.Now().Unix(),
 	}
 	user, err := testQueries.CreateUser(context.Background(), arg)
@@ -31,6 +31,8 @@ func createTestUser(t *testing.T) User {
 	require.NotZero(t, user.ID)
 	require.NotZero(t, user.CreatedAt)
 	require.NotZero(t, user.PasswordChangedAt)
+	require.Equal(t, util.RoleCustomer, user.Role)
+	require.False(t, user.EmailVerified)
 	return user
 }
 
@@ -48,3 +50,20 @@ func TestGetUser(t *testing.T) {
 	require.Equal(t, user.Password, fetchedUser.Password)
 	require.Equal(t, user.Name, fetchedUser.Name)
 }
+
+func TestUpdateUserRole(t *testing.T) {
+	user := createTestUser(t)
+	updated, err := testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Role: util.RoleAdmin,
+		ID:   user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.RoleAdmin, updated.Role)
+
+	//Only the known roles are accepted
+	_, err = testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Role: "owner",
+		ID:   user.ID,
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/util"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+type camtDocument struct {
+	XMLName   xml.Name      `xml:"Document"`
+	Namespace string        `xml:"xmlns,attr"`
+	Header    camtGroupHdr  `xml:"BkToCstmrStmt>GrpHdr"`
+	Statement camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtGroupHdr struct {
+	MsgID   string `xml:"MsgId"`
+	CreDtTm string `xml:"CreDtTm"`
+}
+
+type camtStatement struct {
+	ID        string        `xml:"Id"`
+	CreDtTm   string        `xml:"CreDtTm"`
+	FromDtTm  string        `xml:"FrToDt>FrDtTm"`
+	ToDtTm    string        `xml:"FrToDt>ToDtTm"`
+	AccountID string        `xml:"Acct>Id>Othr>Id"`
+	Currency  string        `xml:"Acct>Ccy"`
+	Balances  []camtBalance `xml:"Bal"`
+	Entries   []camtEntry   `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	DtTm      string     `xml:"Dt>DtTm"`
+}
+
+type camtEntry struct {
+	Reference   string         `xml:"NtryRef"`
+	Amount      camtAmount     `xml:"Amt"`
+	CdtDbtInd   string         `xml:"CdtDbtInd"`
+	Status      string         `xml:"Sts"`
+	BookingDate string         `xml:"BookgDt>DtTm"`
+	ValueDate   string         `xml:"ValDt>DtTm"`
+	BankTxCode  string         `xml:"BkTxCd>Prtry>Cd"`
+	Details     *camtTxDetails `xml:"NtryDtls>TxDtls"`
+	Info        string         `xml:"AddtlNtryInf,omitempty"`
+}
+
+type camtTxDetails struct {
+	TxID string `xml:"Refs>TxId"`
+}
+
+//Writes the statement as an ISO 20022 camt.053 bank to customer statement
+func WriteCAMT053(w io.Writer, statement db.StatementTxResult) error {
+	currency := statement.Account.Currency
+	now := camtTime(time.Now().Unix())
+	statementID := fmt.Sprintf("%d-%d-%d", statement.Account.ID, statement.FromTime, statement.ToTime)
+
+	document := camtDocument{
+		Namespace: camt053Namespace,
+		Header: camtGroupHdr{
+			MsgID:   statementID,
+			CreDtTm: now,
+		},
+		Statement: camtStatement{
+			ID:        statementID,
+			CreDtTm:   now,
+			FromDtTm:  camtTime(statement.FromTime),
+			ToDtTm:    camtTime(statement.ToTime),
+			AccountID: strconv.FormatInt(statement.Account.ID, 10),
+			Currency:  currency,
+			Balances: []camtBalance{
+				newCamtBalance("OPBD", statement.OpeningBalance, currency, statement.FromTime),
+				newCamtBalance("CLBD", statement.ClosingBalance, currency, statement.ToTime),
+			},
+			Entries: make([]camtEntry, len(statement.Lines)),
+		},
+	}
+	for i, line := range statement.Lines {
+		entry := camtEntry{
+			Reference:   strconv.FormatInt(line.ID, 10),
+			Amount:      newCamtAmount(line.Amount, currency),
+			CdtDbtInd:   creditDebitIndicator(line.Amount),
+			Status:      "BOOK",
+			BookingDate: camtTime(line.CreatedAt),
+			ValueDate:   camtTime(line.CreatedAt),
+			BankTxCode:  "TRANSFER",
+		}
+		if line.TransactionID != 0 {
+			entry.Details = &camtTxDetails{TxID: strconv.FormatInt(line.TransactionID, 10)}
+			entry.Info = counterpartyName(line)
+		}
+		document.Statement.Entries[i] = entry
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(document)
+}
+
+func camtTime(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+//camt amounts are always positive, the sign is carried by the credit/debit indicator
+func newCamtAmount(amount int64, currency string) camtAmount {
+	if amount < 0 {
+		amount = -amount
+	}
+	return camtAmount{Currency: currency, Value: util.FormatAmount(amount, currency)}
+}
+
+func newCamtBalance(code string, amount int64, currency string, unix int64) camtBalance {
+	return camtBalance{
+		Code:      code,
+		Amount:    newCamtAmount(amount, currency),
+		CdtDbtInd: creditDebitIndicator(amount),
+		DtTm:      camtTime(unix),
+	}
+}
+
+func creditDebitIndicator(amount int64) string {
+	if amount < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
@@ -0,0 +1,48 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/util"
+)
+
+var csvHeader = []string{
+	"date", "entry_id", "transaction_id", "counterparty_account_id", "amount", "balance", "currency",
+}
+
+//Writes the statement entries as CSV with decimal amounts
+func WriteCSV(w io.Writer, statement db.StatementTxResult) error {
+	writer := csv.NewWriter(w)
+	currency := statement.Account.Currency
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, line := range statement.Lines {
+		record := []string{
+			time.Unix(line.CreatedAt, 0).UTC().Format(time.RFC3339),
+			strconv.FormatInt(line.ID, 10),
+			optionalID(line.TransactionID),
+			optionalID(line.CounterpartyAccountID),
+			util.FormatAmount(line.Amount, currency),
+			util.FormatAmount(line.Balance, currency),
+			currency,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func optionalID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func testStatement() db.StatementTxResult {
+	return db.StatementTxResult{
+		Account:        db.Account{ID: 7, Currency: "USD", Balance: 1050},
+		FromTime:       1664582400,
+		ToTime:         1667260800,
+		OpeningBalance: 1000,
+		ClosingBalance: 1050,
+		Lines: []db.StatementLine{
+			{
+				Entry:                 db.Entry{ID: 11, AccountID: 7, Amount: -250, CreatedAt: 1664668800},
+				Balance:               750,
+				TransactionID:         3,
+				CounterpartyAccountID: 9,
+			},
+			{
+				Entry:   db.Entry{ID: 12, AccountID: 7, Amount: 300, CreatedAt: 1664755200},
+				Balance: 1050,
+			},
+		},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	err := WriteCSV(&buffer, testStatement())
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(&buffer).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, csvHeader, records[0])
+	require.Equal(t, []string{"2022-10-02T00:00:00Z", "11", "3", "9", "-2.50", "7.50", "USD"}, records[1])
+	require.Equal(t, []string{"2022-10-03T00:00:00Z", "12", "", "", "3.00", "10.50", "USD"}, records[2])
+}
+
+func TestWriteOFX(t *testing.T) {
+	var buffer bytes.Buffer
+	err := WriteOFX(&buffer, testStatement())
+	require.NoError(t, err)
+
+	output := buffer.String()
+	require.True(t, strings.HasPrefix(output, xml.Header+ofxHeader))
+
+	var document ofxDocument
+	err = xml.Unmarshal(buffer.Bytes(), &document)
+	require.NoError(t, err)
+
+	statement := document.Bank.Statement
+	require.Equal(t, "USD", statement.Currency)
+	require.Equal(t, "7", statement.AccountID)
+	require.Equal(t, "20221001000000", statement.DTStart)
+	require.Equal(t, "10.50", statement.LedgerBal.Amount)
+	require.Len(t, statement.Lines, 2)
+	require.Equal(t, ofxLine{
+		Type:     "DEBIT",
+		DTPosted: "20221002000000",
+		Amount:   "-2.50",
+		FITID:    "11",
+		Name:     "Transfer to account 9",
+	}, statement.Lines[0])
+	require.Equal(t, "CREDIT", statement.Lines[1].Type)
+	require.Empty(t, statement.Lines[1].Name)
+}
+
+func TestWriteCAMT053(t *testing.T) {
+	var buffer bytes.Buffer
+	err := WriteCAMT053(&buffer, testStatement())
+	require.NoError(t, err)
+	require.Contains(t, buffer.String(), `xmlns="`+camt053Namespace+`"`)
+
+	var document camtDocument
+	err = xml.Unmarshal(buffer.Bytes(), &document)
+	require.NoError(t, err)
+
+	statement := document.Statement
+	require.Equal(t, "7", statement.AccountID)
+	require.Len(t, statement.Balances, 2)
+	require.Equal(t, "OPBD", statement.Balances[0].Code)
+	require.Equal(t, camtAmount{Currency: "USD", Value: "10.00"}, statement.Balances[0].Amount)
+	require.Equal(t, "CLBD", statement.Balances[1].Code)
+	require.Equal(t, "CRDT", statement.Balances[1].CdtDbtInd)
+
+	require.Len(t, statement.Entries, 2)
+	require.Equal(t, camtAmount{Currency: "USD", Value: "2.50"}, statement.Entries[0].Amount)
+	require.Equal(t, "DBIT", statement.Entries[0].CdtDbtInd)
+	require.Equal(t, "3", statement.Entries[0].Details.TxID)
+	require.Equal(t, "CRDT", statement.Entries[1].CdtDbtInd)
+	require.Nil(t, statement.Entries[1].Details)
+	require.Equal(t, 1, strings.Count(buffer.String(), "<NtryDtls>"))
+}
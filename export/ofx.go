@@ -0,0 +1,126 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/util"
+)
+
+const (
+	ofxHeader     = `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`
+	ofxTimeLayout = "20060102150405"
+	bankID        = "SIMPLEBANK"
+)
+
+type ofxDocument struct {
+	XMLName xml.Name      `xml:"OFX"`
+	SignOn  ofxSignOn     `xml:"SIGNONMSGSRSV1>SONRS"`
+	Bank    ofxStatements `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxSignOn struct {
+	Status   ofxStatus `xml:"STATUS"`
+	DTServer string    `xml:"DTSERVER"`
+	Language string    `xml:"LANGUAGE"`
+}
+
+type ofxStatements struct {
+	TrnUID    string       `xml:"TRNUID"`
+	Status    ofxStatus    `xml:"STATUS"`
+	Statement ofxStatement `xml:"STMTRS"`
+}
+
+type ofxStatement struct {
+	Currency    string       `xml:"CURDEF"`
+	BankID      string       `xml:"BANKACCTFROM>BANKID"`
+	AccountID   string       `xml:"BANKACCTFROM>ACCTID"`
+	AccountType string       `xml:"BANKACCTFROM>ACCTTYPE"`
+	DTStart     string       `xml:"BANKTRANLIST>DTSTART"`
+	DTEnd       string       `xml:"BANKTRANLIST>DTEND"`
+	Lines       []ofxLine    `xml:"BANKTRANLIST>STMTTRN"`
+	LedgerBal   ofxLedgerBal `xml:"LEDGERBAL"`
+}
+
+type ofxLine struct {
+	Type     string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	Amount   string `xml:"TRNAMT"`
+	FITID    string `xml:"FITID"`
+	Name     string `xml:"NAME,omitempty"`
+}
+
+type ofxLedgerBal struct {
+	Amount string `xml:"BALAMT"`
+	DTAsOf string `xml:"DTASOF"`
+}
+
+//Writes the statement as an OFX 2.2 bank statement response
+func WriteOFX(w io.Writer, statement db.StatementTxResult) error {
+	currency := statement.Account.Currency
+	now := ofxTime(time.Now().Unix())
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+
+	document := ofxDocument{
+		SignOn: ofxSignOn{Status: ok, DTServer: now, Language: "ENG"},
+		Bank: ofxStatements{
+			TrnUID: "0",
+			Status: ok,
+			Statement: ofxStatement{
+				Currency:    currency,
+				BankID:      bankID,
+				AccountID:   strconv.FormatInt(statement.Account.ID, 10),
+				AccountType: "CHECKING",
+				DTStart:     ofxTime(statement.FromTime),
+				DTEnd:       ofxTime(statement.ToTime),
+				Lines:       make([]ofxLine, len(statement.Lines)),
+				LedgerBal: ofxLedgerBal{
+					Amount: util.FormatAmount(statement.ClosingBalance, currency),
+					DTAsOf: ofxTime(statement.ToTime),
+				},
+			},
+		},
+	}
+	for i, line := range statement.Lines {
+		entry := ofxLine{
+			Type:     "CREDIT",
+			DTPosted: ofxTime(line.CreatedAt),
+			Amount:   util.FormatAmount(line.Amount, currency),
+			FITID:    strconv.FormatInt(line.ID, 10),
+		}
+		if line.Amount < 0 {
+			entry.Type = "DEBIT"
+		}
+		if line.CounterpartyAccountID != 0 {
+			entry.Name = counterpartyName(line)
+		}
+		document.Bank.Statement.Lines[i] = entry
+	}
+
+	if _, err := io.WriteString(w, xml.Header+ofxHeader+"\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(document)
+}
+
+func ofxTime(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(ofxTimeLayout)
+}
+
+func counterpartyName(line db.StatementLine) string {
+	if line.Amount < 0 {
+		return fmt.Sprintf("Transfer to account %d", line.CounterpartyAccountID)
+	}
+	return fmt.Sprintf("Transfer from account %d", line.CounterpartyAccountID)
+}
@@ -0,0 +1,150 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+)
+
+//Returned when the provider doesn't know one of the currencies
+var ErrRateUnavailable = errors.New("exchange rate is not available for this currency pair")
+
+//Returned when the rates are older than the configured max age, nothing is quoted from them
+var ErrQuoteUnavailable = errors.New("exchange rates are out of date, no quote is available")
+
+//Rates are used to convert money between accounts with different currencies.
+//A rate is the amount of the destination currency bought by one unit of the source currency
+type FXRateProvider interface {
+	Rate(ctx context.Context, from, to string) (*big.Rat, error)
+	//When the rates were published, zero when it isn't known
+	AsOf() time.Time
+}
+
+//Serves rates from a fixed table quoted against a single base currency, cross rates are derived from the base
+type tableRateProvider struct {
+	base  string
+	rates map[string]*big.Rat
+	asOf  time.Time
+}
+
+//Format of the rates file, every rate is the price of one unit of base in that currency
+type ratesFile struct {
+	Base  string            `json:"base"`
+	AsOf  time.Time         `json:"as_of"`
+	Rates map[string]string `json:"rates"`
+}
+
+//Creates a rate provider from a table of decimal rates quoted against base and published at asOf
+func NewTableRateProvider(base string, rates map[string]string, asOf time.Time) (FXRateProvider, error) {
+	provider := &tableRateProvider{asOf: asOf}
+	if err := provider.load(base, rates); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+//Creates a rate provider from a json file,
+//e.g. {"base": "USD", "as_of": "2023-01-02T15:04:05Z", "rates": {"EUR": "0.92"}}
+func NewFileRateProvider(path string) (FXRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rates file: %w", err)
+	}
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse rates file: %w", err)
+	}
+	return NewTableRateProvider(file.Base, file.Rates, file.AsOf)
+}
+
+func (provider *tableRateProvider) load(base string, rates map[string]string) error {
+	if len(base) == 0 {
+		return errors.New("base currency is required")
+	}
+	table := make(map[string]*big.Rat, len(rates)+1)
+	for currency, value := range rates {
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return fmt.Errorf("invalid rate [%s] for currency [%s]", value, currency)
+		}
+		table[currency] = rate
+	}
+	table[base] = big.NewRat(1, 1)
+
+	provider.base = base
+	provider.rates = table
+	return nil
+}
+
+func (provider *tableRateProvider) Rate(ctx context.Context, from, to string) (*big.Rat, error) {
+	fromRate, ok := provider.rates[from]
+	if !ok {
+		return nil, fmt.Errorf("%w: [%s]", ErrRateUnavailable, from)
+	}
+	toRate, ok := provider.rates[to]
+	if !ok {
+		return nil, fmt.Errorf("%w: [%s]", ErrRateUnavailable, to)
+	}
+	return new(big.Rat).Quo(toRate, fromRate), nil
+}
+
+func (provider *tableRateProvider) AsOf() time.Time {
+	return provider.asOf
+}
+
+//Refuses to serve rates older than maxAge
+type maxAgeRateProvider struct {
+	FXRateProvider
+	maxAge time.Duration
+}
+
+//Wraps a provider so rates are only served while they are younger than maxAge. Rates without
+//an as of time are never served since their age isn't known
+func NewMaxAgeRateProvider(provider FXRateProvider, maxAge time.Duration) FXRateProvider {
+	return &maxAgeRateProvider{FXRateProvider: provider, maxAge: maxAge}
+}
+
+func (provider *maxAgeRateProvider) Rate(ctx context.Context, from, to string) (*big.Rat, error) {
+	asOf := provider.AsOf()
+	if asOf.IsZero() || time.Since(asOf) > provider.maxAge {
+		return nil, ErrQuoteUnavailable
+	}
+	return provider.FXRateProvider.Rate(ctx, from, to)
+}
+
+//Converts an amount in minor units of from into minor units of to. The result is rounded down
+//so the bank never credits more than it debits
+func Convert(amount int64, from, to string, rate *big.Rat) (int64, error) {
+	value := new(big.Rat).Mul(big.NewRat(amount, 1), rate)
+	scale := util.MinorUnits(to) - util.MinorUnits(from)
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(scale))), nil)
+	if scale >= 0 {
+		value.Mul(value, new(big.Rat).SetInt(factor))
+	} else {
+		value.Quo(value, new(big.Rat).SetInt(factor))
+	}
+
+	result := new(big.Int).Quo(value.Num(), value.Denom())
+	if !result.IsInt64() {
+		return 0, errors.New("converted amount is too large")
+	}
+	return result.Int64(), nil
+}
+
+//Formats a rate as a decimal string that can be stored in a numeric column
+func FormatRate(rate *big.Rat) string {
+	return rate.FloatString(8)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,118 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testProvider(t *testing.T) FXRateProvider {
+	provider, err := NewTableRateProvider("USD", map[string]string{
+		"EUR": "0.5",
+		"CAD": "1.25",
+		"JPY": "150",
+	}, time.Now())
+	require.NoError(t, err)
+	return provider
+}
+
+func TestRate(t *testing.T) {
+	provider := testProvider(t)
+
+	rate, err := provider.Rate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, "0.50000000", FormatRate(rate))
+
+	//Cross rates are derived from the base currency
+	rate, err = provider.Rate(context.Background(), "EUR", "CAD")
+	require.NoError(t, err)
+	require.Equal(t, "2.50000000", FormatRate(rate))
+
+	rate, err = provider.Rate(context.Background(), "CAD", "CAD")
+	require.NoError(t, err)
+	require.Equal(t, "1.00000000", FormatRate(rate))
+
+	_, err = provider.Rate(context.Background(), "USD", "INR")
+	require.True(t, errors.Is(err, ErrRateUnavailable))
+}
+
+func TestInvalidRates(t *testing.T) {
+	_, err := NewTableRateProvider("", map[string]string{"EUR": "0.5"}, time.Now())
+	require.Error(t, err)
+
+	_, err = NewTableRateProvider("USD", map[string]string{"EUR": "abc"}, time.Now())
+	require.Error(t, err)
+
+	_, err = NewTableRateProvider("USD", map[string]string{"EUR": "-1"}, time.Now())
+	require.Error(t, err)
+}
+
+func TestFileRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"base": "USD", "as_of": "2023-01-02T15:04:05Z", "rates": {"EUR": "0.5"}}`), 0600)
+	require.NoError(t, err)
+
+	provider, err := NewFileRateProvider(path)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.January, 2, 15, 4, 5, 0, time.UTC), provider.AsOf().UTC())
+
+	rate, err := provider.Rate(context.Background(), "EUR", "USD")
+	require.NoError(t, err)
+	require.Equal(t, 0, rate.Cmp(big.NewRat(2, 1)))
+
+	_, err = NewFileRateProvider(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestMaxAgeRateProvider(t *testing.T) {
+	rates := map[string]string{"EUR": "0.5"}
+
+	fresh, err := NewTableRateProvider("USD", rates, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = NewMaxAgeRateProvider(fresh, time.Hour).Rate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+
+	stale, err := NewTableRateProvider("USD", rates, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	_, err = NewMaxAgeRateProvider(stale, time.Hour).Rate(context.Background(), "USD", "EUR")
+	require.ErrorIs(t, err, ErrQuoteUnavailable)
+
+	//Rates without an as of time have an unknown age
+	unknown, err := NewTableRateProvider("USD", rates, time.Time{})
+	require.NoError(t, err)
+	_, err = NewMaxAgeRateProvider(unknown, time.Hour).Rate(context.Background(), "USD", "EUR")
+	require.ErrorIs(t, err, ErrQuoteUnavailable)
+}
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		name   string
+		amount int64
+		from   string
+		to     string
+		rate   *big.Rat
+		result int64
+	}{
+		{name: "SameExponent", amount: 1000, from: "USD", to: "EUR", rate: big.NewRat(1, 2), result: 500},
+		{name: "RoundsDown", amount: 333, from: "USD", to: "EUR", rate: big.NewRat(1, 2), result: 166},
+		{name: "ToZeroExponent", amount: 1000, from: "USD", to: "JPY", rate: big.NewRat(150, 1), result: 1500},
+		{name: "FromZeroExponent", amount: 150, from: "JPY", to: "USD", rate: big.NewRat(1, 150), result: 100},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := Convert(testCase.amount, testCase.from, testCase.to, testCase.rate)
+			require.NoError(t, err)
+			require.Equal(t, testCase.result, result)
+		})
+	}
+
+	_, err := Convert(1<<62, "JPY", "USD", big.NewRat(1000, 1))
+	require.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package ledger
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+)
+
+//Result of a reconciliation run
+type Report struct {
+	//Accounts whose stored balance differs from the sum of their entries
+	Balances []db.ListBalanceDiscrepanciesRow `json:"balances"`
+	//Transactions whose entries don't match the transfer or don't net to zero
+	Transactions []db.Transaction `json:"transactions"`
+	//Entries of no transaction or adjustment, they are left out of the balances above
+	Orphans []db.Entry `json:"orphans"`
+	//Accounts whose balance was reset to the sum of their entries
+	Repaired []db.Account `json:"repaired"`
+}
+
+//Reports whether the ledger is consistent
+func (report Report) Clean() bool {
+	return len(report.Balances) == 0 && len(report.Transactions) == 0 && len(report.Orphans) == 0
+}
+
+//Checks every account balance against its entries and every transaction against its pair of entries.
+//When repair is set, drifted balances are recomputed from the entries, transactions and orphan
+//entries are only reported
+func Reconcile(ctx context.Context, store db.Store, repair bool) (Report, error) {
+	var report Report
+	var err error
+
+	report.Balances, err = store.ListBalanceDiscrepancies(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	report.Transactions, err = store.ListUnbalancedTransactions(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	report.Orphans, err = store.ListOrphanEntries(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	if !repair {
+		return report, nil
+	}
+	for _, discrepancy := range report.Balances {
+		account, err := store.RepairBalanceTx(ctx, discrepancy.AccountID)
+		if err != nil {
+			return report, err
+		}
+		report.Repaired = append(report.Repaired, account)
+	}
+	return report, nil
+}
+
+//Logs every discrepancy found in the report
+func LogReport(report Report) {
+	for _, discrepancy := range report.Balances {
+		log.Printf("account [%d] balance %d doesn't match entries total %d",
+			discrepancy.AccountID, discrepancy.Balance, discrepancy.EntriesTotal)
+	}
+	for _, transaction := range report.Transactions {
+		log.Printf("transaction [%d] doesn't match entries [%d] and [%d]",
+			transaction.ID, transaction.FromEntryID, transaction.ToEntryID)
+	}
+	for _, entry := range report.Orphans {
+		log.Printf("entry [%d] of %d on account [%d] belongs to no transaction",
+			entry.ID, entry.Amount, entry.AccountID)
+	}
+	for _, account := range report.Repaired {
+		log.Printf("account [%d] balance repaired to %d", account.ID, account.Balance)
+	}
+	if report.Clean() {
+		log.Printf("ledger is consistent")
+	}
+}
+
+//Runs Reconcile every interval until the context is cancelled
+func RunPeriodically(ctx context.Context, store db.Store, interval time.Duration, repair bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := Reconcile(ctx, store, repair)
+			if err != nil {
+				log.Printf("reconciliation failed: %v", err)
+				continue
+			}
+			LogReport(report)
+		}
+	}
+}
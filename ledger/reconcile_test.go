@@ -0,0 +1,111 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	discrepancy := db.ListBalanceDiscrepanciesRow{AccountID: 4, Balance: 120, EntriesTotal: 100}
+	transaction := db.Transaction{ID: 9, FromAccountID: 4, ToAccountID: 5, FromEntryID: 17, ToEntryID: 18, Amount: 10}
+	orphan := db.Entry{ID: 21, AccountID: 4, Amount: -10}
+
+	testCases := []struct {
+		name        string
+		repair      bool
+		buildStubs  func(store *mock_db.MockStore)
+		checkResult func(t *testing.T, report Report, err error)
+	}{
+		{
+			name: "Clean",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListBalanceDiscrepancies(gomock.Any()).Times(1).Return([]db.ListBalanceDiscrepanciesRow{}, nil)
+				store.EXPECT().ListUnbalancedTransactions(gomock.Any()).Times(1).Return([]db.Transaction{}, nil)
+				store.EXPECT().ListOrphanEntries(gomock.Any()).Times(1).Return([]db.Entry{}, nil)
+				store.EXPECT().RepairBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResult: func(t *testing.T, report Report, err error) {
+				require.NoError(t, err)
+				require.True(t, report.Clean())
+			},
+		},
+		{
+			name: "ReportOnly",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListBalanceDiscrepancies(gomock.Any()).Times(1).Return([]db.ListBalanceDiscrepanciesRow{discrepancy}, nil)
+				store.EXPECT().ListUnbalancedTransactions(gomock.Any()).Times(1).Return([]db.Transaction{transaction}, nil)
+				store.EXPECT().ListOrphanEntries(gomock.Any()).Times(1).Return([]db.Entry{}, nil)
+				store.EXPECT().RepairBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResult: func(t *testing.T, report Report, err error) {
+				require.NoError(t, err)
+				require.False(t, report.Clean())
+				require.Equal(t, []db.ListBalanceDiscrepanciesRow{discrepancy}, report.Balances)
+				require.Equal(t, []db.Transaction{transaction}, report.Transactions)
+				require.Empty(t, report.Repaired)
+			},
+		},
+		{
+			name:   "Repair",
+			repair: true,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListBalanceDiscrepancies(gomock.Any()).Times(1).Return([]db.ListBalanceDiscrepanciesRow{discrepancy}, nil)
+				store.EXPECT().ListUnbalancedTransactions(gomock.Any()).Times(1).Return([]db.Transaction{}, nil)
+				store.EXPECT().ListOrphanEntries(gomock.Any()).Times(1).Return([]db.Entry{}, nil)
+				store.EXPECT().RepairBalanceTx(gomock.Any(), gomock.Eq(discrepancy.AccountID)).Times(1).
+					Return(db.Account{ID: discrepancy.AccountID, Balance: discrepancy.EntriesTotal}, nil)
+			},
+			checkResult: func(t *testing.T, report Report, err error) {
+				require.NoError(t, err)
+				require.Len(t, report.Repaired, 1)
+				require.Equal(t, discrepancy.EntriesTotal, report.Repaired[0].Balance)
+			},
+		},
+		{
+			name:   "OrphanEntries",
+			repair: true,
+			buildStubs: func(store *mock_db.MockStore) {
+				//The orphan is left out of the totals, so the balance doesn't drift because of it
+				store.EXPECT().ListBalanceDiscrepancies(gomock.Any()).Times(1).Return([]db.ListBalanceDiscrepanciesRow{}, nil)
+				store.EXPECT().ListUnbalancedTransactions(gomock.Any()).Times(1).Return([]db.Transaction{}, nil)
+				store.EXPECT().ListOrphanEntries(gomock.Any()).Times(1).Return([]db.Entry{orphan}, nil)
+				store.EXPECT().RepairBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResult: func(t *testing.T, report Report, err error) {
+				require.NoError(t, err)
+				require.False(t, report.Clean())
+				require.Equal(t, []db.Entry{orphan}, report.Orphans)
+				require.Empty(t, report.Repaired)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListBalanceDiscrepancies(gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+				store.EXPECT().ListUnbalancedTransactions(gomock.Any()).Times(0)
+			},
+			checkResult: func(t *testing.T, report Report, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			store := mock_db.NewMockStore(controller)
+			testCase.buildStubs(store)
+
+			report, err := Reconcile(context.Background(), store, testCase.repair)
+			testCase.checkResult(t, report, err)
+		})
+	}
+}
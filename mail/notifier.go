@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+//Sends users the tokens they need to prove who they are. Handlers only depend on this,
+//so another channel such as sms can be plugged in without touching them
+type Notifier interface {
+	PasswordReset(ctx context.Context, to string, token string, expiresIn time.Duration) error
+	EmailVerification(ctx context.Context, to string, token string, expiresIn time.Duration) error
+	EmailChanged(ctx context.Context, to string, newEmail string) error
+}
+
+//Delivers notifications as plain text mail
+type mailNotifier struct {
+	sender Sender
+}
+
+func NewNotifier(sender Sender) Notifier {
+	return &mailNotifier{sender: sender}
+}
+
+func (notifier *mailNotifier) PasswordReset(ctx context.Context, to string, token string, expiresIn time.Duration) error {
+	return notifier.sender.Send(ctx, Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this token to reset your password, it expires in %s and can only be used once:\n\n%s\n\nIf you didn't ask for a reset you can ignore this mail.",
+			expiresIn, token),
+	})
+}
+
+func (notifier *mailNotifier) EmailVerification(ctx context.Context, to string, token string, expiresIn time.Duration) error {
+	return notifier.sender.Send(ctx, Message{
+		To:      to,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Use this token to verify your email address, it expires in %s:\n\n%s\n\nIf you didn't sign up you can ignore this mail.",
+			expiresIn, token),
+	})
+}
+
+
+//Sent to the old address so the owner finds out if someone else moved their account to another email
+func (notifier *mailNotifier) EmailChanged(ctx context.Context, to string, newEmail string) error {
+	return notifier.sender.Send(ctx, Message{
+		To:      to,
+		Subject: "Your email was changed",
+		Body: fmt.Sprintf("The email address of your account was changed to %s.\n\nIf you didn't make this change, contact support straight away.",
+			newEmail),
+	})
+}
\ No newline at end of file
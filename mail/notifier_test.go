@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSender struct {
+	messages []Message
+}
+
+func (sender *recordingSender) Send(ctx context.Context, msg Message) error {
+	sender.messages = append(sender.messages, msg)
+	return nil
+}
+
+func TestNotifier(t *testing.T) {
+	sender := &recordingSender{}
+	notifier := NewNotifier(sender)
+
+	require.NoError(t, notifier.PasswordReset(context.Background(), "user@example.com", "reset-token", 30*time.Minute))
+	require.NoError(t, notifier.EmailVerification(context.Background(), "user@example.com", "verify-token", 24*time.Hour))
+	require.NoError(t, notifier.EmailChanged(context.Background(), "user@example.com", "new@example.com"))
+
+	require.Len(t, sender.messages, 3)
+	require.Equal(t, "user@example.com", sender.messages[0].To)
+	require.Contains(t, sender.messages[0].Body, "\nreset-token\n")
+	require.Contains(t, sender.messages[0].Body, "30m0s")
+	require.Contains(t, sender.messages[1].Body, "\nverify-token\n")
+	require.NotEqual(t, sender.messages[0].Subject, sender.messages[1].Subject)
+	require.Equal(t, "user@example.com", sender.messages[2].To)
+	require.Contains(t, sender.messages[2].Body, "new@example.com")
+}
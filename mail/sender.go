@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+//A plain text mail to a single user
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+//Delivers mail to users. A real provider can be plugged in behind a Notifier, the senders
+//here are meant for local use
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+//Writes every mail to the log
+type logSender struct{}
+
+func NewLogSender() Sender {
+	return logSender{}
+}
+
+func (logSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+//Writes every mail to its own file in a directory, so links and tokens can be copied out of them
+type fileSender struct {
+	dir   string
+	count uint64
+}
+
+func NewFileSender(dir string) (Sender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create mail directory: %w", err)
+	}
+	return &fileSender{dir: dir}, nil
+}
+
+var unsafeFileName = regexp.MustCompile(`[^A-Za-z0-9@._-]`)
+
+func (sender *fileSender) Send(ctx context.Context, msg Message) error {
+	//The counter keeps names unique when two mails are sent in the same nanosecond
+	name := fmt.Sprintf("%d-%d-%s.eml", time.Now().UnixNano(), atomic.AddUint64(&sender.count, 1), unsafeFileName.ReplaceAllString(msg.To, "_"))
+	var content strings.Builder
+	fmt.Fprintf(&content, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&content, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&content, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	content.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	content.WriteString(msg.Body)
+	return os.WriteFile(filepath.Join(sender.dir, name), []byte(content.String()), 0o600)
+}
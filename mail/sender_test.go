@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	sender, err := NewFileSender(dir)
+	require.NoError(t, err)
+
+	msg := Message{
+		To:      "user/name@example.com",
+		Subject: "Reset your password",
+		Body:    "Your token is abc",
+	}
+	require.NoError(t, sender.Send(context.Background(), msg))
+	require.NoError(t, sender.Send(context.Background(), msg))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.NotContains(t, files[0].Name(), "/")
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: user/name@example.com\r\n")
+	require.Contains(t, string(data), "Subject: Reset your password\r\n")
+	require.Contains(t, string(data), "\r\n\r\nYour token is abc")
+}
+
+func TestLogSender(t *testing.T) {
+	require.NoError(t, NewLogSender().Send(context.Background(), Message{To: "user@example.com"}))
+}
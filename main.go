@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"log"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/faisal-a-n/simplebank/api"
 	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/faisal-a-n/simplebank/ledger"
+	"github.com/faisal-a-n/simplebank/scheduler"
 	"github.com/faisal-a-n/simplebank/util"
 	_ "github.com/lib/pq"
 )
@@ -15,18 +22,196 @@ func main() {
 	if err != nil {
 		log.Fatalf("Coudln't load config %v", err)
 	}
-
 	conn, err := sql.Open(config.DB_DRIVER, config.DB_SOURCE)
 	if err != nil {
 		log.Fatalf("Coudln't connect to db %v", err.Error())
 	}
 
 	store := db.NewStore(conn)
+
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		runReconcile(store, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "currency" {
+		runCurrency(store, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "role" {
+		runRole(store, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		runKeys(store, config, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		runAudit(store, os.Args[2:])
+		return
+	}
+
+	//Fall back to the built in currencies when the table can't be read
+	if err := api.LoadCurrencies(context.Background(), store); err != nil {
+		log.Printf("Couldn't load currencies %v", err.Error())
+	}
+	if config.CURRENCY_REFRESH > 0 {
+		go api.WatchCurrencies(context.Background(), store, config.CURRENCY_REFRESH)
+	}
+
+	if config.RECONCILE_INTERVAL > 0 {
+		go ledger.RunPeriodically(context.Background(), store, config.RECONCILE_INTERVAL, config.RECONCILE_REPAIR)
+	}
+	if config.IDEMPOTENCY_KEY_TTL > 0 && config.IDEMPOTENCY_CLEANUP_INTERVAL > 0 {
+		go api.ExpireIdempotencyKeys(context.Background(), store, config.IDEMPOTENCY_KEY_TTL, config.IDEMPOTENCY_CLEANUP_INTERVAL)
+	}
+	if config.SCHEDULER_INTERVAL > 0 {
+		transferScheduler := scheduler.New(store, scheduler.RetryPolicy{
+			MaxRetries: config.SCHEDULER_MAX_RETRIES,
+			Delay:      config.SCHEDULER_RETRY_DELAY,
+		})
+		go transferScheduler.Run(context.Background(), config.SCHEDULER_INTERVAL)
+	}
+
 	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatalf("Coudln't create server %v", err.Error())
 	}
+	//Keep signing with the config key when the table can't be read
+	if err := server.LoadKeys(context.Background()); err != nil {
+		log.Printf("Couldn't load signing keys %v", err.Error())
+	}
+	if config.TOKEN_KEY_REFRESH > 0 {
+		go server.WatchKeys(context.Background(), config.TOKEN_KEY_REFRESH)
+	}
 	if err = server.Start(config.PORT); err != nil {
 		log.Fatalf("Coudln't start server %v", err.Error())
 	}
 }
+
+//Checks the ledger once and exits with a non zero code if discrepancies are left
+func runReconcile(store db.Store, args []string) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "reset drifted account balances to the sum of their entries")
+	flags.Parse(args)
+
+	report, err := ledger.Reconcile(context.Background(), store, *repair)
+	if err != nil {
+		log.Fatalf("Couldn't reconcile ledger %v", err.Error())
+	}
+	ledger.LogReport(report)
+	if len(report.Transactions) > 0 || len(report.Orphans) > 0 || (len(report.Balances) > 0 && !*repair) {
+		os.Exit(1)
+	}
+}
+
+//Lists, enables or disables currencies, running servers pick the change up on their next refresh
+func runCurrency(store db.Store, args []string) {
+	if len(args) == 0 || args[0] == "list" {
+		currencies, err := store.ListCurrencies(context.Background())
+		if err != nil {
+			log.Fatalf("Couldn't list currencies %v", err.Error())
+		}
+		for _, currency := range currencies {
+			log.Printf("%s %s exponent=%d enabled=%t", currency.Code, currency.NumericCode, currency.Exponent, currency.Enabled)
+		}
+		return
+	}
+	if len(args) != 2 || (args[0] != "enable" && args[0] != "disable") {
+		log.Fatalf("Usage: currency [list | enable CODE | disable CODE]")
+	}
+
+	currency, err := store.SetCurrencyEnabled(context.Background(), db.SetCurrencyEnabledParams{
+		Code:      strings.ToUpper(args[1]),
+		Enabled:   args[0] == "enable",
+		UpdatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		log.Fatalf("Couldn't update currency %v", err.Error())
+	}
+	log.Printf("%s enabled=%t", currency.Code, currency.Enabled)
+}
+
+//Sets a user's role, which is how the first admin is created. It applies from the user's next login or refresh
+func runRole(store db.Store, args []string) {
+	if len(args) != 2 || !util.IsSupportedRole(args[1]) {
+		log.Fatalf("Usage: role EMAIL customer|support|admin")
+	}
+	user, err := store.GetUserByEmail(context.Background(), args[0])
+	if err != nil {
+		log.Fatalf("Couldn't find user %v", err.Error())
+	}
+	user, err = store.UpdateUserRole(context.Background(), db.UpdateUserRoleParams{
+		Role: args[1],
+		ID:   user.ID,
+	})
+	if err != nil {
+		log.Fatalf("Couldn't update role %v", err.Error())
+	}
+	log.Printf("%s role=%s", user.Email, user.Role)
+}
+
+//Lists, adds, retires or encrypts token signing keys. A new key signs only after DELAY, which defaults to
+//twice the key refresh so every running server can verify its tokens before it is used
+func runKeys(store db.Store, config util.Config, args []string) {
+	tokenType := config.TOKEN_TYPE
+	if len(tokenType) == 0 {
+		tokenType = "paseto"
+	}
+	if len(args) == 0 || args[0] == "list" {
+		keys, err := store.ListSigningKeys(context.Background(), tokenType)
+		if err != nil {
+			log.Fatalf("Couldn't list signing keys %v", err.Error())
+		}
+		for _, key := range keys {
+			log.Printf("%s not_before=%s retired=%t", key.ID, time.Unix(key.NotBefore, 0).Format(time.RFC3339), key.RetiredAt > 0)
+		}
+		return
+	}
+
+	switch {
+	case args[0] == "add" && len(args) <= 2:
+		delay := 2 * config.TOKEN_KEY_REFRESH
+		if len(args) == 2 {
+			var err error
+			delay, err = time.ParseDuration(args[1])
+			if err != nil {
+				log.Fatalf("Invalid delay %v", err.Error())
+			}
+		}
+		key, err := api.AddSigningKey(context.Background(), store, config, delay)
+		if err != nil {
+			log.Fatalf("Couldn't add signing key %v", err.Error())
+		}
+		log.Printf("%s signs from %s", key.ID, time.Unix(key.NotBefore, 0).Format(time.RFC3339))
+	case args[0] == "retire" && len(args) == 2:
+		key, err := api.RetireSigningKey(context.Background(), store, config, args[1])
+		if err != nil {
+			log.Fatalf("Couldn't retire signing key %v", err.Error())
+		}
+		log.Printf("%s retired", key.ID)
+	case args[0] == "encrypt" && len(args) == 1:
+		converted, err := api.EncryptSigningKeys(context.Background(), store, config)
+		if err != nil {
+			log.Fatalf("Couldn't encrypt signing keys %v", err.Error())
+		}
+		log.Printf("encrypted=%d", converted)
+	default:
+		log.Fatalf("Usage: keys [list | add [DELAY] | retire ID | encrypt]")
+	}
+}
+
+//Walks the audit log hash chain and exits with a non zero code if a row was changed or removed
+func runAudit(store db.Store, args []string) {
+	if len(args) != 1 || args[0] != "verify" {
+		log.Fatalf("Usage: audit verify")
+	}
+	result, err := db.VerifyAuditLog(context.Background(), store)
+	if err != nil {
+		log.Fatalf("Couldn't verify audit log %v", err.Error())
+	}
+	log.Printf("checked=%d unchained=%d", result.Checked, result.Unchained)
+	if !result.Valid() {
+		log.Printf("chain is broken at row %d", result.BrokenAt)
+		os.Exit(1)
+	}
+}
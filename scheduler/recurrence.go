@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+const maxInterval = 366
+
+//A subset of RFC 5545 RRULE: FREQ, INTERVAL and BYMONTHDAY, e.g. FREQ=MONTHLY;BYMONTHDAY=1
+//pays on the first of every month. Occurrences are computed in UTC
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByMonthDay int
+}
+
+//Parses a recurrence rule, an optional RRULE: prefix is accepted
+func ParseRule(value string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+	if len(value) == 0 {
+		return rule, errors.New("recurrence rule is empty")
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("invalid recurrence rule part [%s]", part)
+		}
+		switch key {
+		case "FREQ":
+			rule.Freq = val
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 || interval > maxInterval {
+				return rule, fmt.Errorf("invalid INTERVAL [%s]", val)
+			}
+			rule.Interval = interval
+		case "BYMONTHDAY":
+			day, err := strconv.Atoi(val)
+			if err != nil || day < 1 || day > 31 {
+				return rule, fmt.Errorf("invalid BYMONTHDAY [%s]", val)
+			}
+			rule.ByMonthDay = day
+		default:
+			return rule, fmt.Errorf("unsupported recurrence rule part [%s]", key)
+		}
+	}
+
+	switch rule.Freq {
+	case Daily, Weekly:
+		if rule.ByMonthDay != 0 {
+			return rule, errors.New("BYMONTHDAY can only be used with MONTHLY or YEARLY")
+		}
+	case Monthly, Yearly:
+	default:
+		return rule, fmt.Errorf("unsupported FREQ [%s]", rule.Freq)
+	}
+	return rule, nil
+}
+
+//Returns the first occurrence on or after start. With BYMONTHDAY that is the day in the start
+//month, or in the month after when it has already passed. Days past the end of a month fall on
+//its last day, the same as in Next
+func (rule Rule) First(start time.Time) time.Time {
+	start = start.UTC()
+	if (rule.Freq != Monthly && rule.Freq != Yearly) || rule.ByMonthDay == 0 {
+		return start
+	}
+	first := time.Date(start.Year(), start.Month(), 1, start.Hour(), start.Minute(), start.Second(), 0, time.UTC)
+	occurrence := rule.onDay(first)
+	if occurrence.Before(start) {
+		occurrence = rule.onDay(first.AddDate(0, 1, 0))
+	}
+	return occurrence
+}
+
+//Pins the day of month to the first occurrence so short months don't shift later payments
+func (rule Rule) Anchor(start time.Time) Rule {
+	if (rule.Freq == Monthly || rule.Freq == Yearly) && rule.ByMonthDay == 0 {
+		rule.ByMonthDay = start.UTC().Day()
+	}
+	return rule
+}
+
+//Returns the occurrence after t. Days past the end of a month fall on its last day
+func (rule Rule) Next(t time.Time) time.Time {
+	t = t.UTC()
+	switch rule.Freq {
+	case Daily:
+		return t.AddDate(0, 0, rule.Interval)
+	case Weekly:
+		return t.AddDate(0, 0, 7*rule.Interval)
+	}
+
+	months := rule.Interval
+	if rule.Freq == Yearly {
+		months *= 12
+	}
+	//Move from the first of the month so AddDate can't overflow into the month after
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), 0, time.UTC).AddDate(0, months, 0)
+	if rule.ByMonthDay == 0 {
+		rule.ByMonthDay = t.Day()
+	}
+	return rule.onDay(first)
+}
+
+//Moves the first of a month to the rule's day, or to the last day when the month is shorter
+func (rule Rule) onDay(first time.Time) time.Time {
+	day := rule.ByMonthDay
+	if last := daysIn(first.Year(), first.Month()); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+func (rule Rule) String() string {
+	parts := []string{"FREQ=" + rule.Freq}
+	if rule.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rule.Interval))
+	}
+	if rule.ByMonthDay > 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(rule.ByMonthDay))
+	}
+	return strings.Join(parts, ";")
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
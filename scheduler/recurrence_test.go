@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("RRULE:freq=monthly;interval=2;bymonthday=31")
+	require.NoError(t, err)
+	require.Equal(t, Rule{Freq: Monthly, Interval: 2, ByMonthDay: 31}, rule)
+	require.Equal(t, "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=31", rule.String())
+
+	rule, err = ParseRule("FREQ=WEEKLY")
+	require.NoError(t, err)
+	require.Equal(t, Rule{Freq: Weekly, Interval: 1}, rule)
+
+	for _, value := range []string{
+		"",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;INTERVAL=0",
+		"FREQ=MONTHLY;COUNT=3",
+		"FREQ",
+	} {
+		_, err := ParseRule(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestRuleNext(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     Rule
+		from     time.Time
+		expected time.Time
+	}{
+		{"Daily", Rule{Freq: Daily, Interval: 1}, date(2022, 12, 31), date(2023, 1, 1)},
+		{"Weekly", Rule{Freq: Weekly, Interval: 2}, date(2023, 1, 2), date(2023, 1, 16)},
+		{"Monthly", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 1}, date(2023, 1, 1), date(2023, 2, 1)},
+		{"MonthEnd", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 31}, date(2023, 1, 31), date(2023, 2, 28)},
+		{"MonthEndRecovers", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 31}, date(2023, 2, 28), date(2023, 3, 31)},
+		{"Quarterly", Rule{Freq: Monthly, Interval: 3, ByMonthDay: 15}, date(2023, 11, 15), date(2024, 2, 15)},
+		{"LeapDay", Rule{Freq: Yearly, Interval: 1, ByMonthDay: 29}, date(2024, 2, 29), date(2025, 2, 28)},
+		{"Day29February", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 29}, date(2023, 1, 29), date(2023, 2, 28)},
+		{"Day29LeapFebruary", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 29}, date(2024, 1, 29), date(2024, 2, 29)},
+		{"Day29Recovers", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 29}, date(2023, 2, 28), date(2023, 3, 29)},
+		{"Day30February", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 30}, date(2024, 1, 30), date(2024, 2, 29)},
+		{"Day30Recovers", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 30}, date(2024, 2, 29), date(2024, 3, 30)},
+		{"Day31ShortMonth", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 31}, date(2023, 3, 31), date(2023, 4, 30)},
+		{"Day31LeapFebruary", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 31}, date(2024, 1, 31), date(2024, 2, 29)},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, testCase.rule.Next(testCase.from))
+		})
+	}
+}
+
+func TestRuleFirst(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     Rule
+		start    time.Time
+		expected time.Time
+	}{
+		{"NoMonthDay", Rule{Freq: Monthly, Interval: 1}, date(2023, 1, 20), date(2023, 1, 20)},
+		{"Daily", Rule{Freq: Daily, Interval: 1}, date(2023, 1, 20), date(2023, 1, 20)},
+		{"OnTheDay", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 15}, date(2023, 1, 15), date(2023, 1, 15)},
+		{"LaterThisMonth", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 15}, date(2023, 1, 10), date(2023, 1, 15)},
+		{"PassedThisMonth", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 15}, date(2023, 1, 20), date(2023, 2, 15)},
+		{"PassedThisYear", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 1}, date(2023, 12, 2), date(2024, 1, 1)},
+		{"KeepsTimeOfDay", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 15}, date(2023, 1, 10).Add(time.Hour), date(2023, 1, 15).Add(time.Hour)},
+		{"Day29February", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 29}, date(2023, 2, 10), date(2023, 2, 28)},
+		{"Day29LeapFebruary", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 29}, date(2024, 2, 10), date(2024, 2, 29)},
+		{"Day29PassedIntoFebruary", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 29}, date(2023, 1, 30), date(2023, 2, 28)},
+		{"Day30February", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 30}, date(2023, 2, 1), date(2023, 2, 28)},
+		{"Day30ShortMonth", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 30}, date(2023, 4, 30), date(2023, 4, 30)},
+		{"Day31February", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 31}, date(2023, 2, 28), date(2023, 2, 28)},
+		{"Day31ShortMonth", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 31}, date(2023, 4, 1), date(2023, 4, 30)},
+		{"Day31LongMonth", Rule{Freq: Monthly, Interval: 1, ByMonthDay: 31}, date(2023, 3, 1), date(2023, 3, 31)},
+		{"YearlyPassed", Rule{Freq: Yearly, Interval: 1, ByMonthDay: 29}, date(2024, 1, 30), date(2024, 2, 29)},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			first := testCase.rule.First(testCase.start)
+			require.Equal(t, testCase.expected, first)
+			require.False(t, first.Before(testCase.start))
+		})
+	}
+}
+
+func TestRuleAnchor(t *testing.T) {
+	rule := Rule{Freq: Monthly, Interval: 1}.Anchor(date(2023, 1, 31))
+	require.Equal(t, 31, rule.ByMonthDay)
+
+	rule = Rule{Freq: Weekly, Interval: 1}.Anchor(date(2023, 1, 31))
+	require.Zero(t, rule.ByMonthDay)
+}
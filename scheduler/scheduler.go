@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+)
+
+const (
+	defaultLease     = 5 * time.Minute
+	defaultBatchSize = 50
+)
+
+//How often a scheduled transfer is retried when the account doesn't have enough balance.
+//The delay doubles after every failed attempt
+type RetryPolicy struct {
+	MaxRetries int
+	Delay      time.Duration
+}
+
+//Returns when to retry after the given failed attempt, or 0 when no retries are left
+func (policy RetryPolicy) retryAt(attempt int32, now time.Time) int64 {
+	if int(attempt) > policy.MaxRetries || policy.Delay <= 0 {
+		return 0
+	}
+	return now.Add(policy.Delay << (attempt - 1)).Unix()
+}
+
+//Executes due scheduled transfers. Every replica can run a scheduler, transfers are claimed
+//with a lease in Postgres so each occurrence is executed by a single replica
+type Scheduler struct {
+	store     db.Store
+	policy    RetryPolicy
+	lease     time.Duration
+	batchSize int32
+	now       func() time.Time
+}
+
+func New(store db.Store, policy RetryPolicy) *Scheduler {
+	return &Scheduler{
+		store:     store,
+		policy:    policy,
+		lease:     defaultLease,
+		batchSize: defaultBatchSize,
+		now:       time.Now,
+	}
+}
+
+//Claims one batch of due transfers and executes them, returns how many were executed
+func (scheduler *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	now := scheduler.now()
+	lockedUntil := now.Add(scheduler.lease).Unix()
+	due, err := scheduler.store.ClaimDueScheduledTransfers(ctx, db.ClaimDueScheduledTransfersParams{
+		LockedUntil: lockedUntil,
+		Now:         now.Unix(),
+		Count:       scheduler.batchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	executed := 0
+	for _, scheduled := range due {
+		result, err := scheduler.store.RunScheduledTransferTx(ctx, db.RunScheduledTransferTxParams{
+			ID:              scheduled.ID,
+			LockedUntil:     lockedUntil,
+			RunAt:           now.Unix(),
+			NextScheduledAt: nextOccurrence(scheduled, now),
+			RetryAt:         scheduler.policy.retryAt(scheduled.Attempts+1, now),
+		})
+		if err != nil {
+			if !errors.Is(err, db.ErrScheduleLeaseLost) {
+				log.Printf("scheduled transfer [%d] failed: %v", scheduled.ID, err)
+			}
+			continue
+		}
+		executed++
+		if result.Run.Status != db.ScheduledRunSucceeded {
+			log.Printf("scheduled transfer [%d] %s: %s", scheduled.ID, result.Run.Status, result.Run.Error)
+		}
+	}
+	return executed, nil
+}
+
+//Runs the scheduler until the context is cancelled
+func (scheduler *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			//Keep going while full batches come back so a backlog is cleared in one tick
+			for {
+				executed, err := scheduler.RunOnce(ctx)
+				if err != nil {
+					log.Printf("scheduler failed: %v", err)
+				}
+				if err != nil || executed < int(scheduler.batchSize) {
+					break
+				}
+			}
+		}
+	}
+}
+
+//Returns the occurrence after the current one, or 0 when the transfer doesn't repeat any more.
+//Occurrences missed while no scheduler was running are skipped instead of being paid all at once
+func nextOccurrence(scheduled db.ScheduledTransfer, now time.Time) int64 {
+	if len(scheduled.Recurrence) == 0 {
+		return 0
+	}
+	rule, err := ParseRule(scheduled.Recurrence)
+	if err != nil {
+		log.Printf("scheduled transfer [%d] has an invalid recurrence: %v", scheduled.ID, err)
+		return 0
+	}
+
+	next := rule.Next(time.Unix(scheduled.ScheduledAt, 0))
+	for !next.After(now) {
+		next = rule.Next(next)
+	}
+	if scheduled.EndAt > 0 && next.Unix() > scheduled.EndAt {
+		return 0
+	}
+	return next.Unix()
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mock_db "github.com/faisal-a-n/simplebank/db/mock"
+	db "github.com/faisal-a-n/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	now := date(2023, 1, 1)
+	policy := RetryPolicy{MaxRetries: 2, Delay: time.Hour}
+
+	require.Equal(t, now.Add(time.Hour).Unix(), policy.retryAt(1, now))
+	require.Equal(t, now.Add(2*time.Hour).Unix(), policy.retryAt(2, now))
+	require.Zero(t, policy.retryAt(3, now))
+	require.Zero(t, RetryPolicy{}.retryAt(1, now))
+}
+
+func TestNextOccurrence(t *testing.T) {
+	now := date(2023, 3, 10)
+	scheduled := db.ScheduledTransfer{
+		Recurrence:  "FREQ=MONTHLY;BYMONTHDAY=1",
+		ScheduledAt: date(2023, 3, 1).Unix(),
+	}
+	require.Equal(t, date(2023, 4, 1).Unix(), nextOccurrence(scheduled, now))
+
+	//Missed occurrences are skipped
+	scheduled.ScheduledAt = date(2022, 12, 1).Unix()
+	require.Equal(t, date(2023, 4, 1).Unix(), nextOccurrence(scheduled, now))
+
+	scheduled.EndAt = date(2023, 3, 31).Unix()
+	require.Zero(t, nextOccurrence(scheduled, now))
+
+	scheduled.Recurrence = ""
+	require.Zero(t, nextOccurrence(scheduled, now))
+}
+
+func TestRunOnce(t *testing.T) {
+	now := date(2023, 3, 1)
+	lockedUntil := now.Add(defaultLease).Unix()
+	monthly := db.ScheduledTransfer{
+		ID:          1,
+		Recurrence:  "FREQ=MONTHLY;BYMONTHDAY=1",
+		ScheduledAt: now.Unix(),
+		Status:      db.ScheduledTransferActive,
+	}
+	retried := db.ScheduledTransfer{
+		ID:          2,
+		ScheduledAt: now.Add(-time.Hour).Unix(),
+		Attempts:    1,
+		Status:      db.ScheduledTransferActive,
+	}
+
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	store.EXPECT().ClaimDueScheduledTransfers(gomock.Any(), gomock.Eq(db.ClaimDueScheduledTransfersParams{
+		LockedUntil: lockedUntil,
+		Now:         now.Unix(),
+		Count:       defaultBatchSize,
+	})).Times(1).Return([]db.ScheduledTransfer{monthly, retried}, nil)
+	store.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Eq(db.RunScheduledTransferTxParams{
+		ID:              monthly.ID,
+		LockedUntil:     lockedUntil,
+		RunAt:           now.Unix(),
+		NextScheduledAt: date(2023, 4, 1).Unix(),
+		RetryAt:         now.Add(time.Minute).Unix(),
+	})).Times(1).Return(db.RunScheduledTransferTxResult{
+		Run: db.ScheduledTransferRun{Status: db.ScheduledRunSucceeded},
+	}, nil)
+	store.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Eq(db.RunScheduledTransferTxParams{
+		ID:          retried.ID,
+		LockedUntil: lockedUntil,
+		RunAt:       now.Unix(),
+		RetryAt:     0,
+	})).Times(1).Return(db.RunScheduledTransferTxResult{}, db.ErrScheduleLeaseLost)
+
+	scheduler := New(store, RetryPolicy{MaxRetries: 1, Delay: time.Minute})
+	scheduler.now = func() time.Time { return now }
+
+	executed, err := scheduler.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, executed)
+}
+
+func TestRunOnceClaimError(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	store := mock_db.NewMockStore(controller)
+	store.EXPECT().ClaimDueScheduledTransfers(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+	store.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := New(store, RetryPolicy{}).RunOnce(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
@@ -6,30 +6,46 @@ import (
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 )
 
 const minSecretKeySize = 32
 
 //JWT Maker
 type JWTMaker struct {
-	secretKey string
+	keys keyring
 }
 
 //Creates jwt token
 func NewJWTMaker(secret string) (Maker, error) {
-	if len(secret) < minSecretKeySize {
-		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
+	maker := &JWTMaker{}
+	if err := maker.SetKeys([]Key{{ID: LegacyKeyID, Secret: []byte(secret)}}); err != nil {
+		return nil, err
 	}
-	return &JWTMaker{secret}, nil
+	return maker, nil
 }
 
-func (maker *JWTMaker) CreateToken(userID int64, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(userID, duration)
+func (maker *JWTMaker) SetKeys(keys []Key) error {
+	return maker.keys.set(keys, func(key Key) error {
+		if len(key.Secret) < minSecretKeySize {
+			return fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
+		}
+		return nil
+	})
+}
+
+func (maker *JWTMaker) CreateToken(userID int64, sessionID uuid.UUID, role string, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(userID, sessionID, role, scopes, duration)
+	if err != nil {
+		return "", nil, err
+	}
+	key, err := maker.keys.signingKey(payload.IssuedAt)
 	if err != nil {
 		return "", nil, err
 	}
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	token, err := jwtToken.SignedString([]byte(maker.secretKey))
+	jwtToken.Header["kid"] = key.ID
+	token, err := jwtToken.SignedString(key.Secret)
 	if err != nil {
 		return "", nil, err
 	}
@@ -42,7 +58,11 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		if !ok {
 			return nil, ERR_INVALID_TOKEN
 		}
-		return []byte(maker.secretKey), nil
+		key, ok := maker.keys.key(headerKeyID(token))
+		if !ok {
+			return nil, ERR_INVALID_TOKEN
+		}
+		return key.Secret, nil
 	}
 
 	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
@@ -60,3 +80,9 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	}
 	return payload, nil
 }
+
+//Tokens issued before key rotation have no kid header
+func headerKeyID(token *jwt.Token) string {
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
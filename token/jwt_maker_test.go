@@ -6,6 +6,7 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/faisal-a-n/simplebank/util"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -14,11 +15,13 @@ func TestJWTMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	id := util.GenerateRandomInt(1000, 1)
+	sessionID := uuid.New()
+	scopes := util.RoleScopes(util.RoleCustomer)
 	duration := time.Minute
 	issued_at := time.Now()
 	expires_at := time.Now().Add(time.Minute)
 
-	token, _, err := maker.CreateToken(id, duration)
+	token, _, err := maker.CreateToken(id, sessionID, util.RoleCustomer, scopes, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
@@ -27,6 +30,10 @@ func TestJWTMaker(t *testing.T) {
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
+	require.Equal(t, id, payload.UserID)
+	require.Equal(t, sessionID, payload.SessionID)
+	require.Equal(t, util.RoleCustomer, payload.Role)
+	require.Equal(t, scopes, payload.Scopes)
 	require.WithinDuration(t, issued_at, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expires_at, payload.ExpiredAt, time.Second)
 }
@@ -37,7 +44,7 @@ func TestExpiredJWTToken(t *testing.T) {
 
 	id := util.GenerateRandomInt(1000, 1)
 
-	token, _, err := maker.CreateToken(id, -1)
+	token, _, err := maker.CreateToken(id, uuid.New(), util.RoleCustomer, nil, -1)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
@@ -48,7 +55,7 @@ func TestExpiredJWTToken(t *testing.T) {
 }
 
 func TestInvalidJWTToken(t *testing.T) {
-	payload, err := NewPayload(util.GenerateRandomInt(1000, 1), time.Minute)
+	payload, err := NewPayload(util.GenerateRandomInt(1000, 1), uuid.New(), util.RoleCustomer, nil, time.Minute)
 	require.NoError(t, err)
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
@@ -0,0 +1,134 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+const minRSAKeySize = 2048
+
+//Signs JWTs with a private key (EdDSA or RS256) so services holding only the public key
+//can verify tokens but not forge them
+type JWTPublicMaker struct {
+	method jwt.SigningMethod
+	keys   keyring
+}
+
+//Creates a maker that signs and verifies tokens. alg is EdDSA or RS256 and must match the key
+func NewJWTPublicMaker(alg string, privateKey crypto.PrivateKey) (Maker, error) {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return newJWTPublicMaker(alg, Key{ID: LegacyKeyID, PrivateKey: privateKey, PublicKey: signer.Public()})
+}
+
+//Creates a maker that can only verify tokens, for services that don't issue them
+func NewJWTVerifier(alg string, publicKey crypto.PublicKey) (Maker, error) {
+	return newJWTPublicMaker(alg, Key{ID: LegacyKeyID, PublicKey: publicKey})
+}
+
+func newJWTPublicMaker(alg string, key Key) (*JWTPublicMaker, error) {
+	maker := &JWTPublicMaker{}
+	switch alg {
+	case SigningMethodEdDSA.Alg():
+		maker.method = SigningMethodEdDSA
+	case jwt.SigningMethodRS256.Alg():
+		maker.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %s", alg)
+	}
+	if err := maker.SetKeys([]Key{key}); err != nil {
+		return nil, err
+	}
+	return maker, nil
+}
+
+func (maker *JWTPublicMaker) SetKeys(keys []Key) error {
+	return maker.keys.set(keys, func(key Key) error {
+		switch publicKey := key.PublicKey.(type) {
+		case ed25519.PublicKey:
+			if maker.method != SigningMethodEdDSA {
+				return fmt.Errorf("an Ed25519 key can't be used with %s", maker.method.Alg())
+			}
+			if key.PrivateKey != nil {
+				if _, ok := key.PrivateKey.(ed25519.PrivateKey); !ok {
+					return errors.New("private key does not match the public key")
+				}
+			}
+		case *rsa.PublicKey:
+			if maker.method != jwt.SigningMethodRS256 {
+				return fmt.Errorf("an RSA key can't be used with %s", maker.method.Alg())
+			}
+			if publicKey.N.BitLen() < minRSAKeySize {
+				return fmt.Errorf("invalid key size: RSA keys must be at least %d bits", minRSAKeySize)
+			}
+			if key.PrivateKey != nil {
+				if _, ok := key.PrivateKey.(*rsa.PrivateKey); !ok {
+					return errors.New("private key does not match the public key")
+				}
+			}
+		default:
+			return errors.New("unsupported public key type")
+		}
+		return nil
+	})
+}
+
+func (maker *JWTPublicMaker) CreateToken(userID int64, sessionID uuid.UUID, role string, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(userID, sessionID, role, scopes, duration)
+	if err != nil {
+		return "", nil, err
+	}
+	key, err := maker.keys.signingKey(payload.IssuedAt)
+	if err != nil {
+		return "", nil, err
+	}
+	jwtToken := jwt.NewWithClaims(maker.method, payload)
+	jwtToken.Header["kid"] = key.ID
+	token, err := jwtToken.SignedString(key.PrivateKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, payload, nil
+}
+
+func (maker *JWTPublicMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		//Only accept the configured algorithm, so a token can't pick a weaker one
+		if token.Method.Alg() != maker.method.Alg() {
+			return nil, ERR_INVALID_TOKEN
+		}
+		key, ok := maker.keys.key(headerKeyID(token))
+		if !ok {
+			return nil, ERR_INVALID_TOKEN
+		}
+		return key.PublicKey, nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	if err != nil {
+		verr, ok := err.(*jwt.ValidationError)
+		if ok && errors.Is(verr.Inner, ERR_TOKEN_EXPIRED) {
+			return nil, ERR_TOKEN_EXPIRED
+		}
+		return nil, ERR_INVALID_TOKEN
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ERR_INVALID_TOKEN
+	}
+	return payload, nil
+}
+
+func (maker *JWTPublicMaker) PublicKeys() []JWK {
+	return publicKeys(maker.keys.list(), maker.method.Alg())
+}
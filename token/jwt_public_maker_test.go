@@ -0,0 +1,99 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTPublicMaker(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, minRSAKeySize)
+	require.NoError(t, err)
+	edKey := newTestEd25519Key(t)
+
+	testCases := []struct {
+		alg        string
+		privateKey interface{}
+	}{
+		{alg: "EdDSA", privateKey: edKey},
+		{alg: "RS256", privateKey: rsaKey},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.alg, func(t *testing.T) {
+			maker, err := NewJWTPublicMaker(testCase.alg, testCase.privateKey)
+			require.NoError(t, err)
+
+			id := util.GenerateRandomInt(1000, 1)
+			sessionID := uuid.New()
+			token, _, err := maker.CreateToken(id, sessionID, util.RoleCustomer, nil, time.Minute)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, id, payload.UserID)
+			require.Equal(t, sessionID, payload.SessionID)
+
+			keys := maker.(PublicKeyMaker).PublicKeys()
+			require.Len(t, keys, 1)
+			require.Equal(t, testCase.alg, keys[0].Alg)
+		})
+	}
+}
+
+func TestJWTVerifier(t *testing.T) {
+	privateKey := newTestEd25519Key(t)
+	maker, err := NewJWTPublicMaker("EdDSA", privateKey)
+	require.NoError(t, err)
+	verifier, err := NewJWTVerifier("EdDSA", privateKey.Public())
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.GenerateRandomInt(1000, 1), uuid.New(), util.RoleCustomer, nil, time.Minute)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+	_, _, err = verifier.CreateToken(util.GenerateRandomInt(1000, 1), uuid.New(), util.RoleCustomer, nil, time.Minute)
+	require.ErrorIs(t, err, ErrVerifierOnly)
+}
+
+func TestJWTPublicMakerKeyMismatch(t *testing.T) {
+	_, err := NewJWTPublicMaker("RS256", newTestEd25519Key(t))
+	require.Error(t, err)
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = NewJWTPublicMaker("RS256", smallKey)
+	require.Error(t, err)
+}
+
+func TestInvalidJWTPublicToken(t *testing.T) {
+	maker, err := NewJWTPublicMaker("EdDSA", newTestEd25519Key(t))
+	require.NoError(t, err)
+	payload, err := NewPayload(util.GenerateRandomInt(1000, 1), uuid.New(), util.RoleCustomer, nil, time.Minute)
+	require.NoError(t, err)
+
+	//Unsigned tokens and tokens signed with a shared secret are rejected
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, payload).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, payload).SignedString([]byte(util.GenerateString(32)))
+	require.NoError(t, err)
+
+	for _, token := range []string{none, hmac} {
+		verified, err := maker.VerifyToken(token)
+		require.EqualError(t, err, ERR_INVALID_TOKEN.Error())
+		require.Nil(t, verified)
+	}
+
+	expired, _, err := maker.CreateToken(util.GenerateRandomInt(1000, 1), uuid.New(), util.RoleCustomer, nil, -1)
+	require.NoError(t, err)
+	verified, err := maker.VerifyToken(expired)
+	require.EqualError(t, err, ERR_TOKEN_EXPIRED.Error())
+	require.Nil(t, verified)
+}
@@ -0,0 +1,118 @@
+package token
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//Tokens without a key id were issued before keys were rotated, they are verified with this key
+const LegacyKeyID = "legacy"
+
+//Returned by CreateToken when every key that can sign has a NotBefore in the future
+var ErrNoSigningKey = errors.New("No signing key is active yet")
+
+//A signing key. Shared secret makers use Secret, the others PrivateKey and PublicKey.
+//A key only signs tokens once NotBefore has passed but it verifies them straight away
+type Key struct {
+	ID         string
+	Secret     []byte
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+	NotBefore  time.Time
+}
+
+func (key Key) canSign() bool {
+	return len(key.Secret) > 0 || key.PrivateKey != nil
+}
+
+//Implemented by makers that can rotate keys. SetKeys replaces the whole keyring, every key
+//that is left out can no longer verify tokens
+type KeyringMaker interface {
+	Maker
+	SetKeys(keys []Key) error
+}
+
+//Keys shared by a maker, the key id travels in the token so the right key can verify it
+type keyring struct {
+	mu   sync.RWMutex
+	keys []Key
+}
+
+func (ring *keyring) set(keys []Key, check func(Key) error) error {
+	if len(keys) == 0 {
+		return errors.New("keyring needs at least one key")
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if len(key.ID) == 0 || seen[key.ID] {
+			return fmt.Errorf("key id %q is empty or used twice", key.ID)
+		}
+		seen[key.ID] = true
+		if err := check(key); err != nil {
+			return fmt.Errorf("key %s: %v", key.ID, err)
+		}
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	ring.keys = append([]Key(nil), keys...)
+	return nil
+}
+
+//The key with the latest NotBefore that has passed signs new tokens. Newer keys only verify
+//until then, so every server knows a key before any of them signs with it
+func (ring *keyring) signingKey(now time.Time) (Key, error) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	var signing Key
+	found, canSign := false, false
+	for _, key := range ring.keys {
+		if !key.canSign() {
+			continue
+		}
+		canSign = true
+		if key.NotBefore.After(now) {
+			continue
+		}
+		if !found || key.NotBefore.After(signing.NotBefore) {
+			signing = key
+			found = true
+		}
+	}
+	if !canSign {
+		return Key{}, ErrVerifierOnly
+	}
+	if !found {
+		return Key{}, ErrNoSigningKey
+	}
+	return signing, nil
+}
+
+func (ring *keyring) key(id string) (Key, bool) {
+	if len(id) == 0 {
+		id = LegacyKeyID
+	}
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	for _, key := range ring.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+func (ring *keyring) list() []Key {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	return append([]Key(nil), ring.keys...)
+}
+
+//Footer of PASETO tokens, it is authenticated but not encrypted
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
@@ -0,0 +1,179 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRotation(t *testing.T) {
+	rsaKeys := make([]*rsa.PrivateKey, 2)
+	for i := range rsaKeys {
+		key, err := rsa.GenerateKey(rand.Reader, minRSAKeySize)
+		require.NoError(t, err)
+		rsaKeys[i] = key
+	}
+
+	testCases := []struct {
+		name     string
+		newMaker func(t *testing.T) Maker
+		newKey   func(t *testing.T, id string, notBefore time.Time) Key
+	}{
+		{
+			name: "Paseto",
+			newMaker: func(t *testing.T) Maker {
+				maker, err := NewPasetoMaker(util.GenerateString(32))
+				require.NoError(t, err)
+				return maker
+			},
+			newKey: func(t *testing.T, id string, notBefore time.Time) Key {
+				return Key{ID: id, Secret: []byte(util.GenerateString(32)), NotBefore: notBefore}
+			},
+		},
+		{
+			name: "JWT",
+			newMaker: func(t *testing.T) Maker {
+				maker, err := NewJWTMaker(util.GenerateString(32))
+				require.NoError(t, err)
+				return maker
+			},
+			newKey: func(t *testing.T, id string, notBefore time.Time) Key {
+				return Key{ID: id, Secret: []byte(util.GenerateString(32)), NotBefore: notBefore}
+			},
+		},
+		{
+			name: "PasetoPublic",
+			newMaker: func(t *testing.T) Maker {
+				maker, err := NewPasetoPublicMaker(newTestEd25519Key(t))
+				require.NoError(t, err)
+				return maker
+			},
+			newKey: func(t *testing.T, id string, notBefore time.Time) Key {
+				privateKey := newTestEd25519Key(t)
+				return Key{ID: id, PrivateKey: privateKey, PublicKey: privateKey.Public(), NotBefore: notBefore}
+			},
+		},
+		{
+			name: "JWTRS256",
+			newMaker: func(t *testing.T) Maker {
+				maker, err := NewJWTPublicMaker("RS256", rsaKeys[0])
+				require.NoError(t, err)
+				return maker
+			},
+			newKey: func(t *testing.T, id string, notBefore time.Time) Key {
+				return Key{ID: id, PrivateKey: rsaKeys[1], PublicKey: rsaKeys[1].Public(), NotBefore: notBefore}
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			maker := testCase.newMaker(t)
+			rotating, ok := maker.(KeyringMaker)
+			require.True(t, ok)
+			legacy := keysOf(maker)
+
+			legacyToken, _, err := maker.CreateToken(1, uuid.New(), util.RoleCustomer, nil, time.Minute)
+			require.NoError(t, err)
+
+			//A new key verifies straight away but doesn't sign until NotBefore
+			next := testCase.newKey(t, "next", time.Now().Add(time.Hour))
+			err = rotating.SetKeys(append(legacy, next))
+			require.NoError(t, err)
+
+			token, _, err := maker.CreateToken(1, uuid.New(), util.RoleCustomer, nil, time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, LegacyKeyID, tokenKeyID(t, maker, token))
+
+			next.NotBefore = time.Now().Add(-time.Second)
+			err = rotating.SetKeys(append(legacy, next))
+			require.NoError(t, err)
+
+			token, _, err = maker.CreateToken(1, uuid.New(), util.RoleCustomer, nil, time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, next.ID, tokenKeyID(t, maker, token))
+
+			_, err = maker.VerifyToken(legacyToken)
+			require.NoError(t, err)
+
+			//Retiring the legacy key invalidates the tokens it signed
+			err = rotating.SetKeys([]Key{next})
+			require.NoError(t, err)
+
+			_, err = maker.VerifyToken(legacyToken)
+			require.EqualError(t, err, ERR_INVALID_TOKEN.Error())
+			_, err = maker.VerifyToken(token)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestKeyringRejectsInvalidKeys(t *testing.T) {
+	maker, err := NewPasetoMaker(util.GenerateString(32))
+	require.NoError(t, err)
+	rotating := maker.(KeyringMaker)
+
+	require.Error(t, rotating.SetKeys(nil))
+	require.Error(t, rotating.SetKeys([]Key{{ID: "short", Secret: []byte("short")}}))
+
+	key := Key{ID: "same", Secret: []byte(util.GenerateString(32))}
+	require.Error(t, rotating.SetKeys([]Key{key, key}))
+
+	//A future key alone can't sign yet
+	key.NotBefore = time.Now().Add(time.Hour)
+	require.NoError(t, rotating.SetKeys([]Key{key}))
+	_, _, err = maker.CreateToken(1, uuid.New(), util.RoleCustomer, nil, time.Minute)
+	require.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+//Tokens issued before rotation have no kid and are verified with the legacy key
+func TestTokenWithoutKeyID(t *testing.T) {
+	secret := util.GenerateString(32)
+	maker, err := NewJWTMaker(secret)
+	require.NoError(t, err)
+
+	payload, err := NewPayload(1, uuid.New(), util.RoleCustomer, nil, time.Minute)
+	require.NoError(t, err)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, payload).SignedString([]byte(secret))
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+}
+
+func keysOf(maker Maker) []Key {
+	switch maker := maker.(type) {
+	case *PasetoMaker:
+		return maker.keys.list()
+	case *JWTMaker:
+		return maker.keys.list()
+	case *PasetoPublicMaker:
+		return maker.keys.list()
+	case *JWTPublicMaker:
+		return maker.keys.list()
+	}
+	return nil
+}
+
+//Finds the key that verifies the token by trying each key on its own
+func tokenKeyID(t *testing.T, maker Maker, token string) string {
+	rotating := maker.(KeyringMaker)
+	keys := keysOf(maker)
+	defer func() {
+		require.NoError(t, rotating.SetKeys(keys))
+	}()
+
+	for _, key := range keys {
+		require.NoError(t, rotating.SetKeys([]Key{key}))
+		if _, err := maker.VerifyToken(token); err == nil {
+			return key.ID
+		}
+	}
+	return ""
+}
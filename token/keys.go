@@ -0,0 +1,139 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+//Returned by CreateToken when the maker only holds a public key
+var ErrVerifierOnly = errors.New("Token maker can only verify tokens")
+
+//A public key in JSON Web Key format (RFC 7517) so other services can verify tokens
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+//Implemented by makers that sign with a private key, so their public keys can be published
+type PublicKeyMaker interface {
+	Maker
+	PublicKeys() []JWK
+}
+
+func NewJWK(publicKey crypto.PublicKey, alg string) (JWK, error) {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+			Alg: alg,
+			Use: "sig",
+		}, nil
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			Alg: alg,
+			Use: "sig",
+		}, nil
+	}
+	return JWK{}, errors.New("unsupported public key type")
+}
+
+func publicKeys(keys []Key, alg string) []JWK {
+	list := make([]JWK, 0, len(keys))
+	for _, key := range keys {
+		jwk, err := NewJWK(key.PublicKey, alg)
+		if err != nil {
+			continue
+		}
+		jwk.Kid = key.ID
+		list = append(list, jwk)
+	}
+	return list
+}
+
+//Reads a PEM encoded PKCS #8 or PKCS #1 private key
+func LoadPrivateKey(path string) (crypto.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data)
+}
+
+//Parses a PEM encoded PKCS #8 or PKCS #1 private key
+func ParsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+//Encodes a private key as PEM encoded PKCS #8
+func EncodePrivateKey(privateKey crypto.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+//Reads a PEM encoded PKIX public key
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+//Parses a PEM encoded PKIX public key
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+//Encodes a public key as PEM encoded PKIX
+func EncodePublicKey(publicKey crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM encoded key", path)
+	}
+	return block, nil
+}
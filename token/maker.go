@@ -1,8 +1,12 @@
 package token
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Maker interface {
-	CreateToken(id int64, duration time.Duration) (string, *Payload, error)
+	CreateToken(id int64, sessionID uuid.UUID, role string, scopes []string, duration time.Duration) (string, *Payload, error)
 	VerifyToken(token string) (*Payload, error)
 }
@@ -4,32 +4,45 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/o1egl/paseto"
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
 type PasetoMaker struct {
 	paseto *paseto.V2
-	secret []byte
+	keys   keyring
 }
 
 func NewPasetoMaker(secret string) (Maker, error) {
-	if len(secret) != chacha20poly1305.KeySize {
-		return nil, fmt.Errorf("invalid key size: required %d characters", chacha20poly1305.KeySize)
-	}
 	maker := &PasetoMaker{
 		paseto: paseto.NewV2(),
-		secret: []byte(secret),
+	}
+	if err := maker.SetKeys([]Key{{ID: LegacyKeyID, Secret: []byte(secret)}}); err != nil {
+		return nil, err
 	}
 	return maker, nil
 }
 
-func (maker *PasetoMaker) CreateToken(userID int64, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(userID, duration)
+func (maker *PasetoMaker) SetKeys(keys []Key) error {
+	return maker.keys.set(keys, func(key Key) error {
+		if len(key.Secret) != chacha20poly1305.KeySize {
+			return fmt.Errorf("invalid key size: required %d characters", chacha20poly1305.KeySize)
+		}
+		return nil
+	})
+}
+
+func (maker *PasetoMaker) CreateToken(userID int64, sessionID uuid.UUID, role string, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(userID, sessionID, role, scopes, duration)
 	if err != nil {
 		return "", nil, err
 	}
-	token, err := maker.paseto.Encrypt(maker.secret, payload, nil)
+	key, err := maker.keys.signingKey(payload.IssuedAt)
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := maker.paseto.Encrypt(key.Secret, payload, pasetoFooter{KeyID: key.ID})
 	if err != nil {
 		return "", nil, err
 	}
@@ -37,8 +50,17 @@ func (maker *PasetoMaker) CreateToken(userID int64, duration time.Duration) (str
 }
 
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+	footer := pasetoFooter{}
+	if err := paseto.ParseFooter(token, &footer); err != nil {
+		return nil, ERR_INVALID_TOKEN
+	}
+	key, ok := maker.keys.key(footer.KeyID)
+	if !ok {
+		return nil, ERR_INVALID_TOKEN
+	}
+
 	payload := &Payload{}
-	err := maker.paseto.Decrypt(token, maker.secret, payload, nil)
+	err := maker.paseto.Decrypt(token, key.Secret, payload, nil)
 	if err != nil {
 		return nil, ERR_INVALID_TOKEN
 	}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/faisal-a-n/simplebank/util"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,11 +14,13 @@ func TestPasetoMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	id := util.GenerateRandomInt(1000, 1)
+	sessionID := uuid.New()
+	scopes := util.RoleScopes(util.RoleCustomer)
 	duration := time.Minute
 	issued_at := time.Now()
 	expires_at := time.Now().Add(time.Minute)
 
-	token, _, err := maker.CreateToken(id, duration)
+	token, _, err := maker.CreateToken(id, sessionID, util.RoleCustomer, scopes, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
@@ -26,6 +29,10 @@ func TestPasetoMaker(t *testing.T) {
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
+	require.Equal(t, id, payload.UserID)
+	require.Equal(t, sessionID, payload.SessionID)
+	require.Equal(t, util.RoleCustomer, payload.Role)
+	require.Equal(t, scopes, payload.Scopes)
 	require.WithinDuration(t, issued_at, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expires_at, payload.ExpiredAt, time.Second)
 }
@@ -36,7 +43,7 @@ func TestExpiredPasetoToken(t *testing.T) {
 
 	id := util.GenerateRandomInt(1000, 1)
 
-	token, _, err := maker.CreateToken(id, -1)
+	token, _, err := maker.CreateToken(id, uuid.New(), util.RoleCustomer, nil, -1)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
@@ -0,0 +1,147 @@
+package token
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const pasetoV4PublicHeader = "v4.public."
+
+//Signs PASETO v4.public tokens with an Ed25519 key. The payload is readable by anyone
+//holding the token but only the private key can sign one
+type PasetoPublicMaker struct {
+	keys keyring
+}
+
+//Creates a maker that signs and verifies tokens
+func NewPasetoPublicMaker(privateKey crypto.PrivateKey) (Maker, error) {
+	key, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("PASETO v4.public requires an Ed25519 key")
+	}
+	maker := &PasetoPublicMaker{}
+	if err := maker.SetKeys([]Key{{ID: LegacyKeyID, PrivateKey: key, PublicKey: key.Public()}}); err != nil {
+		return nil, err
+	}
+	return maker, nil
+}
+
+//Creates a maker that can only verify tokens, for services that don't issue them
+func NewPasetoPublicVerifier(publicKey crypto.PublicKey) (Maker, error) {
+	maker := &PasetoPublicMaker{}
+	if err := maker.SetKeys([]Key{{ID: LegacyKeyID, PublicKey: publicKey}}); err != nil {
+		return nil, err
+	}
+	return maker, nil
+}
+
+func (maker *PasetoPublicMaker) SetKeys(keys []Key) error {
+	return maker.keys.set(keys, func(key Key) error {
+		if _, ok := key.PublicKey.(ed25519.PublicKey); !ok {
+			return errors.New("PASETO v4.public requires an Ed25519 key")
+		}
+		if key.PrivateKey != nil {
+			if _, ok := key.PrivateKey.(ed25519.PrivateKey); !ok {
+				return errors.New("PASETO v4.public requires an Ed25519 key")
+			}
+		}
+		return nil
+	})
+}
+
+func (maker *PasetoPublicMaker) CreateToken(userID int64, sessionID uuid.UUID, role string, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(userID, sessionID, role, scopes, duration)
+	if err != nil {
+		return "", nil, err
+	}
+	key, err := maker.keys.signingKey(payload.IssuedAt)
+	if err != nil {
+		return "", nil, err
+	}
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+	footer, err := json.Marshal(pasetoFooter{KeyID: key.ID})
+	if err != nil {
+		return "", nil, err
+	}
+	signature := ed25519.Sign(key.PrivateKey.(ed25519.PrivateKey), preAuthEncode([]byte(pasetoV4PublicHeader), message, footer, nil))
+	token := pasetoV4PublicHeader + base64.RawURLEncoding.EncodeToString(append(message, signature...)) +
+		"." + base64.RawURLEncoding.EncodeToString(footer)
+	return token, payload, nil
+}
+
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	if !strings.HasPrefix(token, pasetoV4PublicHeader) {
+		return nil, ERR_INVALID_TOKEN
+	}
+	parts := strings.Split(token[len(pasetoV4PublicHeader):], ".")
+	if len(parts) > 2 {
+		return nil, ERR_INVALID_TOKEN
+	}
+	body, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(body) < ed25519.SignatureSize {
+		return nil, ERR_INVALID_TOKEN
+	}
+	var footer []byte
+	keyID := LegacyKeyID
+	if len(parts) == 2 {
+		footer, err = base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, ERR_INVALID_TOKEN
+		}
+		decoded := pasetoFooter{}
+		if err := json.Unmarshal(footer, &decoded); err != nil {
+			return nil, ERR_INVALID_TOKEN
+		}
+		keyID = decoded.KeyID
+	}
+	key, ok := maker.keys.key(keyID)
+	if !ok {
+		return nil, ERR_INVALID_TOKEN
+	}
+
+	message := body[:len(body)-ed25519.SignatureSize]
+	signature := body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(key.PublicKey.(ed25519.PublicKey), preAuthEncode([]byte(pasetoV4PublicHeader), message, footer, nil), signature) {
+		return nil, ERR_INVALID_TOKEN
+	}
+
+	payload := &Payload{}
+	if err := json.Unmarshal(message, payload); err != nil {
+		return nil, ERR_INVALID_TOKEN
+	}
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (maker *PasetoPublicMaker) PublicKeys() []JWK {
+	return publicKeys(maker.keys.list(), "v4.public")
+}
+
+//Pre-authentication encoding from the PASETO spec, every piece is prefixed with its
+//length so pieces can't be shifted into each other
+func preAuthEncode(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	length := make([]byte, 8)
+	binary.LittleEndian.PutUint64(length, uint64(len(pieces)))
+	buf.Write(length)
+	for _, piece := range pieces {
+		binary.LittleEndian.PutUint64(length, uint64(len(piece)))
+		buf.Write(length)
+		buf.Write(piece)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,106 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faisal-a-n/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return privateKey
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	privateKey := newTestEd25519Key(t)
+	maker, err := NewPasetoPublicMaker(privateKey)
+	require.NoError(t, err)
+
+	id := util.GenerateRandomInt(1000, 1)
+	sessionID := uuid.New()
+	issued_at := time.Now()
+	expires_at := time.Now().Add(time.Minute)
+
+	token, _, err := maker.CreateToken(id, sessionID, util.RoleCustomer, nil, time.Minute)
+	require.NoError(t, err)
+	require.Contains(t, token, pasetoV4PublicHeader)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, id, payload.UserID)
+	require.Equal(t, sessionID, payload.SessionID)
+	require.WithinDuration(t, issued_at, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expires_at, payload.ExpiredAt, time.Second)
+
+	//A service holding only the public key can verify but not issue tokens
+	verifier, err := NewPasetoPublicVerifier(privateKey.Public())
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+	_, _, err = verifier.CreateToken(id, sessionID, util.RoleCustomer, nil, time.Minute)
+	require.ErrorIs(t, err, ErrVerifierOnly)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.GenerateRandomInt(1000, 1), uuid.New(), util.RoleCustomer, nil, -1)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ERR_TOKEN_EXPIRED.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidPasetoPublicToken(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+	other, err := NewPasetoPublicMaker(newTestEd25519Key(t))
+	require.NoError(t, err)
+
+	token, _, err := other.CreateToken(util.GenerateRandomInt(1000, 1), uuid.New(), util.RoleCustomer, nil, time.Minute)
+	require.NoError(t, err)
+
+	//Signed by another key
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ERR_INVALID_TOKEN.Error())
+	require.Nil(t, payload)
+
+	//Tampered payload
+	parts := strings.Split(token[len(pasetoV4PublicHeader):], ".")
+	require.Len(t, parts, 2)
+	body, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	body[0] ^= 1
+	payload, err = other.VerifyToken(pasetoV4PublicHeader + base64.RawURLEncoding.EncodeToString(body) + "." + parts[1])
+	require.EqualError(t, err, ERR_INVALID_TOKEN.Error())
+	require.Nil(t, payload)
+
+	//A local token is not accepted
+	payload, err = maker.VerifyToken("v2.local." + token[len(pasetoV4PublicHeader):])
+	require.EqualError(t, err, ERR_INVALID_TOKEN.Error())
+	require.Nil(t, payload)
+}
+
+//Test vector 4-S-1 from the PASETO specification
+func TestPasetoPublicVector(t *testing.T) {
+	secret, err := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774" +
+		"1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	require.NoError(t, err)
+	message := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+
+	signature := ed25519.Sign(ed25519.PrivateKey(secret), preAuthEncode([]byte(pasetoV4PublicHeader), message, nil, nil))
+	token := pasetoV4PublicHeader + base64.RawURLEncoding.EncodeToString(append(message, signature...))
+	require.Equal(t, "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9"+
+		"bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA", token)
+}
@@ -12,14 +12,19 @@ var (
 	ERR_INVALID_TOKEN = errors.New("Invalid token")
 )
 
+//SessionID ties the token to the login it was issued for so it can be revoked with it.
+//Role is the user's role when the token was issued, Scopes what the token may be used for
 type Payload struct {
 	ID        uuid.UUID `json:"uid"`
 	UserID    int64     `json:"user_id"`
+	SessionID uuid.UUID `json:"session_id"`
+	Role      string    `json:"role"`
+	Scopes    []string  `json:"scopes"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-func NewPayload(id int64, duration time.Duration) (*Payload, error) {
+func NewPayload(id int64, sessionID uuid.UUID, role string, scopes []string, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -27,6 +32,9 @@ func NewPayload(id int64, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:        tokenID,
 		UserID:    id,
+		SessionID: sessionID,
+		Role:      role,
+		Scopes:    scopes,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
@@ -39,3 +47,12 @@ func (payload *Payload) Valid() error {
 	}
 	return nil
 }
+
+func (payload *Payload) HasScope(scope string) bool {
+	for _, granted := range payload.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
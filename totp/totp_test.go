@@ -0,0 +1,83 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHOTP(t *testing.T) {
+	//Test vectors from RFC 6238 appendix B for SHA1
+	key := []byte("12345678901234567890")
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.code, hotp(key, uint64(testCase.unix/Period), 8))
+	}
+}
+
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	code, err := Code(secret, Step(time.Unix(59, 0)))
+	require.NoError(t, err)
+	require.Equal(t, "287082", code)
+
+	_, err = Code("not base32!", 1)
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	now := time.Now()
+	current := Step(now)
+	code, err := Code(secret, current)
+	require.NoError(t, err)
+
+	step, ok := Validate(secret, code, now, 0)
+	require.True(t, ok)
+	require.Equal(t, current, step)
+
+	//Drift of one period is allowed, more isn't
+	previous, err := Code(secret, current-1)
+	require.NoError(t, err)
+	_, ok = Validate(secret, previous, now, 0)
+	require.True(t, ok)
+	old, err := Code(secret, current-2)
+	require.NoError(t, err)
+	_, ok = Validate(secret, old, now, 0)
+	require.False(t, ok)
+
+	//A code can't be replayed once its step was used
+	_, ok = Validate(secret, code, now, current)
+	require.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now, 0)
+	require.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Simple Bank", "user@example.com", "JBSWY3DPEHPK3PXP")
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", parsed.Scheme)
+	require.Equal(t, "totp", parsed.Host)
+	require.Equal(t, "/Simple Bank:user@example.com", parsed.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", parsed.Query().Get("secret"))
+	require.Equal(t, "Simple Bank", parsed.Query().Get("issuer"))
+	require.Equal(t, "6", parsed.Query().Get("digits"))
+}
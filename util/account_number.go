@@ -0,0 +1,61 @@
+package util
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+//Account numbers are laid out like an IBAN: the bank prefix, two mod 97 check digits and a
+//random 12 digit number, for example SB23000012345678. Any single mistyped digit or swap of
+//two neighbouring digits fails the check
+const (
+	AccountNumberPrefix = "SB"
+	accountNumberDigits = 12
+	accountNumberLength = len(AccountNumberPrefix) + 2 + accountNumberDigits
+)
+
+var accountNumberSpace = new(big.Int).Exp(big.NewInt(10), big.NewInt(accountNumberDigits), nil)
+
+//Creates an account number from a random number so numbers don't reveal how many accounts exist
+func NewAccountNumber() (string, error) {
+	n, err := rand.Int(rand.Reader, accountNumberSpace)
+	if err != nil {
+		return "", err
+	}
+	return accountNumber(n.Int64()), nil
+}
+
+func accountNumber(n int64) string {
+	digits := fmt.Sprintf("%0*d", accountNumberDigits, n)
+	check := 98 - mod97(digits+AccountNumberPrefix+"00")
+	return fmt.Sprintf("%s%02d%s", AccountNumberPrefix, check, digits)
+}
+
+func IsValidAccountNumber(number string) bool {
+	if len(number) != accountNumberLength || !strings.HasPrefix(number, AccountNumberPrefix) {
+		return false
+	}
+	for _, c := range number[len(AccountNumberPrefix):] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	//The check digits make the rearranged number leave a remainder of 1
+	return mod97(number[4:]+number[:4]) == 1
+}
+
+//Remainder of a number mod 97, letters count as two digits from A=10 to Z=35 as in ISO 13616
+func mod97(number string) int {
+	remainder := 0
+	for _, c := range number {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A'+10)) % 97
+		}
+	}
+	return remainder
+}
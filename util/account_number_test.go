@@ -0,0 +1,46 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAccountNumber(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		number, err := NewAccountNumber()
+		require.NoError(t, err)
+		require.Len(t, number, accountNumberLength)
+		require.True(t, IsValidAccountNumber(number), number)
+		require.False(t, seen[number])
+		seen[number] = true
+	}
+}
+
+func TestIsValidAccountNumber(t *testing.T) {
+	number := accountNumber(12345678)
+	require.Equal(t, "SB23000012345678", number)
+
+	testCases := []struct {
+		name   string
+		number string
+		valid  bool
+	}{
+		{"Valid", number, true},
+		{"WrongDigit", "SB23000012345679", false},
+		{"SwappedDigits", "SB23000012345687", false},
+		{"WrongCheckDigits", "SB24000012345678", false},
+		{"WrongPrefix", "XX23000012345678", false},
+		{"Lowercase", "sb23000012345678", false},
+		{"TooShort", "SB2300001234567", false},
+		{"TooLong", "SB230000123456780", false},
+		{"Letters", "SB2300001234567A", false},
+		{"Empty", "", false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.valid, IsValidAccountNumber(testCase.number))
+		})
+	}
+}
@@ -12,8 +12,53 @@ type Config struct {
 	DB_SOURCE              string        `mapstructure:"DB_SOURCE"`
 	PORT                   string        `mapstructure:"PORT"`
 	SECRET_KEY             string        `mapstructure:"SECRET_KEY"`
+	TOKEN_TYPE             string        `mapstructure:"TOKEN_TYPE"`
+	TOKEN_PRIVATE_KEY_FILE string        `mapstructure:"TOKEN_PRIVATE_KEY_FILE"`
+	TOKEN_PUBLIC_KEY_FILE  string        `mapstructure:"TOKEN_PUBLIC_KEY_FILE"`
+	TOKEN_KEY_REFRESH      time.Duration `mapstructure:"TOKEN_KEY_REFRESH"`
 	ACCESS_TOKEN_DURATION  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	REFRESH_TOKEN_DURATION time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	SESSION_POLICY         string        `mapstructure:"SESSION_POLICY"`
+	REVOCATION_CACHE_TTL   time.Duration `mapstructure:"REVOCATION_CACHE_TTL"`
+	RECONCILE_INTERVAL     time.Duration `mapstructure:"RECONCILE_INTERVAL"`
+	RECONCILE_REPAIR       bool          `mapstructure:"RECONCILE_REPAIR"`
+	FX_RATES_FILE          string        `mapstructure:"FX_RATES_FILE"`
+	FX_QUOTE_DURATION      time.Duration `mapstructure:"FX_QUOTE_DURATION"`
+	CURRENCY_REFRESH       time.Duration `mapstructure:"CURRENCY_REFRESH"`
+	SCHEDULER_INTERVAL     time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	SCHEDULER_MAX_RETRIES  int           `mapstructure:"SCHEDULER_MAX_RETRIES"`
+	SCHEDULER_RETRY_DELAY  time.Duration `mapstructure:"SCHEDULER_RETRY_DELAY"`
+	//Mail is written to files in MAIL_DIR, or to the log when it is empty
+	MAIL_DIR                string        `mapstructure:"MAIL_DIR"`
+	PASSWORD_RESET_DURATION time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	//Users have to verify their email before they can open accounts or send money
+	REQUIRE_EMAIL_VERIFICATION  bool          `mapstructure:"REQUIRE_EMAIL_VERIFICATION"`
+	EMAIL_VERIFICATION_DURATION time.Duration `mapstructure:"EMAIL_VERIFICATION_DURATION"`
+	//Shown by authenticator apps next to the user's email
+	TOTP_ISSUER              string        `mapstructure:"TOTP_ISSUER"`
+	LOGIN_CHALLENGE_DURATION time.Duration `mapstructure:"LOGIN_CHALLENGE_DURATION"`
+	//Comma separated currency:value pairs like EUR:1000, transfers of more than the value in their
+	//currency need a totp code. Currencies without a pair don't need one
+	TRANSFER_2FA_THRESHOLDS []string `mapstructure:"TRANSFER_2FA_THRESHOLDS"`
+	//Failed logins allowed per email and per client ip before each further failure locks logins
+	//for twice as long, starting at LOGIN_BACKOFF. Reaching LOGIN_LOCKOUT_DURATION is a lockout.
+	//Wrong two factor codes outside of logins are limited per user the same way as emails
+	LOGIN_FREE_ATTEMPTS    int32         `mapstructure:"LOGIN_FREE_ATTEMPTS"`
+	LOGIN_IP_FREE_ATTEMPTS int32         `mapstructure:"LOGIN_IP_FREE_ATTEMPTS"`
+	LOGIN_BACKOFF          time.Duration `mapstructure:"LOGIN_BACKOFF"`
+	LOGIN_LOCKOUT_DURATION time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	//Failures are forgotten after this long without another one
+	LOGIN_FAILURE_WINDOW time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	//Comma separated ips or cidrs of the proxies allowed to set X-Forwarded-For, empty trusts none
+	TRUSTED_PROXIES []string `mapstructure:"TRUSTED_PROXIES"`
+	//Idempotency keys can be reused after IDEMPOTENCY_KEY_TTL and are deleted on the cleanup interval
+	IDEMPOTENCY_KEY_TTL          time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	IDEMPOTENCY_CLEANUP_INTERVAL time.Duration `mapstructure:"IDEMPOTENCY_CLEANUP_INTERVAL"`
+	//Encrypts the shared secrets and private keys stored by the keys command
+	TOKEN_KEY_ENCRYPTION_KEY string `mapstructure:"TOKEN_KEY_ENCRYPTION_KEY"`
+	//Cross currency transfers and quotes are refused once the rates file's as_of is older than
+	//this, zero never refuses them
+	FX_RATES_MAX_AGE time.Duration `mapstructure:"FX_RATES_MAX_AGE"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -1,13 +1,105 @@
 package util
 
-var supportedCurrencies = map[string]bool{
-	"USD": true,
-	"EUR": true,
-	"INR": true,
-	"CAD": true,
-	"YEN": true,
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//An ISO 4217 currency. Exponent is the number of digits of the minor unit amounts are stored in
+type Currency struct {
+	Code        string `json:"code"`
+	NumericCode string `json:"numeric_code"`
+	Exponent    int    `json:"exponent"`
+	Symbol      string `json:"symbol"`
+	Name        string `json:"name"`
+	Enabled     bool   `json:"enabled"`
+}
+
+//Used until the registry is loaded from the currencies table, matches the seeded rows
+var defaultCurrencies = []Currency{
+	{Code: "USD", NumericCode: "840", Exponent: 2, Symbol: "$", Name: "US Dollar", Enabled: true},
+	{Code: "EUR", NumericCode: "978", Exponent: 2, Symbol: "€", Name: "Euro", Enabled: true},
+	{Code: "INR", NumericCode: "356", Exponent: 2, Symbol: "₹", Name: "Indian Rupee", Enabled: true},
+	{Code: "CAD", NumericCode: "124", Exponent: 2, Symbol: "CA$", Name: "Canadian Dollar", Enabled: true},
+	{Code: "JPY", NumericCode: "392", Exponent: 0, Symbol: "¥", Name: "Yen", Enabled: true},
+	{Code: "GBP", NumericCode: "826", Exponent: 2, Symbol: "£", Name: "Pound Sterling", Enabled: false},
+	{Code: "CHF", NumericCode: "756", Exponent: 2, Symbol: "CHF", Name: "Swiss Franc", Enabled: false},
+}
+
+var (
+	currenciesMu sync.RWMutex
+	currencies   = indexCurrencies(defaultCurrencies)
+)
+
+func indexCurrencies(list []Currency) map[string]Currency {
+	index := make(map[string]Currency, len(list))
+	for _, currency := range list {
+		index[currency.Code] = currency
+	}
+	return index
+}
+
+//Replaces the registry, called whenever the currencies are reloaded from the database
+func SetCurrencies(list []Currency) {
+	index := indexCurrencies(list)
+
+	currenciesMu.Lock()
+	defer currenciesMu.Unlock()
+	currencies = index
+}
+
+//Returns every known currency ordered by code, including the disabled ones
+func Currencies() []Currency {
+	currenciesMu.RLock()
+	defer currenciesMu.RUnlock()
+
+	list := make([]Currency, 0, len(currencies))
+	for _, currency := range currencies {
+		list = append(list, currency)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+func LookupCurrency(code string) (Currency, bool) {
+	currenciesMu.RLock()
+	defer currenciesMu.RUnlock()
+
+	currency, ok := currencies[code]
+	return currency, ok
+}
+
+//New accounts and transfers can only use enabled currencies
+func IsSupportedCurrency(code string) bool {
+	currency, ok := LookupCurrency(code)
+	return ok && currency.Enabled
+}
+
+//Returns the number of decimal digits used by the currency, amounts are stored in this minor unit.
+//Disabled currencies still have an exponent so existing balances can be displayed
+func MinorUnits(code string) int {
+	currency, _ := LookupCurrency(code)
+	return currency.Exponent
 }
 
-func IsSupportedCurrency(currency string) bool {
-	return supportedCurrencies[currency]
+//Formats an amount stored in minor units as a decimal string, e.g. -1234 USD becomes -12.34
+func FormatAmount(amount int64, currency string) string {
+	digits := MinorUnits(currency)
+	sign := ""
+	value := fmt.Sprintf("%d", amount)
+	if amount < 0 {
+		sign = "-"
+		value = value[1:]
+	}
+	if digits == 0 {
+		return sign + value
+	}
+	if len(value) <= digits {
+		value = strings.Repeat("0", digits-len(value)+1) + value
+	}
+	return sign + value[:len(value)-digits] + "." + value[len(value)-digits:]
 }
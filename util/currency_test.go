@@ -0,0 +1,55 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAmount(t *testing.T) {
+	testCases := []struct {
+		amount   int64
+		currency string
+		expected string
+	}{
+		{1234, "USD", "12.34"},
+		{-1234, "USD", "-12.34"},
+		{5, "EUR", "0.05"},
+		{-5, "EUR", "-0.05"},
+		{0, "CAD", "0.00"},
+		{100, "INR", "1.00"},
+		{1234, "JPY", "1234"},
+		{-1234, "JPY", "-1234"},
+		{math.MinInt64, "USD", "-92233720368547758.08"},
+	}
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.expected, FormatAmount(testCase.amount, testCase.currency))
+	}
+}
+
+
+func TestCurrencyRegistry(t *testing.T) {
+	defer SetCurrencies(defaultCurrencies)
+
+	require.True(t, IsSupportedCurrency("USD"))
+	require.False(t, IsSupportedCurrency("YEN"))
+	require.False(t, IsSupportedCurrency("GBP"))
+
+	SetCurrencies([]Currency{
+		{Code: "USD", NumericCode: "840", Exponent: 2, Enabled: false},
+		{Code: "GBP", NumericCode: "826", Exponent: 2, Enabled: true},
+	})
+	require.False(t, IsSupportedCurrency("USD"))
+	require.True(t, IsSupportedCurrency("GBP"))
+	require.False(t, IsSupportedCurrency("EUR"))
+
+	//Disabled currencies keep their exponent for formatting
+	require.Equal(t, "1.00", FormatAmount(100, "USD"))
+
+	list := Currencies()
+	require.Len(t, list, 2)
+	require.Equal(t, "GBP", list[0].Code)
+	require.Equal(t, "USD", list[1].Code)
+	require.Equal(t, "GBP", GenerateCurrency())
+}
\ No newline at end of file
@@ -0,0 +1,191 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("money must have the same currency")
+	ErrAmountOverflow   = errors.New("amount is out of range")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+//An amount of money in the minor unit of its currency, e.g. {1234, "USD"} is $12.34
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+//Encoded form of Money, amount holds the minor units and value the formatted decimal
+type moneyJSON struct {
+	Amount   *int64 `json:"amount"`
+	Currency string `json:"currency"`
+	Value    string `json:"value"`
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+//Parses a decimal string like "-12.34" into minor units. The value can't have more
+//fraction digits than the currency's exponent
+func ParseMoney(value string, currency string) (Money, error) {
+	info, ok := LookupCurrency(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("unknown currency [%s]", currency)
+	}
+
+	digits := value
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign = "-"
+		digits = digits[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(digits, ".")
+	if len(whole) == 0 || !isDigits(whole) || !isDigits(fraction) || (hasFraction && len(fraction) == 0) {
+		return Money{}, fmt.Errorf("%w: [%s]", ErrInvalidAmount, value)
+	}
+	if len(fraction) > info.Exponent {
+		return Money{}, fmt.Errorf("%w: [%s] has more than %d decimal places", ErrInvalidAmount, value, info.Exponent)
+	}
+	fraction += strings.Repeat("0", info.Exponent-len(fraction))
+
+	amount, err := strconv.ParseInt(sign+whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: [%s]", ErrAmountOverflow, value)
+	}
+	return NewMoney(amount, currency), nil
+}
+
+func isDigits(value string) bool {
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrAmountOverflow
+	}
+	return NewMoney(m.Amount+other.Amount, m.Currency), nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return m.Add(NewMoney(-other.Amount, other.Currency))
+}
+
+//Splits the money by the given ratios. Leftover minor units are handed out one at a time
+//starting from the first share, so the shares always add up to the original amount
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	total := big.NewInt(0)
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, errors.New("ratios must not be negative")
+		}
+		total.Add(total, big.NewInt(ratio))
+	}
+	if total.Sign() == 0 {
+		return nil, errors.New("ratios must add up to more than zero")
+	}
+
+	amount := big.NewInt(m.Amount)
+	shares := make([]Money, len(ratios))
+	remainder := m.Amount
+	for i, ratio := range ratios {
+		share := new(big.Int).Mul(amount, big.NewInt(ratio))
+		share.Quo(share, total)
+		shares[i] = NewMoney(share.Int64(), m.Currency)
+		remainder -= share.Int64()
+	}
+
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(shares) {
+		if ratios[i] == 0 {
+			continue
+		}
+		shares[i].Amount += step
+		remainder -= step
+	}
+	return shares, nil
+}
+
+//Splits the money into n shares that differ by at most one minor unit
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, errors.New("money must be split into at least one share")
+	}
+	ratios := make([]int64, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return m.Allocate(ratios...)
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+//Returns the amount as a decimal string without the currency, e.g. 12.34
+func (m Money) Decimal() string {
+	return FormatAmount(m.Amount, m.Currency)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{
+		Amount:   &m.Amount,
+		Currency: m.Currency,
+		Value:    m.Decimal(),
+	})
+}
+
+//Accepts the minor units, the decimal value or both as long as they agree
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var decoded moneyJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if len(decoded.Value) == 0 {
+		if decoded.Amount == nil {
+			return fmt.Errorf("%w: amount or value is required", ErrInvalidAmount)
+		}
+		*m = NewMoney(*decoded.Amount, decoded.Currency)
+		return nil
+	}
+
+	parsed, err := ParseMoney(decoded.Value, decoded.Currency)
+	if err != nil {
+		return err
+	}
+	if decoded.Amount != nil && *decoded.Amount != parsed.Amount {
+		return fmt.Errorf("%w: amount %d doesn't match value %s", ErrInvalidAmount, *decoded.Amount, decoded.Value)
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,129 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		value    string
+		currency string
+		amount   int64
+		err      error
+	}{
+		{"12.34", "USD", 1234, nil},
+		{"-12.3", "USD", -1230, nil},
+		{"12", "EUR", 1200, nil},
+		{"0.05", "EUR", 5, nil},
+		{"1234", "JPY", 1234, nil},
+		{"12.345", "USD", 0, ErrInvalidAmount},
+		{"1.5", "JPY", 0, ErrInvalidAmount},
+		{"", "USD", 0, ErrInvalidAmount},
+		{".5", "USD", 0, ErrInvalidAmount},
+		{"5.", "USD", 0, ErrInvalidAmount},
+		{"1e5", "USD", 0, ErrInvalidAmount},
+		{"+5", "USD", 0, ErrInvalidAmount},
+		{"92233720368547758.08", "USD", 0, ErrAmountOverflow},
+	}
+	for _, testCase := range testCases {
+		money, err := ParseMoney(testCase.value, testCase.currency)
+		if testCase.err != nil {
+			require.True(t, errors.Is(err, testCase.err), testCase.value)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, NewMoney(testCase.amount, testCase.currency), money)
+	}
+
+	_, err := ParseMoney("1.00", "XXX")
+	require.Error(t, err)
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	sum, err := NewMoney(150, "USD").Add(NewMoney(250, "USD"))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(400, "USD"), sum)
+
+	difference, err := NewMoney(150, "USD").Sub(NewMoney(250, "USD"))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(-100, "USD"), difference)
+	require.True(t, difference.IsNegative())
+
+	_, err = NewMoney(150, "USD").Add(NewMoney(250, "EUR"))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = NewMoney(math.MaxInt64, "USD").Add(NewMoney(1, "USD"))
+	require.ErrorIs(t, err, ErrAmountOverflow)
+
+	_, err = NewMoney(math.MinInt64, "USD").Sub(NewMoney(1, "USD"))
+	require.ErrorIs(t, err, ErrAmountOverflow)
+
+	_, err = NewMoney(0, "USD").Sub(NewMoney(math.MinInt64, "USD"))
+	require.ErrorIs(t, err, ErrAmountOverflow)
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		amount int64
+		ratios []int64
+		shares []int64
+	}{
+		{"Even", 100, []int64{1, 1}, []int64{50, 50}},
+		{"Remainder", 100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"Weighted", 5, []int64{3, 7}, []int64{2, 3}},
+		{"Negative", -100, []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{"ZeroRatio", 101, []int64{0, 1, 1}, []int64{0, 51, 50}},
+		{"Large", math.MaxInt64, []int64{1, 1}, []int64{math.MaxInt64/2 + 1, math.MaxInt64 / 2}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			shares, err := NewMoney(testCase.amount, "USD").Allocate(testCase.ratios...)
+			require.NoError(t, err)
+			require.Len(t, shares, len(testCase.shares))
+			for i, share := range shares {
+				require.Equal(t, NewMoney(testCase.shares[i], "USD"), share)
+			}
+		})
+	}
+
+	_, err := NewMoney(100, "USD").Allocate(0, 0)
+	require.Error(t, err)
+	_, err = NewMoney(100, "USD").Allocate(1, -1)
+	require.Error(t, err)
+
+	shares, err := NewMoney(10, "USD").Split(3)
+	require.NoError(t, err)
+	require.Equal(t, []Money{NewMoney(4, "USD"), NewMoney(3, "USD"), NewMoney(3, "USD")}, shares)
+	_, err = NewMoney(10, "USD").Split(0)
+	require.Error(t, err)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(NewMoney(-1234, "USD"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"amount": -1234, "currency": "USD", "value": "-12.34"}`, string(data))
+
+	var money Money
+	require.NoError(t, json.Unmarshal(data, &money))
+	require.Equal(t, NewMoney(-1234, "USD"), money)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"value": "5.5", "currency": "EUR"}`), &money))
+	require.Equal(t, NewMoney(550, "EUR"), money)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": 7, "currency": "JPY"}`), &money))
+	require.Equal(t, NewMoney(7, "JPY"), money)
+
+	err = json.Unmarshal([]byte(`{"amount": 7, "value": "0.08", "currency": "USD"}`), &money)
+	require.ErrorIs(t, err, ErrInvalidAmount)
+
+	err = json.Unmarshal([]byte(`{"currency": "USD"}`), &money)
+	require.ErrorIs(t, err, ErrInvalidAmount)
+
+	require.Equal(t, "0.07 USD", NewMoney(7, "USD").String())
+}
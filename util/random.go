@@ -30,13 +30,19 @@ func GenerateAmount() int64 {
 }
 
 func GenerateCurrency() string {
-	currencies := []string{}
-	for k := range supportedCurrencies {
-		currencies = append(currencies, k)
+	codes := []string{}
+	for _, currency := range Currencies() {
+		if currency.Enabled {
+			codes = append(codes, currency.Code)
+		}
 	}
-	return currencies[rand.Intn(len(currencies))]
+	return codes[rand.Intn(len(codes))]
 }
 
 func RandomEmail() string {
 	return fmt.Sprintf("%s@%s.com", GenerateString(5), GenerateString(4))
 }
+
+func GenerateAccountNumber() string {
+	return accountNumber(rand.Int63n(1000000000000))
+}
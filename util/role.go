@@ -0,0 +1,50 @@
+package util
+
+//Roles a user can hold, stored in users.role
+const (
+	RoleCustomer = "customer"
+	RoleSupport  = "support"
+	RoleAdmin    = "admin"
+)
+
+//Scopes carried in access tokens. A token can be issued with fewer scopes than its role
+//allows, for example a read only token for a reporting tool
+const (
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeAdminRead      = "admin:read"
+	ScopeAdminWrite     = "admin:write"
+)
+
+var customerScopes = []string{ScopeAccountsRead, ScopeAccountsWrite, ScopeTransfersWrite}
+
+var roleScopes = map[string][]string{
+	RoleCustomer: customerScopes,
+	RoleSupport:  append(append([]string{}, customerScopes...), ScopeAdminRead),
+	RoleAdmin:    append(append([]string{}, customerScopes...), ScopeAdminRead, ScopeAdminWrite),
+}
+
+func IsSupportedRole(role string) bool {
+	_, ok := roleScopes[role]
+	return ok
+}
+
+//Every scope a role may be granted, nil for unknown roles
+func RoleScopes(role string) []string {
+	return append([]string(nil), roleScopes[role]...)
+}
+
+//Keeps the requested scopes the role allows, in the order they were requested
+func AllowedScopes(role string, requested []string) []string {
+	allowed := []string{}
+	for _, scope := range requested {
+		for _, roleScope := range roleScopes[role] {
+			if scope == roleScope {
+				allowed = append(allowed, scope)
+				break
+			}
+		}
+	}
+	return allowed
+}
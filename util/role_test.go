@@ -0,0 +1,31 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleScopes(t *testing.T) {
+	require.True(t, IsSupportedRole(RoleCustomer))
+	require.False(t, IsSupportedRole("owner"))
+	require.Nil(t, RoleScopes("owner"))
+
+	require.NotContains(t, RoleScopes(RoleCustomer), ScopeAdminRead)
+	require.Contains(t, RoleScopes(RoleSupport), ScopeAdminRead)
+	require.NotContains(t, RoleScopes(RoleSupport), ScopeAdminWrite)
+	require.Contains(t, RoleScopes(RoleAdmin), ScopeAdminWrite)
+
+	//The returned slice is a copy
+	scopes := RoleScopes(RoleCustomer)
+	scopes[0] = ScopeAdminWrite
+	require.NotContains(t, RoleScopes(RoleCustomer), ScopeAdminWrite)
+}
+
+func TestAllowedScopes(t *testing.T) {
+	requested := []string{ScopeAdminRead, ScopeAccountsRead, ScopeAdminWrite}
+	require.Equal(t, []string{ScopeAccountsRead}, AllowedScopes(RoleCustomer, requested))
+	require.Equal(t, []string{ScopeAdminRead, ScopeAccountsRead}, AllowedScopes(RoleSupport, requested))
+	require.Equal(t, requested, AllowedScopes(RoleAdmin, requested))
+	require.Empty(t, AllowedScopes("owner", requested))
+}
@@ -0,0 +1,52 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSecretToken(t *testing.T) {
+	token1, err := NewSecretToken(32)
+	require.NoError(t, err)
+	require.Len(t, token1, 43)
+
+	token2, err := NewSecretToken(32)
+	require.NoError(t, err)
+	require.NotEqual(t, token1, token2)
+}
+
+func TestHashSecretToken(t *testing.T) {
+	token, err := NewSecretToken(32)
+	require.NoError(t, err)
+
+	hash := HashSecretToken(token)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashSecretToken(token))
+	require.NotEqual(t, hash, HashSecretToken(token+"x"))
+}
+
+func TestEncryptSecret(t *testing.T) {
+	key := GenerateString(32)
+	secret := GenerateString(40)
+
+	encrypted, err := EncryptSecret(key, secret)
+	require.NoError(t, err)
+	require.NotContains(t, encrypted, secret)
+
+	//Every call uses a new nonce
+	again, err := EncryptSecret(key, secret)
+	require.NoError(t, err)
+	require.NotEqual(t, encrypted, again)
+
+	decrypted, err := DecryptSecret(key, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, secret, decrypted)
+
+	_, err = DecryptSecret(GenerateString(32), encrypted)
+	require.Error(t, err)
+	_, err = DecryptSecret(key, encrypted[:len(encrypted)-2]+"AA")
+	require.Error(t, err)
+	_, err = DecryptSecret(key, "")
+	require.Error(t, err)
+}